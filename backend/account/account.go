@@ -0,0 +1,196 @@
+// Package account builds account data exports and deletes accounts whose deletion is due in the background.
+package account
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Files of an export archive.
+const (
+	ProfileFile  = "profile.json"
+	ListsFile    = "lists.json"
+	SessionsFile = "sessions.json"
+)
+
+// Session describes a login of a user as found in the activity log.
+type Session struct {
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WriteArchive writes the ZIP archive of a user's profile, lists with their items and sessions to w.
+func WriteArchive(w io.Writer, user *todo.User, lists []*todo.List, sessions []*Session) error {
+	// the password hash and keys are secrets of the server, not data of the user
+	profile := *user
+	profile.Password = ""
+
+	zw := zip.NewWriter(w)
+	if err := writeJSON(zw, ProfileFile, profile); err != nil {
+		return err
+	}
+
+	f, err := zw.Create(ListsFile)
+	if err != nil {
+		return err
+	} else if err := format.JSON.Encode(f, format.Lists(lists)); err != nil {
+		return err
+	}
+
+	if err := writeJSON(zw, SessionsFile, sessions); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeJSON(zw *zip.Writer, name string, v interface{}) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Worker periodically builds pending exports and deletes accounts whose deletion is due.
+type Worker struct {
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+
+	ExportService   todo.AccountExportService
+	UserService     todo.UserService
+	ItemListService todo.ItemListService
+	ActivityService todo.ActivityService
+	Logger          todo.Logger
+
+	// Interval between polls for pending exports and due deletions.
+	Interval time.Duration
+	// BatchSize is the maximum number of exports claimed per poll.
+	BatchSize int
+	// Lease is how long a claimed export is hidden from other workers.
+	Lease time.Duration
+}
+
+func NewWorker(exports todo.AccountExportService, users todo.UserService, items todo.ItemListService, activity todo.ActivityService) *Worker {
+	w := &Worker{
+		ExportService:   exports,
+		UserService:     users,
+		ItemListService: items,
+		ActivityService: activity,
+		Logger:          todo.NewLogger(),
+		Interval:        10 * time.Second,
+		BatchSize:       5,
+		Lease:           5 * time.Minute,
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	return w
+}
+
+// Open starts working in the background.
+func (w *Worker) Open() {
+	w.done = make(chan struct{})
+	go w.run()
+}
+
+// Close stops working and waits for the export being built to finish.
+func (w *Worker) Close() error {
+	w.cancel()
+	if w.done != nil {
+		<-w.done
+	}
+	return nil
+}
+
+func (w *Worker) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		// keep exporting while full batches are returned
+		for {
+			n, err := w.Export(w.ctx)
+			if err != nil && w.ctx.Err() == nil {
+				w.Logger.Errorf("account worker: %v", err)
+			}
+			if err != nil || n < w.BatchSize {
+				break
+			}
+		}
+
+		if n, err := w.UserService.DeleteScheduledUsers(w.ctx); err != nil && w.ctx.Err() == nil {
+			w.Logger.Errorf("account worker: delete scheduled users: %v", err)
+		} else if n > 0 {
+			w.Logger.Infof("account worker: deleted %d accounts", n)
+		}
+
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Export claims a batch of pending exports and builds each of them. It returns the number of exports claimed.
+func (w *Worker) Export(ctx context.Context) (int, error) {
+	exports, err := w.ExportService.ClaimAccountExports(ctx, w.BatchSize, w.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim exports: %w", err)
+	}
+
+	for _, export := range exports {
+		upd := todo.AccountExportUpdate{Status: todo.ExportReady}
+		if upd.Archive, err = w.archive(ctx, export.UserID); err != nil {
+			w.Logger.Errorf("account worker: export %d of user %d failed: %v", export.ID, export.UserID, err)
+			upd = todo.AccountExportUpdate{Status: todo.ExportFailed, Error: todo.ErrMessage(err)}
+		}
+		if err := w.ExportService.UpdateAccountExport(ctx, export.ID, upd); err != nil {
+			return 0, fmt.Errorf("update export %d: %w", export.ID, err)
+		}
+	}
+	return len(exports), nil
+}
+
+// archive returns the export archive of a user.
+func (w *Worker) archive(ctx context.Context, userID int) ([]byte, error) {
+	user, err := w.UserService.FindUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// lists are only found for the user in the context
+	ctx = todo.NewContextWithUser(ctx, user)
+	lists, err := w.ItemListService.FindLists(ctx, todo.ListFilter{UserID: &user.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	action := todo.ActionUserLogin
+	logins, err := w.ActivityService.FindActivity(ctx, todo.ActivityFilter{UserID: &user.ID, Action: &action})
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*Session, 0, len(logins))
+	for _, login := range logins {
+		sessions = append(sessions, &Session{IP: login.IP, UserAgent: login.UserAgent, CreatedAt: login.CreatedAt})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteArchive(&buf, user, lists, sessions); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
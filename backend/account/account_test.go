@@ -0,0 +1,168 @@
+package account_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/account"
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// exports is an AccountExportService which only implements the queue of exports.
+type exports struct {
+	todo.AccountExportService
+
+	exports  []*todo.AccountExport
+	archives map[int][]byte
+}
+
+func (s *exports) ClaimAccountExports(ctx context.Context, n int, lease time.Duration) ([]*todo.AccountExport, error) {
+	var claimed []*todo.AccountExport
+	for _, export := range s.exports {
+		if len(claimed) < n && export.Status == todo.ExportPending {
+			other := *export
+			claimed = append(claimed, &other)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *exports) UpdateAccountExport(ctx context.Context, id int, upd todo.AccountExportUpdate) error {
+	for _, export := range s.exports {
+		if export.ID == id {
+			export.Status, export.Error = upd.Status, upd.Error
+			s.archives[id] = upd.Archive
+			return nil
+		}
+	}
+	return todo.NotFound
+}
+
+type users struct {
+	todo.UserService
+
+	users []*todo.User
+}
+
+func (s *users) FindUserByID(ctx context.Context, id int) (*todo.User, error) {
+	for _, user := range s.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, todo.Err(todo.ENOTFOUND, "could not find user with id %d", id)
+}
+
+type lists struct {
+	todo.ItemListService
+
+	lists []*todo.List
+}
+
+func (s *lists) FindLists(ctx context.Context, f todo.ListFilter) ([]*todo.List, error) {
+	var found []*todo.List
+	for _, list := range s.lists {
+		if list.UserID == todo.UserFromContext(ctx).ID && list.UserID == *f.UserID {
+			found = append(found, list)
+		}
+	}
+	return found, nil
+}
+
+type activity struct {
+	todo.ActivityService
+
+	activity []*todo.Activity
+}
+
+func (s *activity) FindActivity(ctx context.Context, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	var found []*todo.Activity
+	for _, a := range s.activity {
+		if a.UserID == *f.UserID && a.Action == *f.Action {
+			found = append(found, a)
+		}
+	}
+	return found, nil
+}
+
+func TestWorker_Export(t *testing.T) {
+	now := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	george := &todo.User{ID: 1, Name: "george", Password: "hash", APIKey: "key", CreatedAt: now, UpdatedAt: now}
+	groceries := &todo.List{ID: 1, UserID: george.ID, Name: "Groceries", Items: []*todo.Item{
+		{ID: 1, UserID: george.ID, ListID: 1, Name: "milk", CreatedAt: now, UpdatedAt: now},
+	}, CreatedAt: now, UpdatedAt: now}
+
+	queue := &exports{
+		exports: []*todo.AccountExport{
+			{ID: 1, UserID: george.ID, Status: todo.ExportPending},
+			{ID: 2, UserID: 99, Status: todo.ExportPending},
+		},
+		archives: make(map[int][]byte),
+	}
+	w := account.NewWorker(
+		queue,
+		&users{users: []*todo.User{george}},
+		&lists{lists: []*todo.List{groceries, {ID: 2, UserID: 2, Name: "Secret"}}},
+		&activity{activity: []*todo.Activity{
+			{UserID: george.ID, Action: todo.ActionUserLogin, IP: "127.0.0.1", UserAgent: "curl", CreatedAt: now},
+			{UserID: george.ID, Action: todo.ActionListCreated, CreatedAt: now},
+		}},
+	)
+
+	if n, err := w.Export(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n != 2 {
+		t.Fatalf("want 2 exports got %d", n)
+	}
+
+	if got := queue.exports[1]; got.Status != todo.ExportFailed || got.Error == "" {
+		t.Fatalf("want the export of an unknown user to fail got %+v", got)
+	} else if got := queue.exports[0]; got.Status != todo.ExportReady {
+		t.Fatalf("want the export ready got %+v", got)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(queue.archives[1]), int64(len(queue.archives[1])))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if files[f.Name], err = ioutil.ReadAll(rc); err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+	}
+
+	var profile todo.User
+	if err := json.Unmarshal(files[account.ProfileFile], &profile); err != nil {
+		t.Fatal(err)
+	} else if profile.Name != george.Name || profile.Password != "" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	got, err := format.JSON.Decode(bytes.NewReader(files[account.ListsFile]))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(got) != 1 || got[0].Name != "Groceries" || len(got[0].Items) != 1 {
+		t.Fatalf("unexpected lists %v", got)
+	}
+
+	var sessions []*account.Session
+	want := []*account.Session{{IP: "127.0.0.1", UserAgent: "curl", CreatedAt: now}}
+	if err := json.Unmarshal(files[account.SessionsFile], &sessions); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(sessions, want) {
+		t.Fatalf("want sessions %v got %v", want, sessions)
+	}
+}
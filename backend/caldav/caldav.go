@@ -0,0 +1,421 @@
+// Package caldav serves the lists of a user as CalDAV (RFC 4791) task calendars so that native task apps can sync
+// with them in both directions.
+package caldav
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	gocaldav "github.com/emersion/go-webdav/caldav"
+)
+
+// Handler serves the calendars of the user authenticated with HTTP Basic auth, where the password is the API key of
+// the user. Every list is a calendar with a VTODO resource for each of its items:
+//
+//	<prefix>/<user id>                                the principal of the user
+//	<prefix>/<user id>/lists                          the calendar home set
+//	<prefix>/<user id>/lists/<list id>                a list
+//	<prefix>/<user id>/lists/<list id>/<item id>.ics  an item
+//
+// Resources are named after the ID of their item, unless a client created the item under a name and UID of its own
+// choosing, which are kept so that the client can keep addressing it by them. Lists cannot be created or deleted over
+// CalDAV.
+type Handler struct {
+	// Prefix is the path the Handler is mounted at.
+	Prefix string
+
+	ItemListService       todo.ItemListService
+	CalendarObjectService todo.CalendarObjectService
+	UserService           todo.UserService
+}
+
+func NewHandler(prefix string, items todo.ItemListService, objects todo.CalendarObjectService, users todo.UserService) *Handler {
+	return &Handler{
+		Prefix:                strings.TrimSuffix(prefix, "/"),
+		ItemListService:       items,
+		CalendarObjectService: objects,
+		UserService:           users,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, apiKey, ok := r.BasicAuth()
+	if !ok {
+		unauthorized(w)
+		return
+	}
+
+	user, err := h.UserService.FindUserByAPIKey(r.Context(), apiKey)
+	if todo.ErrCode(err) == todo.ENOTFOUND || (err == nil && !strings.EqualFold(user.Name, name)) {
+		unauthorized(w)
+		return
+	} else if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	dav := &gocaldav.Handler{Backend: &backend{Handler: h}, Prefix: h.Prefix}
+	dav.ServeHTTP(w, r.WithContext(todo.NewContextWithUser(r.Context(), user)))
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="todo", charset="UTF-8"`)
+	http.Error(w, "invalid credentials", http.StatusUnauthorized)
+}
+
+// backend implements the CalDAV backend for the user in the request context.
+type backend struct {
+	*Handler
+}
+
+func (b *backend) CurrentUserPrincipal(ctx context.Context) (string, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return "", davError(err)
+	}
+	return fmt.Sprintf("%s/%d", b.Prefix, user.ID), nil
+}
+
+func (b *backend) CalendarHomeSetPath(ctx context.Context) (string, error) {
+	principal, err := b.CurrentUserPrincipal(ctx)
+	if err != nil {
+		return "", err
+	}
+	return principal + "/lists", nil
+}
+
+func (b *backend) CreateCalendar(ctx context.Context, calendar *gocaldav.Calendar) error {
+	return webdav.NewHTTPError(http.StatusForbidden, errors.New("lists cannot be created over CalDAV"))
+}
+
+func (b *backend) ListCalendars(ctx context.Context) ([]gocaldav.Calendar, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, davError(err)
+	}
+
+	lists, err := b.ItemListService.FindLists(ctx, todo.ListFilter{UserID: &user.ID})
+	if err != nil {
+		return nil, davError(err)
+	}
+
+	calendars := make([]gocaldav.Calendar, 0, len(lists))
+	for _, list := range lists {
+		calendars = append(calendars, b.calendar(ctx, list))
+	}
+	return calendars, nil
+}
+
+func (b *backend) GetCalendar(ctx context.Context, p string) (*gocaldav.Calendar, error) {
+	listID, _, err := b.parsePath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := b.ItemListService.FindListByID(ctx, listID)
+	if err != nil {
+		return nil, davError(err)
+	}
+	calendar := b.calendar(ctx, list)
+	return &calendar, nil
+}
+
+func (b *backend) GetCalendarObject(ctx context.Context, p string, req *gocaldav.CalendarCompRequest) (*gocaldav.CalendarObject, error) {
+	item, obj, err := b.findItem(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return b.object(ctx, item, obj), nil
+}
+
+func (b *backend) ListCalendarObjects(ctx context.Context, p string, req *gocaldav.CalendarCompRequest) ([]gocaldav.CalendarObject, error) {
+	listID, _, err := b.parsePath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := b.ItemListService.FindListByID(ctx, listID)
+	if err != nil {
+		return nil, davError(err)
+	}
+	created, err := b.CalendarObjectService.FindCalendarObjects(ctx, listID)
+	if err != nil {
+		return nil, davError(err)
+	}
+	byItem := make(map[int]*todo.CalendarObject, len(created))
+	for _, obj := range created {
+		byItem[obj.ItemID] = obj
+	}
+
+	objects := make([]gocaldav.CalendarObject, 0, len(list.Items))
+	for _, item := range list.Items {
+		objects = append(objects, *b.object(ctx, item, byItem[item.ID]))
+	}
+	return objects, nil
+}
+
+func (b *backend) QueryCalendarObjects(ctx context.Context, p string, query *gocaldav.CalendarQuery) ([]gocaldav.CalendarObject, error) {
+	objects, err := b.ListCalendarObjects(ctx, p, &query.CompRequest)
+	if err != nil {
+		return nil, err
+	}
+	return gocaldav.Filter(query, objects)
+}
+
+func (b *backend) PutCalendarObject(ctx context.Context, p string, cal *ical.Calendar, opts *gocaldav.PutCalendarObjectOptions) (*gocaldav.CalendarObject, error) {
+	listID, name, err := b.parsePath(ctx, p)
+	if err != nil {
+		return nil, err
+	} else if name == "" {
+		return nil, webdav.NewHTTPError(http.StatusForbidden, errors.New("only items can be written"))
+	}
+
+	upd, uid, err := parseTodo(cal)
+	if err != nil {
+		return nil, err
+	}
+	itemID, obj, err := b.resolve(ctx, listID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// items of other lists and users are treated as if they did not exist
+	var item *todo.Item
+	if itemID > 0 {
+		item, err = b.ItemListService.FindItemByID(ctx, itemID)
+		if code := todo.ErrCode(err); code == todo.ENOTFOUND || code == todo.EUNAUTHORIZED {
+			item = nil
+		} else if err != nil {
+			return nil, davError(err)
+		} else if item.ListID != listID {
+			item = nil
+		}
+	}
+
+	switch {
+	case item != nil && opts.IfNoneMatch.IsSet():
+		return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("the item already exists"))
+	case item != nil && opts.IfMatch.IsSet() && !opts.IfMatch.IsWildcard():
+		if etag, err := opts.IfMatch.ETag(); err != nil {
+			return nil, webdav.NewHTTPError(http.StatusBadRequest, err)
+		} else if etag != itemETag(item) {
+			return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("the item has been modified"))
+		}
+	case item == nil && opts.IfMatch.IsSet():
+		return nil, webdav.NewHTTPError(http.StatusPreconditionFailed, errors.New("the item does not exist"))
+	}
+
+	if item == nil {
+		// the client keeps the name and UID it created the item under
+		item = &todo.Item{ListID: listID, Name: *upd.Name, Completed: *upd.Completed}
+		obj = &todo.CalendarObject{Name: name, UID: uid}
+		if err := b.CalendarObjectService.CreateCalendarObject(ctx, item, obj); todo.ErrCode(err) == todo.ECONFLICT {
+			return nil, gocaldav.NewPreconditionError(gocaldav.PreconditionNoUIDConflict)
+		} else if err != nil {
+			return nil, davError(err)
+		}
+	} else if item, err = b.ItemListService.UpdateItem(ctx, item.ID, upd); err != nil {
+		return nil, davError(err)
+	}
+	return b.object(ctx, item, obj), nil
+}
+
+func (b *backend) DeleteCalendarObject(ctx context.Context, p string) error {
+	if _, name, err := b.parsePath(ctx, p); err != nil {
+		return err
+	} else if name == "" {
+		return webdav.NewHTTPError(http.StatusForbidden, errors.New("only items can be deleted"))
+	}
+
+	item, _, err := b.findItem(ctx, p)
+	if err != nil {
+		return err
+	}
+	return davError(b.ItemListService.DeleteItem(ctx, item.ID))
+}
+
+// parsePath returns the ID of the list and the name of the resource of a path of the current user. The list ID is
+// zero if the path is above a list and the name is empty if the path is not a resource in a list.
+func (b *backend) parsePath(ctx context.Context, p string) (listID int, name string, err error) {
+	home, err := b.CalendarHomeSetPath(ctx)
+	if err != nil {
+		return 0, "", err
+	}
+
+	p = path.Clean(p)
+	if p == home || strings.HasPrefix(home, p+"/") {
+		return 0, "", nil
+	} else if !strings.HasPrefix(p, home+"/") {
+		return 0, "", webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("%s not found", p))
+	}
+
+	parts := strings.Split(strings.TrimPrefix(p, home+"/"), "/")
+	if listID, err = strconv.Atoi(parts[0]); err != nil || len(parts) > 2 {
+		return 0, "", webdav.NewHTTPError(http.StatusNotFound, fmt.Errorf("%s not found", p))
+	}
+	if len(parts) == 2 {
+		name = parts[1]
+	}
+	return listID, name, nil
+}
+
+// resolve returns the ID of the item of a resource in a list and the object the item was created as by a client, if
+// it was. Resources are found by the name a client created them under first and by the ID of their item otherwise.
+// The item ID is zero if the name is neither.
+func (b *backend) resolve(ctx context.Context, listID int, name string) (int, *todo.CalendarObject, error) {
+	objects, err := b.CalendarObjectService.FindCalendarObjects(ctx, listID)
+	if err != nil {
+		return 0, nil, davError(err)
+	}
+	for _, obj := range objects {
+		if obj.Name == name {
+			return obj.ItemID, obj, nil
+		}
+	}
+
+	itemID, _ := strconv.Atoi(strings.TrimSuffix(name, ".ics"))
+	for _, obj := range objects {
+		if obj.ItemID == itemID {
+			return itemID, obj, nil
+		}
+	}
+	return itemID, nil, nil
+}
+
+// findItem returns the item at path p, which must belong to the list of the path, and the object it was created as.
+func (b *backend) findItem(ctx context.Context, p string) (*todo.Item, *todo.CalendarObject, error) {
+	listID, name, err := b.parsePath(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	} else if name == "" {
+		return nil, nil, davError(todo.Err(todo.ENOTFOUND, "%s not found", p))
+	}
+	itemID, obj, err := b.resolve(ctx, listID, name)
+	if err != nil {
+		return nil, nil, err
+	} else if itemID == 0 {
+		return nil, nil, davError(todo.Err(todo.ENOTFOUND, "%s not found", p))
+	}
+
+	item, err := b.ItemListService.FindItemByID(ctx, itemID)
+	if err != nil {
+		return nil, nil, davError(err)
+	} else if item.ListID != listID {
+		return nil, nil, davError(todo.Err(todo.ENOTFOUND, "%s not found", p))
+	}
+	return item, obj, nil
+}
+
+func (b *backend) calendar(ctx context.Context, list *todo.List) gocaldav.Calendar {
+	home, _ := b.CalendarHomeSetPath(ctx)
+	return gocaldav.Calendar{
+		Path:                  fmt.Sprintf("%s/%d", home, list.ID),
+		Name:                  list.Name,
+		SupportedComponentSet: []string{ical.CompToDo},
+	}
+}
+
+// object returns the resource of an item, under the name and UID of obj if a client created it.
+func (b *backend) object(ctx context.Context, item *todo.Item, obj *todo.CalendarObject) *gocaldav.CalendarObject {
+	home, _ := b.CalendarHomeSetPath(ctx)
+	name, uid := fmt.Sprintf("%d.ics", item.ID), format.ICalUID(item)
+	if obj != nil {
+		name, uid = obj.Name, obj.UID
+	}
+	return &gocaldav.CalendarObject{
+		Path:    fmt.Sprintf("%s/%d/%s", home, item.ListID, name),
+		ModTime: item.UpdatedAt,
+		ETag:    itemETag(item),
+		Data:    newCalendar(item, uid),
+	}
+}
+
+// itemETag changes whenever the item is updated.
+func itemETag(item *todo.Item) string {
+	return fmt.Sprintf("%d-%x", item.ID, item.UpdatedAt.UnixNano())
+}
+
+// newCalendar returns a calendar containing the VTODO of an item with the UID.
+func newCalendar(item *todo.Item, uid string) *ical.Calendar {
+	vtodo := ical.NewComponent(ical.CompToDo)
+	vtodo.Props.SetText(ical.PropUID, uid)
+	vtodo.Props.SetDateTime(ical.PropDateTimeStamp, item.UpdatedAt.UTC())
+	vtodo.Props.SetText(ical.PropSummary, item.Name)
+	if item.Completed {
+		vtodo.Props.SetText(ical.PropStatus, "COMPLETED")
+		vtodo.Props.SetDateTime(ical.PropCompleted, item.UpdatedAt.UTC())
+		vtodo.Props.SetText(ical.PropPercentComplete, "100")
+	} else {
+		vtodo.Props.SetText(ical.PropStatus, "NEEDS-ACTION")
+	}
+	vtodo.Props.SetDateTime(ical.PropCreated, item.CreatedAt.UTC())
+	vtodo.Props.SetDateTime(ical.PropLastModified, item.UpdatedAt.UTC())
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//todo-app//todo-app "+todo.Build.Version+"//EN")
+	cal.Children = append(cal.Children, vtodo)
+	return cal
+}
+
+// parseTodo returns the name and completed state and the UID of the VTODO in a calendar written by a client.
+func parseTodo(cal *ical.Calendar) (todo.ItemUpdate, string, error) {
+	typ, uid, err := gocaldav.ValidateCalendarObject(cal)
+	if err != nil {
+		return todo.ItemUpdate{}, "", webdav.NewHTTPError(http.StatusBadRequest, err)
+	} else if typ != ical.CompToDo {
+		return todo.ItemUpdate{}, "", gocaldav.NewPreconditionError(gocaldav.PreconditionSupportedCalendarComponent)
+	}
+
+	var vtodo *ical.Component
+	for _, child := range cal.Children {
+		if child.Name == ical.CompToDo {
+			vtodo = child
+			break
+		}
+	}
+
+	name, err := vtodo.Props.Text(ical.PropSummary)
+	if err != nil {
+		return todo.ItemUpdate{}, "", webdav.NewHTTPError(http.StatusBadRequest, err)
+	} else if name = strings.TrimSpace(name); name == "" {
+		return todo.ItemUpdate{}, "", webdav.NewHTTPError(http.StatusBadRequest, errors.New("the task has no summary"))
+	}
+
+	// clients which do not set a status mark completed tasks with the time they were completed
+	var completed bool
+	if status := vtodo.Props.Get(ical.PropStatus); status != nil {
+		completed = strings.EqualFold(status.Value, "COMPLETED")
+	} else {
+		completed = vtodo.Props.Get(ical.PropCompleted) != nil
+	}
+	return todo.ItemUpdate{Name: &name, Completed: &completed}, uid, nil
+}
+
+// davError converts a todo error into an error with the corresponding HTTP status code.
+func davError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	status := http.StatusInternalServerError
+	switch todo.ErrCode(err) {
+	case todo.EINVALID:
+		status = http.StatusBadRequest
+	case todo.ECONFLICT:
+		status = http.StatusConflict
+	case todo.ENOTFOUND, todo.EUNAUTHORIZED:
+		// other users' lists and items are not revealed
+		status = http.StatusNotFound
+	}
+	return webdav.NewHTTPError(status, err)
+}
@@ -0,0 +1,373 @@
+package caldav_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/caldav"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	gocaldav "github.com/emersion/go-webdav/caldav"
+)
+
+// ItemListService keeps lists, items and calendar objects in memory, it only implements the methods used by the
+// CalDAV handler.
+type ItemListService struct {
+	todo.ItemListService
+
+	lists   map[int]*todo.List
+	items   map[int]*todo.Item
+	objects map[int]*todo.CalendarObject
+	nextID  int
+	now     time.Time
+}
+
+func NewItemListService() *ItemListService {
+	return &ItemListService{
+		lists:   make(map[int]*todo.List),
+		items:   make(map[int]*todo.Item),
+		objects: make(map[int]*todo.CalendarObject),
+		now:     time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// tick returns a new time for every change so that ETags change too.
+func (s *ItemListService) tick() time.Time {
+	s.now = s.now.Add(time.Second)
+	return s.now
+}
+
+func (s *ItemListService) FindLists(ctx context.Context, f todo.ListFilter) ([]*todo.List, error) {
+	var lists []*todo.List
+	for id := range s.lists {
+		if list, err := s.FindListByID(ctx, id); err == nil && (f.UserID == nil || list.UserID == *f.UserID) {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+	return lists, nil
+}
+
+func (s *ItemListService) FindListByID(ctx context.Context, id int) (*todo.List, error) {
+	list, ok := s.lists[id]
+	if !ok {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find list with id %d", id)
+	} else if list.UserID != todo.UserFromContext(ctx).ID {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "unauthorized")
+	}
+
+	other := *list
+	other.Items = nil
+	for _, item := range s.items {
+		if item.ListID == id {
+			copy := *item
+			other.Items = append(other.Items, &copy)
+		}
+	}
+	sort.Slice(other.Items, func(i, j int) bool { return other.Items[i].ID < other.Items[j].ID })
+	return &other, nil
+}
+
+func (s *ItemListService) CreateList(ctx context.Context, list *todo.List) error {
+	s.nextID++
+	list.ID, list.UserID = s.nextID, todo.UserFromContext(ctx).ID
+	list.CreatedAt = s.tick()
+	list.UpdatedAt = list.CreatedAt
+	s.lists[list.ID] = list
+	return nil
+}
+
+func (s *ItemListService) FindItemByID(ctx context.Context, id int) (*todo.Item, error) {
+	item, ok := s.items[id]
+	if !ok {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find item with id %d", id)
+	} else if item.UserID != todo.UserFromContext(ctx).ID {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "unauthorized")
+	}
+	copy := *item
+	return &copy, nil
+}
+
+func (s *ItemListService) CreateItem(ctx context.Context, item *todo.Item) error {
+	if _, err := s.FindListByID(ctx, item.ListID); err != nil {
+		return err
+	}
+	s.nextID++
+	item.ID, item.UserID = s.nextID, todo.UserFromContext(ctx).ID
+	item.CreatedAt = s.tick()
+	item.UpdatedAt = item.CreatedAt
+	copy := *item
+	s.items[item.ID] = &copy
+	return nil
+}
+
+func (s *ItemListService) UpdateItem(ctx context.Context, id int, upd todo.ItemUpdate) (*todo.Item, error) {
+	if _, err := s.FindItemByID(ctx, id); err != nil {
+		return nil, err
+	}
+	item := s.items[id]
+	if upd.Name != nil {
+		item.Name = *upd.Name
+	}
+	if upd.Completed != nil {
+		item.Completed = *upd.Completed
+	}
+	item.UpdatedAt = s.tick()
+	copy := *item
+	return &copy, nil
+}
+
+func (s *ItemListService) DeleteItem(ctx context.Context, id int) error {
+	if _, err := s.FindItemByID(ctx, id); err != nil {
+		return err
+	}
+	delete(s.items, id)
+	delete(s.objects, id)
+	return nil
+}
+
+func (s *ItemListService) FindCalendarObjects(ctx context.Context, listID int) ([]*todo.CalendarObject, error) {
+	if _, err := s.FindListByID(ctx, listID); err != nil {
+		return nil, err
+	}
+	var objects []*todo.CalendarObject
+	for _, obj := range s.objects {
+		if obj.ListID == listID {
+			copy := *obj
+			objects = append(objects, &copy)
+		}
+	}
+	return objects, nil
+}
+
+func (s *ItemListService) CreateCalendarObject(ctx context.Context, item *todo.Item, obj *todo.CalendarObject) error {
+	for _, other := range s.objects {
+		if other.ListID == item.ListID && (other.Name == obj.Name || other.UID == obj.UID) {
+			return todo.Err(todo.ECONFLICT, "conflict")
+		}
+	}
+	if err := s.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	obj.ItemID, obj.ListID = item.ID, item.ListID
+	copy := *obj
+	s.objects[item.ID] = &copy
+	return nil
+}
+
+// UserService finds users by their API key.
+type UserService struct {
+	todo.UserService
+
+	users []*todo.User
+}
+
+func (s *UserService) FindUserByAPIKey(ctx context.Context, apiKey string) (*todo.User, error) {
+	for _, user := range s.users {
+		if user.APIKey == apiKey {
+			return user, nil
+		}
+	}
+	return nil, todo.Err(todo.ENOTFOUND, "could not find user with api key")
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	george := &todo.User{ID: 1, Name: "george", APIKey: "george-key"}
+	other := &todo.User{ID: 2, Name: "other", APIKey: "other-key"}
+
+	items := NewItemListService()
+	users := &UserService{users: []*todo.User{george, other}}
+
+	groceries := &todo.List{Name: "Groceries"}
+	if err := items.CreateList(todo.NewContextWithUser(ctx, george), groceries); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range []*todo.Item{{ListID: groceries.ID, Name: "milk"}, {ListID: groceries.ID, Name: "eggs", Completed: true}} {
+		if err := items.CreateItem(todo.NewContextWithUser(ctx, george), item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := items.CreateList(todo.NewContextWithUser(ctx, other), &todo.List{Name: "Secret"}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(caldav.NewHandler("/dav", items, items, users))
+	defer srv.Close()
+
+	newClient := func(t *testing.T, name, apiKey string) *gocaldav.Client {
+		t.Helper()
+		c, err := gocaldav.NewClient(webdav.HTTPClientWithBasicAuth(srv.Client(), name, apiKey), srv.URL+"/dav/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	c := newClient(t, "George", "george-key")
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		for _, creds := range [][2]string{{"george", "wrong"}, {"other", "george-key"}} {
+			if _, err := newClient(t, creds[0], creds[1]).FindCurrentUserPrincipal(ctx); err == nil {
+				t.Fatalf("want error for %v", creds)
+			}
+		}
+	})
+
+	var calendarPath string
+	t.Run("Discovery", func(t *testing.T) {
+		principal, err := c.FindCurrentUserPrincipal(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if want := "/dav/1"; principal != want {
+			t.Fatalf("want principal %q got %q", want, principal)
+		}
+
+		home, err := c.FindCalendarHomeSet(ctx, principal)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		calendars, err := c.FindCalendars(ctx, home)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(calendars) != 1 {
+			t.Fatalf("want 1 calendar got %d", len(calendars))
+		} else if got := calendars[0]; got.Name != "Groceries" || got.SupportedComponentSet[0] != ical.CompToDo {
+			t.Fatalf("unexpected calendar %+v", got)
+		}
+		calendarPath = calendars[0].Path
+	})
+
+	query := func(t *testing.T) map[string]*gocaldav.CalendarObject {
+		t.Helper()
+		objects, err := c.QueryCalendar(ctx, calendarPath, &gocaldav.CalendarQuery{
+			CompFilter: gocaldav.CompFilter{Name: ical.CompCalendar, Comps: []gocaldav.CompFilter{{Name: ical.CompToDo}}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		summaries := make(map[string]*gocaldav.CalendarObject)
+		for i := range objects {
+			summary, err := objects[i].Data.Children[0].Props.Text(ical.PropSummary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			summaries[summary] = &objects[i]
+		}
+		return summaries
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		objects := query(t)
+		if len(objects) != 2 {
+			t.Fatalf("want 2 objects got %d", len(objects))
+		}
+		if status, _ := objects["eggs"].Data.Children[0].Props.Text(ical.PropStatus); status != "COMPLETED" {
+			t.Fatalf("want eggs completed got %q", status)
+		}
+	})
+
+	t.Run("Put", func(t *testing.T) {
+		// a new task under a name and UID chosen by the client, which keeps using them
+		p := calendarPath + "/6B29FC40-CA47.ics"
+		created, err := c.PutCalendarObject(ctx, p, newTodo("bread", false))
+		if err != nil {
+			t.Fatal(err)
+		} else if created.Path != p {
+			t.Fatalf("want path %q got %q", p, created.Path)
+		}
+
+		// complete the task
+		if _, err := c.PutCalendarObject(ctx, p, newTodo("bread", true)); err != nil {
+			t.Fatal(err)
+		}
+
+		object, err := c.GetCalendarObject(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		} else if status, _ := object.Data.Children[0].Props.Text(ical.PropStatus); status != "COMPLETED" {
+			t.Fatalf("want completed got %q", status)
+		} else if uid, _ := object.Data.Children[0].Props.Text(ical.PropUID); uid != "bread@client" {
+			t.Fatalf("want the UID of the client got %q", uid)
+		} else if object.ETag == created.ETag {
+			t.Fatal("want the ETag to change")
+		}
+		if objects := query(t); len(objects) != 3 {
+			t.Fatalf("want 3 objects got %d", len(objects))
+		} else if objects["bread"].Path != p {
+			t.Fatalf("want bread at %q got %q", p, objects["bread"].Path)
+		}
+
+		// another resource with the same UID conflicts
+		if _, err := c.PutCalendarObject(ctx, calendarPath+"/other.ics", newTodo("bread", false)); err == nil {
+			t.Fatal("want error for a UID conflict")
+		}
+
+		// a stale ETag is rejected
+		var buf strings.Builder
+		if err := ical.NewEncoder(&buf).Encode(newTodo("stale", false)); err != nil {
+			t.Fatal(err)
+		}
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+p, strings.NewReader(buf.String()))
+		req.SetBasicAuth("george", "george-key")
+		req.Header.Set("Content-Type", ical.MIMEType)
+		req.Header.Set("If-Match", `"`+created.ETag+`"`)
+		if resp, err := srv.Client().Do(req); err != nil {
+			t.Fatal(err)
+		} else if resp.Body.Close(); resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatalf("want status %d got %d", http.StatusPreconditionFailed, resp.StatusCode)
+		}
+
+		if _, ok := query(t)["bread"]; !ok {
+			t.Fatal("want bread in the calendar")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		milk := query(t)["milk"]
+		if err := c.RemoveAll(ctx, milk.Path); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := query(t)["milk"]; ok {
+			t.Fatal("want milk deleted")
+		}
+		if _, err := c.GetCalendarObject(ctx, milk.Path); err == nil {
+			t.Fatal("want error for a deleted item")
+		}
+	})
+
+	t.Run("OtherUser", func(t *testing.T) {
+		// the list of the other user is not found under the own home set
+		if _, err := c.QueryCalendar(ctx, "/dav/1/lists/4", &gocaldav.CalendarQuery{}); err == nil {
+			t.Fatal("want error for the list of another user")
+		}
+		if calendars, err := c.FindCalendars(ctx, "/dav/2/lists"); err != nil {
+			t.Fatal(err)
+		} else if len(calendars) != 0 {
+			t.Fatalf("want no calendars of another user got %d", len(calendars))
+		}
+	})
+}
+
+func newTodo(summary string, completed bool) *ical.Calendar {
+	vtodo := ical.NewComponent(ical.CompToDo)
+	vtodo.Props.SetText(ical.PropUID, summary+"@client")
+	vtodo.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	vtodo.Props.SetText(ical.PropSummary, summary)
+	if completed {
+		vtodo.Props.SetText(ical.PropStatus, "COMPLETED")
+	}
+
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//client//EN")
+	cal.Children = append(cal.Children, vtodo)
+	return cal
+}
@@ -0,0 +1,177 @@
+// Package client is a typed client of the todo server API as described by /api/openapi.json. Errors returned by
+// the server are mapped back to a *todo.Error with the code of the response status, so that todo.ErrCode works
+// the same on both sides.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+
+	todohttp "github.com/cmokbel1/todo-app/backend/http"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Client calls the todo server API. Requests are authenticated with the APIKey of a user or, after Login, with the
+// session cookie. Administrative requests are authenticated with the ServerAPIKey.
+type Client struct {
+	// URL is the base URL of the server, e.g. "http://localhost:8080".
+	URL          string
+	APIKey       string
+	ServerAPIKey string
+
+	// HTTPClient keeps the session cookie in its cookie jar.
+	HTTPClient *http.Client
+
+	// csrfToken of the session started by Login, which requests that change state must send.
+	csrfToken string
+}
+
+// New returns a Client of the server at url which authenticates with the API key of a user.
+func New(url, apiKey string) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		URL:        url,
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second, Jar: jar},
+	}
+}
+
+// BuildInfo describes the version of the server.
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// Build returns the version of the server.
+func (c *Client) Build(ctx context.Context) (*BuildInfo, error) {
+	var build BuildInfo
+	if err := c.do(ctx, http.MethodGet, "/api/build", nil, &build); err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
+
+// OpenAPI returns the OpenAPI document of the server.
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	return c.download(ctx, "/api/openapi.json")
+}
+
+// request describes a single API call.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is encoded as JSON unless it is an io.Reader, which is sent as is.
+	body interface{}
+	// server authenticates the request with the ServerAPIKey.
+	server bool
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	return c.send(ctx, request{method: method, path: path, body: body}, v)
+}
+
+// download returns the raw body of a GET request.
+func (c *Client) download(ctx context.Context, path string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.send(ctx, request{method: http.MethodGet, path: path}, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// send performs the request and decodes the JSON response into v. If v is an io.Writer the body is copied to it
+// instead.
+func (c *Client) send(ctx context.Context, r request, v interface{}) error {
+	resp, err := c.open(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch v := v.(type) {
+	case nil:
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	case io.Writer:
+		_, err := io.Copy(v, resp.Body)
+		return err
+	default:
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+}
+
+// open performs the request and returns the response if it was successful. The caller must close its body.
+func (c *Client) open(ctx context.Context, r request) (*http.Response, error) {
+	u := strings.TrimSuffix(c.URL, "/") + r.path
+	if len(r.query) > 0 {
+		u += "?" + r.query.Encode()
+	}
+
+	var body io.Reader
+	contentType := "application/json"
+	switch b := r.body.(type) {
+	case nil:
+	case io.Reader:
+		body, contentType = b, "application/octet-stream"
+	default:
+		buf, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.server {
+		req.Header.Set("Todo-Api-Key", c.ServerAPIKey)
+	} else if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if c.csrfToken != "" && !r.server {
+		switch r.method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			req.Header.Set("X-CSRF-Token", c.csrfToken)
+		}
+	}
+	// the request continues the trace of the context, if any
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	}
+	return resp, nil
+}
+
+// decodeError returns the *todo.Error of an unsuccessful response.
+func decodeError(resp *http.Response) error {
+	var e todohttp.Error
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Message == "" {
+		e.Message = fmt.Sprintf("unexpected response status %d", resp.StatusCode)
+	}
+	return todo.Err(todohttp.ErrCodeFromStatus(resp.StatusCode), "%s", e.Message)
+}
@@ -0,0 +1,175 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/client"
+	todohttp "github.com/cmokbel1/todo-app/backend/http"
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func newClient(t *testing.T, h http.HandlerFunc) *client.Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	c := client.New(srv.URL, "user-key")
+	c.ServerAPIKey = "server-key"
+	return c
+}
+
+func TestClient_Errors(t *testing.T) {
+	tt := []struct {
+		Status  int
+		Body    string
+		Code    string
+		Message string
+	}{
+		{Status: http.StatusNotFound, Body: `{"error":"could not find list with id 1"}`, Code: todo.ENOTFOUND, Message: "could not find list with id 1"},
+		{Status: http.StatusBadRequest, Body: `{"error":"name required"}`, Code: todo.EINVALID, Message: "name required"},
+		{Status: http.StatusUnauthorized, Body: `{"error":"unauthorized"}`, Code: todo.EUNAUTHORIZED, Message: "unauthorized"},
+		{Status: http.StatusConflict, Body: `{"error":"name is already taken"}`, Code: todo.ECONFLICT, Message: "name is already taken"},
+		{Status: http.StatusBadGateway, Body: "<html>bad gateway</html>", Code: todo.EINTERNAL, Message: "unexpected response status 502"},
+	}
+
+	for _, tc := range tt {
+		t.Run(fmt.Sprint(tc.Status), func(t *testing.T) {
+			c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.Status)
+				io.WriteString(w, tc.Body)
+			})
+
+			_, err := c.FindList(context.Background(), 1)
+			if code := todo.ErrCode(err); code != tc.Code {
+				t.Fatalf("want code %q got %q", tc.Code, code)
+			} else if msg := todo.ErrMessage(err); msg != tc.Message {
+				t.Fatalf("want message %q got %q", tc.Message, msg)
+			}
+		})
+	}
+}
+
+func TestClient_CreateList(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/todos" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		} else if got := r.Header.Get("Authorization"); got != "Bearer user-key" {
+			t.Errorf("want bearer auth got %q", got)
+		}
+
+		var list todo.List
+		if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+			t.Error(err)
+		}
+		list.ID, list.UserID, list.Items = 7, 1, []*todo.Item{}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(list)
+	})
+
+	list := &todo.List{Name: "Groceries"}
+	if err := c.CreateList(context.Background(), list); err != nil {
+		t.Fatal(err)
+	} else if list.ID != 7 || list.UserID != 1 || list.Name != "Groceries" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+}
+
+func TestClient_ServerAPIKey(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Todo-Api-Key"); got != "server-key" {
+			t.Errorf("want server key got %q", got)
+		} else if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("want no user auth got %q", got)
+		} else if got := r.URL.Query().Get("user"); got != "3" {
+			t.Errorf("want user 3 got %q", got)
+		}
+		io.WriteString(w, `[{"id":1,"userId":3,"action":"user.login"}]`)
+	})
+
+	userID := 3
+	activity, err := c.FindActivity(context.Background(), todo.ActivityFilter{UserID: &userID})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(activity) != 1 || activity[0].Action != todo.ActionUserLogin {
+		t.Fatalf("unexpected activity %v", activity)
+	}
+}
+
+func TestClient_ImportLists(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query(); got.Get("format") != "csv" || got.Get("dryRun") != "true" {
+			t.Errorf("unexpected query %v", got)
+		}
+		if b, _ := io.ReadAll(r.Body); string(b) != "list,item,completed\n" {
+			t.Errorf("unexpected body %q", b)
+		}
+		io.WriteString(w, `{"dryRun":true,"summary":{"lists":1,"items":2,"completed":1},"lists":[]}`)
+	})
+
+	result, err := c.ImportLists(context.Background(), "csv", strings.NewReader("list,item,completed\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := (client.ImportSummary{Lists: 1, Items: 2, Completed: 1}); !result.DryRun || result.Summary != want {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestClient_Events(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n")
+		io.WriteString(w, "event: item.created\ndata: {\"type\":\"item.created\",\"item\":{\"id\":2,\"name\":\"milk\"}}\n\n")
+	})
+
+	stream, err := c.Events(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if event, err := stream.Next(); err != nil {
+		t.Fatal(err)
+	} else if event.Type != todo.EventItemCreated || event.Item.Name != "milk" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Fatalf("want EOF got %v", err)
+	}
+}
+
+func TestClient_Login(t *testing.T) {
+	s := todohttp.NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = todohttp.NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	srv := httptest.NewServer(s.Handler())
+	t.Cleanup(srv.Close)
+
+	ctx := context.Background()
+	if err := s.UserService.CreateUser(ctx, &todo.User{Name: "george", Password: "password"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session is protected from CSRF, the client sends the token of the session
+	c := client.New(srv.URL, "")
+	if _, err := c.Login(ctx, "george", "password"); err != nil {
+		t.Fatal(err)
+	} else if err := c.CreateList(ctx, &todo.List{Name: "Groceries"}); err != nil {
+		t.Fatal(err)
+	} else if err := c.Logout(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.CreateList(ctx, &todo.List{Name: "Errands"}); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("want unauthorized got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// ImportSummary counts the lists and items of an import.
+type ImportSummary struct {
+	Lists     int `json:"lists"`
+	Items     int `json:"items"`
+	Completed int `json:"completed"`
+}
+
+// ImportResult is the outcome of ImportLists.
+type ImportResult struct {
+	// DryRun is true when the lists were only read and not created.
+	DryRun  bool          `json:"dryRun"`
+	Summary ImportSummary `json:"summary"`
+	Lists   []*todo.List  `json:"lists"`
+}
+
+// ExportLists returns all lists of the current user in the named format, e.g. "csv".
+func (c *Client) ExportLists(ctx context.Context, format string) ([]byte, error) {
+	return c.download(ctx, "/api/export?format="+url.QueryEscape(format))
+}
+
+// ExportList returns a list in the named format.
+func (c *Client) ExportList(ctx context.Context, id int, format string) ([]byte, error) {
+	return c.download(ctx, fmt.Sprintf("/api/todos/%d/export?format=%s", id, url.QueryEscape(format)))
+}
+
+// ExportListICal returns a list as iCalendar tasks.
+func (c *Client) ExportListICal(ctx context.Context, id int) ([]byte, error) {
+	return c.download(ctx, fmt.Sprintf("/api/todos/%d.ics", id))
+}
+
+// Feed returns the calendar feed of the owner of the feed token. It does not require any other authentication.
+func (c *Client) Feed(ctx context.Context, token string) ([]byte, error) {
+	return c.download(ctx, "/api/feed/"+url.PathEscape(token)+".ics")
+}
+
+// ImportLists imports the lists read from r in the named format, e.g. "trello". A dry run only reports what would
+// be imported.
+func (c *Client) ImportLists(ctx context.Context, format string, r io.Reader, dryRun bool) (*ImportResult, error) {
+	query := url.Values{"format": {format}}
+	if dryRun {
+		query.Set("dryRun", "true")
+	}
+
+	var result ImportResult
+	if err := c.send(ctx, request{method: http.MethodPost, path: "/api/import", query: query, body: r}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/gorilla/websocket"
+)
+
+// EventStream reads the Server-Sent Events of the current user.
+type EventStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// Events opens the stream of list and item events of the current user. The stream ends when ctx is canceled or
+// the stream is closed.
+func (c *Client) Events(ctx context.Context) (*EventStream, error) {
+	resp, err := c.open(ctx, request{method: http.MethodGet, path: "/api/events"})
+	if err != nil {
+		return nil, err
+	}
+	return &EventStream{body: resp.Body, scanner: bufio.NewScanner(resp.Body)}, nil
+}
+
+// Next blocks until the next event is received. It returns io.EOF once the server ended the stream.
+func (s *EventStream) Next() (*todo.Event, error) {
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case line == "" && data.Len() > 0:
+			var event todo.Event
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return nil, fmt.Errorf("invalid event: %w", err)
+			}
+			return &event, nil
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// comments keep the connection alive and the event type is repeated in the data
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close ends the stream.
+func (s *EventStream) Close() error {
+	return s.body.Close()
+}
+
+// DialList opens the WebSocket of a list on which its changes are received and item mutations can be submitted.
+func (c *Client) DialList(ctx context.Context, id int) (*websocket.Conn, error) {
+	u := strings.TrimSuffix(c.URL, "/") + fmt.Sprintf("/api/todos/%d/ws", id)
+	u = "ws" + strings.TrimPrefix(u, "http")
+
+	header := make(http.Header)
+	if c.APIKey != "" {
+		header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	dialer := *websocket.DefaultDialer
+	dialer.Jar = c.HTTPClient.Jar
+	conn, resp, err := dialer.DialContext(ctx, u, header)
+	if err != nil && resp != nil {
+		defer resp.Body.Close()
+		return nil, decodeError(resp)
+	} else if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
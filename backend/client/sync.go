@@ -0,0 +1,32 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// FindChanges returns the changes after the since token.
+func (c *Client) FindChanges(ctx context.Context, since string) (*todo.ChangeSet, error) {
+	var changes todo.ChangeSet
+	if err := c.do(ctx, http.MethodGet, "/api/sync?since="+url.QueryEscape(since), nil, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// PushMutations applies the mutations in order.
+func (c *Client) PushMutations(ctx context.Context, mutations []*todo.Mutation) ([]*todo.MutationResult, error) {
+	var resp struct {
+		Results []*todo.MutationResult `json:"results"`
+	}
+	req := struct {
+		Mutations []*todo.Mutation `json:"mutations"`
+	}{mutations}
+	if err := c.do(ctx, http.MethodPost, "/api/sync", req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// FindLists returns the lists of the current user with their items.
+func (c *Client) FindLists(ctx context.Context) ([]*todo.List, error) {
+	var lists []*todo.List
+	if err := c.do(ctx, http.MethodGet, "/api/todos", nil, &lists); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// CreateList creates a list and updates it with the server's copy.
+func (c *Client) CreateList(ctx context.Context, list *todo.List) error {
+	return c.do(ctx, http.MethodPost, "/api/todos", list, list)
+}
+
+// FindList returns a list with its items.
+func (c *Client) FindList(ctx context.Context, id int) (*todo.List, error) {
+	var list todo.List
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/todos/%d", id), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpdateList updates the name or completed state of a list.
+func (c *Client) UpdateList(ctx context.Context, id int, upd todo.ListUpdate) (*todo.List, error) {
+	var list todo.List
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/todos/%d", id), upd, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// DeleteList deletes a list and its items.
+func (c *Client) DeleteList(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/todos/%d", id), nil, nil)
+}
+
+// CreateItem creates an item in the list item.ListID and updates it with the server's copy.
+func (c *Client) CreateItem(ctx context.Context, item *todo.Item) error {
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/api/todos/%d", item.ListID), item, item)
+}
+
+// FindItem returns an item of a list.
+func (c *Client) FindItem(ctx context.Context, listID, id int) (*todo.Item, error) {
+	var item todo.Item
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/todos/%d/%d", listID, id), nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateItem updates the name or completed state of an item.
+func (c *Client) UpdateItem(ctx context.Context, listID, id int, upd todo.ItemUpdate) (*todo.Item, error) {
+	var item todo.Item
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/todos/%d/%d", listID, id), upd, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// DeleteItem deletes an item.
+func (c *Client) DeleteItem(ctx context.Context, listID, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/todos/%d/%d", listID, id), nil, nil)
+}
+
+// FindListActivity returns the activity of a list, newest first. The list ID of the filter is ignored.
+func (c *Client) FindListActivity(ctx context.Context, id int, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	var activity []*todo.Activity
+	r := request{method: http.MethodGet, path: fmt.Sprintf("/api/todos/%d/activity", id), query: activityQuery(f)}
+	if err := c.send(ctx, r, &activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// FindActivity returns the activity of all users, newest first. It requires the ServerAPIKey.
+func (c *Client) FindActivity(ctx context.Context, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	query := activityQuery(f)
+	if f.UserID != nil {
+		query.Set("user", strconv.Itoa(*f.UserID))
+	}
+
+	var activity []*todo.Activity
+	if err := c.send(ctx, request{method: http.MethodGet, path: "/api/audit", query: query, server: true}, &activity); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+func activityQuery(f todo.ActivityFilter) url.Values {
+	query := make(url.Values)
+	if f.Action != nil {
+		query.Set("action", *f.Action)
+	}
+	if f.Since != nil {
+		query.Set("since", f.Since.Format(time.RFC3339))
+	}
+	if f.Until != nil {
+		query.Set("until", f.Until.Format(time.RFC3339))
+	}
+	if f.Limit > 0 {
+		query.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Offset > 0 {
+		query.Set("offset", strconv.Itoa(f.Offset))
+	}
+	return query
+}
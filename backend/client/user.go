@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	todohttp "github.com/cmokbel1/todo-app/backend/http"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Me returns the current user.
+func (c *Client) Me(ctx context.Context) (*todo.User, error) {
+	var user todo.User
+	if err := c.do(ctx, http.MethodGet, "/api/user", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindAPIKey returns the API key of the current user.
+func (c *Client) FindAPIKey(ctx context.Context) (string, error) {
+	var apiKey string
+	if err := c.do(ctx, http.MethodGet, "/api/user/key", nil, &apiKey); err != nil {
+		return "", err
+	}
+	return apiKey, nil
+}
+
+// Login starts a session with the name and password of a user. The session cookie is kept in the cookie jar of
+// the HTTPClient and used instead of the APIKey, together with the CSRF token of the session.
+func (c *Client) Login(ctx context.Context, name, password string) (*todo.User, error) {
+	var user todo.User
+	if err := c.do(ctx, http.MethodPost, "/api/user/login", &todo.User{Name: name, Password: password}, &user); err != nil {
+		return nil, err
+	}
+
+	var token todohttp.CSRFToken
+	if err := c.do(ctx, http.MethodGet, "/api/csrf", nil, &token); err != nil {
+		return nil, err
+	}
+	c.csrfToken = token.Token
+	return &user, nil
+}
+
+// Logout ends the session started by Login.
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodDelete, "/api/user/logout", nil, nil); err != nil {
+		return err
+	}
+	c.csrfToken = ""
+	return nil
+}
+
+type feedResponse struct {
+	URL string `json:"url"`
+}
+
+// FeedURL returns the secret URL of the calendar feed of the current user.
+func (c *Client) FeedURL(ctx context.Context) (string, error) {
+	var resp feedResponse
+	if err := c.do(ctx, http.MethodGet, "/api/user/feed", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// ResetFeedURL replaces the feed token of the current user and returns the new feed URL.
+func (c *Client) ResetFeedURL(ctx context.Context) (string, error) {
+	var resp feedResponse
+	if err := c.do(ctx, http.MethodPost, "/api/user/feed", nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// DeleteAccount schedules the deletion of the current user's account, the password confirms the request.
+func (c *Client) DeleteAccount(ctx context.Context, password string) (*todo.User, error) {
+	var user todo.User
+	req := struct {
+		Password string `json:"password"`
+	}{password}
+	if err := c.do(ctx, http.MethodDelete, "/api/user", req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CancelAccountDeletion cancels the scheduled deletion of the current user's account.
+func (c *Client) CancelAccountDeletion(ctx context.Context) (*todo.User, error) {
+	var user todo.User
+	if err := c.do(ctx, http.MethodDelete, "/api/user/deletion", nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateAccountExport requests an archive of all of the current user's data.
+func (c *Client) CreateAccountExport(ctx context.Context) (*todo.AccountExport, error) {
+	var export todo.AccountExport
+	if err := c.do(ctx, http.MethodPost, "/api/user/export", nil, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindAccountExport returns the status of an export.
+func (c *Client) FindAccountExport(ctx context.Context, id int) (*todo.AccountExport, error) {
+	var export todo.AccountExport
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/user/export/%d", id), nil, &export); err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// DownloadAccountExport returns the ZIP archive of a ready export, it can only be downloaded once.
+func (c *Client) DownloadAccountExport(ctx context.Context, id int) ([]byte, error) {
+	return c.download(ctx, fmt.Sprintf("/api/user/export/%d/download", id))
+}
+
+// FindUsers returns all users. It requires the ServerAPIKey.
+func (c *Client) FindUsers(ctx context.Context) ([]*todo.User, error) {
+	var users []*todo.User
+	if err := c.send(ctx, request{method: http.MethodGet, path: "/api/users", server: true}, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// CreateUser registers a user and updates it with the server's copy. The password is cleared.
+func (c *Client) CreateUser(ctx context.Context, user *todo.User) error {
+	if err := c.do(ctx, http.MethodPost, "/api/users", user, user); err != nil {
+		return err
+	}
+	user.Password = ""
+	return nil
+}
+
+// UpdateUser updates the name or email of the current user.
+func (c *Client) UpdateUser(ctx context.Context, id int, upd todo.UserUpdate) (*todo.User, error) {
+	var user todo.User
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/users/%d", id), upd, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser deletes a user immediately. It requires the ServerAPIKey.
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	return c.send(ctx, request{method: http.MethodDelete, path: fmt.Sprintf("/api/users/%d", id), server: true}, nil)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// FindWebhooks returns the webhooks of the current user.
+func (c *Client) FindWebhooks(ctx context.Context) ([]*todo.Webhook, error) {
+	var webhooks []*todo.Webhook
+	if err := c.do(ctx, http.MethodGet, "/api/webhooks", nil, &webhooks); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// CreateWebhook creates a webhook and updates it with the server's copy, including the generated secret.
+func (c *Client) CreateWebhook(ctx context.Context, w *todo.Webhook) error {
+	return c.do(ctx, http.MethodPost, "/api/webhooks", w, w)
+}
+
+// FindWebhook returns a webhook.
+func (c *Client) FindWebhook(ctx context.Context, id int) (*todo.Webhook, error) {
+	var w todo.Webhook
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/webhooks/%d", id), nil, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// DeleteWebhook deletes a webhook and its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/webhooks/%d", id), nil, nil)
+}
+
+// FindWebhookDeliveries returns the deliveries of the webhook f.WebhookID, newest first.
+func (c *Client) FindWebhookDeliveries(ctx context.Context, f todo.WebhookDeliveryFilter) ([]*todo.WebhookDelivery, error) {
+	if f.WebhookID == nil {
+		return nil, todo.Err(todo.EINVALID, "webhook id required")
+	}
+
+	query := make(url.Values)
+	if f.Status != nil {
+		query.Set("status", *f.Status)
+	}
+	if f.Limit > 0 {
+		query.Set("limit", strconv.Itoa(f.Limit))
+	}
+
+	var deliveries []*todo.WebhookDelivery
+	r := request{method: http.MethodGet, path: fmt.Sprintf("/api/webhooks/%d/deliveries", *f.WebhookID), query: query}
+	if err := c.send(ctx, r, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/crypto"
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// ErrUsage is returned when the command line arguments are invalid. The usage has already been printed.
+var ErrUsage = errors.New("usage")
+
+// AdminCommand runs a one-off administrative task against the database of an instance, so that operators do not
+// need to call the admin endpoints of a running server. Changes are made without a user in the context and are
+// recorded as made by the server.
+type AdminCommand struct {
+	Config Config
+
+	DB              *postgres.DB
+	UserService     todo.UserService
+	ItemListService todo.ItemListService
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// configFile is the path of the config file set by the -config flag.
+	configFile string
+}
+
+func NewAdminCommand() *AdminCommand {
+	return &AdminCommand{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}
+}
+
+// Run runs the subcommand name, one of migrate, user, apikey or check-config, with the arguments following it.
+func (cmd *AdminCommand) Run(ctx context.Context, name string, args []string) error {
+	if name == "check-config" {
+		return cmd.CheckConfig(ctx, args)
+	}
+
+	var sub string
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	switch name + " " + sub {
+	case "migrate up":
+		return cmd.MigrateUp(ctx, args)
+	case "migrate down":
+		return cmd.MigrateDown(ctx, args)
+	case "migrate redo":
+		return cmd.MigrateRedo(ctx, args)
+	case "migrate status":
+		return cmd.MigrateStatus(ctx, args)
+	case "user create":
+		return cmd.CreateUser(ctx, args)
+	case "user delete":
+		return cmd.DeleteUser(ctx, args)
+	case "user reset-password":
+		return cmd.ResetPassword(ctx, args)
+	case "user list":
+		return cmd.ListUsers(ctx, args)
+	case "apikey rotate":
+		return cmd.RotateAPIKey(ctx, args)
+	}
+	fmt.Fprint(cmd.Stderr, usage)
+	return ErrUsage
+}
+
+// MigrateUp applies all pending migrations and prints the state of the migrations.
+func (cmd *AdminCommand) MigrateUp(ctx context.Context, args []string) error {
+	if err := cmd.open(ctx, cmd.flagSet("migrate up"), args, 0); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	if err := cmd.DB.Migrate(); err != nil {
+		return fmt.Errorf("failed to migrate db: %v", err)
+	}
+	return cmd.printMigrations(ctx)
+}
+
+// MigrateDown rolls back the latest applied migration and prints the state of the migrations.
+func (cmd *AdminCommand) MigrateDown(ctx context.Context, args []string) error {
+	if err := cmd.open(ctx, cmd.flagSet("migrate down"), args, 0); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	if err := cmd.DB.MigrateDown(); err != nil {
+		return fmt.Errorf("failed to roll back migration: %v", err)
+	}
+	return cmd.printMigrations(ctx)
+}
+
+// MigrateRedo rolls back the latest applied migration, applies it again and prints the state of the migrations.
+func (cmd *AdminCommand) MigrateRedo(ctx context.Context, args []string) error {
+	if err := cmd.open(ctx, cmd.flagSet("migrate redo"), args, 0); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	if err := cmd.DB.MigrateRedo(); err != nil {
+		return fmt.Errorf("failed to redo migration: %v", err)
+	}
+	return cmd.printMigrations(ctx)
+}
+
+// MigrateStatus prints the state of the migrations.
+func (cmd *AdminCommand) MigrateStatus(ctx context.Context, args []string) error {
+	if err := cmd.open(ctx, cmd.flagSet("migrate status"), args, 0); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+	return cmd.printMigrations(ctx)
+}
+
+func (cmd *AdminCommand) printMigrations(ctx context.Context) error {
+	migrations, err := cmd.DB.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, m := range migrations {
+		appliedAt := "pending"
+		if !m.AppliedAt.IsZero() {
+			appliedAt = m.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", m.Version, m.Name, appliedAt)
+	}
+	return w.Flush()
+}
+
+// CreateUser creates a user with a default list, like registering in the web app does, and prints their API key.
+func (cmd *AdminCommand) CreateUser(ctx context.Context, args []string) error {
+	var email string
+	fs := cmd.flagSet("user create")
+	fs.StringVar(&email, "email", "", "email address of the user")
+	if err := cmd.open(ctx, fs, args, 1); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	password, generated, err := cmd.readPassword()
+	if err != nil {
+		return err
+	}
+
+	user := &todo.User{Name: fs.Arg(0), Password: password}
+	if email != "" {
+		user.Email = &email
+	}
+	if err := cmd.UserService.CreateUser(ctx, user); err != nil {
+		return err
+	}
+	list := &todo.List{Name: "My first list"}
+	if err := cmd.ItemListService.CreateList(todo.NewContextWithUser(ctx, user), list); err != nil {
+		return fmt.Errorf("failed to create list for new user %q: %v", user.Name, err)
+	}
+
+	fmt.Fprintf(cmd.Stdout, "created user %s (id = %d)\napi key: %s\n", user.Name, user.ID, user.APIKey)
+	if generated {
+		fmt.Fprintf(cmd.Stdout, "password: %s\n", password)
+	}
+	return nil
+}
+
+// DeleteUser deletes a user and all of their data immediately, without a grace period.
+func (cmd *AdminCommand) DeleteUser(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("user delete")
+	if err := cmd.open(ctx, fs, args, 1); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	user, err := cmd.UserService.FindUserByName(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	} else if err := cmd.UserService.DeleteUser(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.Stdout, "deleted user %s (id = %d)\n", user.Name, user.ID)
+	return nil
+}
+
+// ResetPassword replaces the password of a user.
+func (cmd *AdminCommand) ResetPassword(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("user reset-password")
+	if err := cmd.open(ctx, fs, args, 1); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	user, err := cmd.UserService.FindUserByName(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	password, generated, err := cmd.readPassword()
+	if err != nil {
+		return err
+	}
+	if _, err := cmd.UserService.ResetPassword(ctx, user.ID, password); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "reset the password of user %s (id = %d)\n", user.Name, user.ID)
+	if generated {
+		fmt.Fprintf(cmd.Stdout, "password: %s\n", password)
+	}
+	return nil
+}
+
+// ListUsers prints all users as a table or as JSON.
+func (cmd *AdminCommand) ListUsers(ctx context.Context, args []string) error {
+	var asJSON bool
+	fs := cmd.flagSet("user list")
+	fs.BoolVar(&asJSON, "json", false, "print JSON instead of a table")
+	if err := cmd.open(ctx, fs, args, 0); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	users, err := cmd.UserService.FindUsers(ctx, todo.UserFilter{})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		enc := json.NewEncoder(cmd.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(users)
+	}
+
+	w := tabwriter.NewWriter(cmd.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tEMAIL\tCREATED AT\tDELETE AT")
+	for _, user := range users {
+		var email, deleteAt string
+		if user.Email != nil {
+			email = *user.Email
+		}
+		if user.DeleteAt != nil {
+			deleteAt = user.DeleteAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", user.ID, user.Name, email, user.CreatedAt.Format(time.RFC3339), deleteAt)
+	}
+	return w.Flush()
+}
+
+// RotateAPIKey replaces the API key of a user and prints the new key. The previous key stops working.
+func (cmd *AdminCommand) RotateAPIKey(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("apikey rotate")
+	if err := cmd.open(ctx, fs, args, 1); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	user, err := cmd.UserService.FindUserByName(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user, err = cmd.UserService.RotateAPIKey(ctx, user.ID); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.Stdout, user.APIKey)
+	return nil
+}
+
+// CheckConfig loads and validates the config and, with -connect, checks that the database is reachable.
+func (cmd *AdminCommand) CheckConfig(ctx context.Context, args []string) error {
+	var connect bool
+	fs := cmd.flagSet("check-config")
+	fs.BoolVar(&connect, "connect", false, "connect to the database and report pending migrations")
+	if err := cmd.parse(ctx, fs, args, 0); err != nil {
+		return err
+	}
+
+	if policy, _ := cmd.Config.CORSPolicies(); policy.AllowsAnyOrigin() {
+		fmt.Fprintln(cmd.Stderr, "warning: cors.allowed_origins allows any origin")
+	}
+	if cmd.Config.HTTP.Domain != "localhost" && !cmd.Config.HTTP.TLS {
+		fmt.Fprintln(cmd.Stderr, "warning: http.tls is disabled, session cookies are sent without the Secure flag")
+	}
+	fmt.Fprintf(cmd.Stdout, "config %s is valid\n", cmd.configFile)
+
+	if !connect {
+		return nil
+	}
+	if err := cmd.openDB(ctx); err != nil {
+		return err
+	}
+	defer cmd.DB.Close()
+
+	migrations, err := cmd.DB.Migrations(ctx)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, m := range migrations {
+		if m.AppliedAt.IsZero() {
+			pending++
+		}
+	}
+	fmt.Fprintf(cmd.Stdout, "connected to the database, %d of %d migrations pending\n", pending, len(migrations))
+	return nil
+}
+
+func (cmd *AdminCommand) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("todo-server "+name, flag.ContinueOnError)
+	fs.SetOutput(cmd.Stderr)
+	fs.StringVar(&cmd.configFile, "config", os.Getenv("TODO_CONFIG"), "path to the config file")
+	return fs
+}
+
+// parse parses the flags, which may follow the arguments, requires exactly n arguments and loads the config.
+func (cmd *AdminCommand) parse(ctx context.Context, fs *flag.FlagSet, args []string, n int) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		} else if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if err := fs.Parse(positional); err != nil {
+		return err
+	} else if fs.NArg() != n {
+		fs.Usage()
+		return ErrUsage
+	}
+
+	config, err := LoadConfig(ctx, cmd.configFile, "")
+	if err != nil {
+		return err
+	} else if err := config.Validate(); err != nil {
+		return err
+	}
+	cmd.Config = config
+	return nil
+}
+
+// open parses the arguments like parse and opens the database.
+func (cmd *AdminCommand) open(ctx context.Context, fs *flag.FlagSet, args []string, n int) error {
+	if err := cmd.parse(ctx, fs, args, n); err != nil {
+		return err
+	}
+	return cmd.openDB(ctx)
+}
+
+func (cmd *AdminCommand) openDB(ctx context.Context) error {
+	cmd.DB = postgres.New(cmd.Config.DB.DSN)
+	cmd.DB.EnableQueryLogging = cmd.Config.DB.EnableQueryLogging
+	cmd.DB.Logger = cmd.Config.NewLogger(cmd.Stderr)
+	if err := cmd.DB.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open db: %v", err)
+	}
+	cmd.UserService = postgres.NewUserService(cmd.DB)
+	cmd.ItemListService = postgres.NewItemListService(cmd.DB)
+	return nil
+}
+
+// readPassword reads a password from the first line of Stdin. If it is empty a random password is generated.
+func (cmd *AdminCommand) readPassword() (password string, generated bool, err error) {
+	fmt.Fprint(cmd.Stderr, "Password (empty to generate one): ")
+	line, err := bufio.NewReader(cmd.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", false, err
+	}
+	if password = strings.TrimRight(line, "\r\n"); password != "" {
+		return password, false, nil
+	}
+	return crypto.RandomString(), true, nil
+}
@@ -6,16 +6,27 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/alexedwards/scs/postgresstore"
+	"github.com/cmokbel1/todo-app/backend/account"
 	"github.com/cmokbel1/todo-app/backend/aws"
 	"github.com/cmokbel1/todo-app/backend/crypto"
+	"github.com/cmokbel1/todo-app/backend/grpc"
 	"github.com/cmokbel1/todo-app/backend/http"
+	"github.com/cmokbel1/todo-app/backend/inmem"
 	"github.com/cmokbel1/todo-app/backend/postgres"
 	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/cmokbel1/todo-app/backend/tracing"
+	"github.com/cmokbel1/todo-app/backend/webhook"
+	"github.com/jackc/pgx/v4"
 )
 
 var (
@@ -31,18 +42,63 @@ func main() {
 
 	logger := todo.NewLogger()
 	logger.SetOutput(os.Stderr)
-	logger.Info(todo.BuildDetails())
 	os.Exit(realMain(logger))
 }
 
 func realMain(logger todo.Logger) int {
-	ctx, cancel := context.WithCancel(context.Background())
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, os.Kill)
-	go func() { <-c; cancel() }()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// a second signal terminates the process at once instead of waiting for the shutdown
+	go func() { <-ctx.Done(); stop() }()
+
+	// without a subcommand the server is started, as it was before there were subcommands
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case "serve":
+		return serve(ctx, logger, args)
+	case "migrate", "user", "apikey", "check-config":
+		err := NewAdminCommand().Run(ctx, name, args)
+		if errors.Is(err, ErrUsage) || errors.Is(err, flag.ErrHelp) {
+			return 2
+		} else if err != nil {
+			logger.Error(err.Error())
+			return 1
+		}
+		return 0
+	case "help":
+		fmt.Fprint(os.Stdout, usage)
+		return 0
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+const usage = `todo-server runs and administers the todo server.
+
+Usage:
+
+	todo-server [serve] [-config file] [-assets dir] [-migrate=false]
+	todo-server migrate up|down|redo|status [-config file]
+	todo-server user create <name> [-email address] [-config file]
+	todo-server user delete|reset-password <name> [-config file]
+	todo-server user list [-json] [-config file]
+	todo-server apikey rotate <name> [-config file]
+	todo-server check-config [-connect] [-config file]
+
+The config file defaults to the TODO_CONFIG environment variable. Passwords are read from the first line of stdin,
+a random password is generated and printed if it is empty.
+`
+
+func serve(ctx context.Context, logger todo.Logger, args []string) int {
+	logger.Info(todo.BuildDetails())
 
 	app := NewApp()
-	if err := app.ParseFlagsAndLoadConfig(ctx, os.Args[1:]); err != nil {
+	if err := app.ParseFlagsAndLoadConfig(ctx, args); err != nil {
 		logger.Error(err.Error())
 		return 1
 	}
@@ -54,13 +110,18 @@ func realMain(logger todo.Logger) int {
 		}
 		return 1
 	}
-	<-ctx.Done()
 
+	code := 0
+	if err := app.Wait(ctx); err != nil {
+		logger.Error(err.Error())
+		code = 1
+	}
+	logger.Info("shutting down")
 	if err := app.Close(); err != nil {
 		logger.Error(err.Error())
 		return 1
 	}
-	return 0
+	return code
 }
 
 func NewApp() *App {
@@ -68,49 +129,125 @@ func NewApp() *App {
 		Logger:     todo.NewLogger(),
 		DB:         postgres.New(""),
 		HTTPServer: http.NewServer(),
+		Migrate:    true,
 	}
 }
 
 type App struct {
 	Config Config
-
-	Logger     todo.Logger
-	HTTPServer *http.Server
-	DB         *postgres.DB
+	// Migrate applies pending migrations on start. It is disabled when migrations run as a separate deploy step.
+	Migrate bool
+
+	Logger       todo.Logger
+	HTTPServer   *http.Server
+	DB           *postgres.DB
+	EventService *postgres.EventService
+	Dispatcher   *webhook.Dispatcher
+	// AccountWorker builds account exports and deletes accounts in the background.
+	AccountWorker *account.Worker
+	// GRPCServer is only started if an address is configured.
+	GRPCServer *grpc.Server
+	// DebugServer is only started if an address is configured.
+	DebugServer *http.DebugServer
+	// SessionStore deletes expired sessions in the background.
+	SessionStore *postgresstore.PostgresStore
+	// TracerProvider exports the spans of requests, transactions and queries if an exporter is configured.
+	TracerProvider *tracing.Provider
 }
 
 func (app *App) Run(ctx context.Context) error {
-	logger := todo.NewLogger()
-	if app.Config.Log.Enabled {
-		logger.SetLevel(app.Config.Log.Level)
-		logger.SetOutput(os.Stderr)
+	app.Logger = app.Config.NewLogger(os.Stderr)
+
+	var err error
+	if app.TracerProvider, err = tracing.NewProvider(ctx, tracing.Config{
+		Exporter:    app.Config.Tracing.Exporter,
+		Endpoint:    app.Config.Tracing.Endpoint,
+		Insecure:    app.Config.Tracing.Insecure,
+		ServiceName: "todo-server",
+		Stdout:      os.Stdout,
+	}); err != nil {
+		return fmt.Errorf("failed to create tracer provider: %v", err)
 	}
-	app.Logger = logger
 
 	app.DB = postgres.New(app.Config.DB.DSN)
 	app.DB.EnableQueryLogging = app.Config.DB.EnableQueryLogging
 	app.DB.Logger = app.Logger
+	app.DB.TracerProvider = app.TracerProvider
 	if err := app.DB.Open(ctx); err != nil {
 		return fmt.Errorf("failed to open db: %v", err)
 	}
 
-	if err := app.DB.Migrate(); err != nil {
-		return fmt.Errorf("failed to migrate db: %v", err)
+	if app.Migrate {
+		if err := app.DB.Migrate(); err != nil {
+			return fmt.Errorf("failed to migrate db: %v", err)
+		}
+	}
+	app.DB.MonitorMetrics()
+
+	// events are fanned out to all servers through the database and delivered by an in-process event bus
+	app.EventService = postgres.NewEventService(app.DB, inmem.NewEventService())
+	if err := app.EventService.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open event listener: %v", err)
 	}
+	app.DB.EventService = app.EventService
+
+	webhookService := postgres.NewWebhookService(app.DB)
+	app.Dispatcher = webhook.NewDispatcher(webhookService)
+	app.Dispatcher.Logger = app.Logger
+	app.Dispatcher.Open()
 
 	app.HTTPServer.Addr = app.Config.HTTP.Addr
 	app.HTTPServer.APIKey = *app.Config.HTTP.APIKey
 	app.HTTPServer.AssetsDirectory = app.Config.HTTP.AssetsDirectory
 	app.HTTPServer.Domain = app.Config.HTTP.Domain
 	app.HTTPServer.TLS = app.Config.HTTP.TLS
-	app.HTTPServer.CORSAllowedOrigins = app.Config.HTTP.CORSAllowedOrigins
+	app.HTTPServer.CertFile = app.Config.HTTP.CertFile
+	app.HTTPServer.KeyFile = app.Config.HTTP.KeyFile
+	app.HTTPServer.ClientCAFile = app.Config.HTTP.ClientCAFile
+	app.HTTPServer.RedirectAddr = app.Config.HTTP.RedirectAddr
+	app.HTTPServer.RateLimits = app.Config.RateLimits()
+	if app.HTTPServer.TrustedProxies, err = app.Config.TrustedProxies(); err != nil {
+		return err
+	}
+	if app.Config.RateLimit.Store == RateLimitStorePostgres {
+		app.HTTPServer.RateLimitService = postgres.NewRateLimitService(app.DB)
+	} else {
+		app.HTTPServer.RateLimitService = inmem.NewRateLimitService()
+	}
+	app.HTTPServer.HSTSMaxAge = time.Duration(app.Config.HTTP.HSTSMaxAgeSeconds) * time.Second
+	app.HTTPServer.CORS, app.HTTPServer.CORSRoutes = app.Config.CORSPolicies()
+	app.HTTPServer.ShutdownDelay = time.Duration(app.Config.HTTP.ShutdownDelaySeconds) * time.Second
+	app.HTTPServer.ReadinessChecks = []http.HealthCheck{
+		{Name: "database", Check: app.DB.Ping},
+		{Name: "migrations", Check: app.DB.CheckMigrations},
+	}
 	app.HTTPServer.Logger = app.Logger
+	app.HTTPServer.TracerProvider = app.TracerProvider
 	app.HTTPServer.ItemListService = postgres.NewItemListService(app.DB)
+	app.HTTPServer.CalendarObjectService = postgres.NewCalendarObjectService(app.DB)
 	app.HTTPServer.UserService = postgres.NewUserService(app.DB)
+	app.HTTPServer.SyncService = postgres.NewSyncService(app.DB)
+	app.HTTPServer.EventService = app.EventService
+	app.HTTPServer.WebhookService = webhookService
+	app.HTTPServer.ActivityService = postgres.NewActivityService(app.DB)
+	app.HTTPServer.AccountExportService = postgres.NewAccountExportService(app.DB)
+	if days := app.Config.Account.DeletionGracePeriodDays; days > 0 {
+		app.HTTPServer.AccountDeletionGracePeriod = time.Duration(days) * 24 * time.Hour
+	}
+
+	app.AccountWorker = account.NewWorker(
+		app.HTTPServer.AccountExportService,
+		app.HTTPServer.UserService,
+		app.HTTPServer.ItemListService,
+		app.HTTPServer.ActivityService,
+	)
+	app.AccountWorker.Logger = app.Logger
+	app.AccountWorker.Open()
 
 	{
 		mgr := http.NewSessionManager()
-		mgr.Store = postgres.NewSessionStore(app.DB)
+		app.SessionStore = postgres.NewSessionStore(app.DB)
+		mgr.Store = app.SessionStore
 		mgr.Cookie.Domain = app.Config.HTTP.Domain
 		mgr.Cookie.Secure = app.Config.HTTP.TLS
 		app.HTTPServer.SessionManager = mgr
@@ -120,9 +257,30 @@ func (app *App) Run(ctx context.Context) error {
 		return err
 	}
 
-	go func() { http.ListenAndServeDebug() }()
+	if app.Config.GRPC.Addr != "" {
+		app.GRPCServer = grpc.NewServer()
+		app.GRPCServer.Addr = app.Config.GRPC.Addr
+		app.GRPCServer.APIKey = *app.Config.HTTP.APIKey
+		app.GRPCServer.Logger = app.Logger
+		app.GRPCServer.ItemListService = app.HTTPServer.ItemListService
+		app.GRPCServer.UserService = app.HTTPServer.UserService
+		app.GRPCServer.EventService = app.EventService
+		if err := app.GRPCServer.Listen(); err != nil {
+			return err
+		}
+		app.Logger.Infof("grpc server running at %q", app.Config.GRPC.Addr)
+	}
 
-	app.Logger.Infof("server running at %q, debug server running at %q", app.HTTPServer.URL(), "http://localhost:6060")
+	if addr := app.Config.Debug.Addr; addr != "" {
+		app.DebugServer = http.NewDebugServer(addr)
+		if err := app.DebugServer.Listen(); err != nil {
+			return fmt.Errorf("failed to start debug server: %v", err)
+		}
+		app.Logger.Infof("server running at %q, debug server running at %q", app.HTTPServer.URL(), addr)
+	} else {
+		app.Logger.Infof("server running at %q", app.HTTPServer.URL())
+	}
+	app.HTTPServer.SetStarted()
 	return nil
 }
 
@@ -130,35 +288,126 @@ func (app *App) ParseFlagsAndLoadConfig(ctx context.Context, args []string) erro
 	var configFile string
 	var assetsDir string
 
-	fs := flag.NewFlagSet("todo", flag.ContinueOnError)
+	fs := flag.NewFlagSet("todo-server serve", flag.ContinueOnError)
 	fs.StringVar(&configFile, "config", os.Getenv("TODO_CONFIG"), "path to the config file")
 	fs.StringVar(&assetsDir, "assets", os.Getenv("TODO_ASSETS"), "path to the frontend assets directory")
+	fs.BoolVar(&app.Migrate, "migrate", app.Migrate, "apply pending migrations on start")
 
 	if err := fs.Parse(args); err != nil {
 		return err
 	} else if app.Config, err = LoadConfig(ctx, configFile, assetsDir); err != nil {
 		return err
+	} else if err := app.Config.Validate(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
+// Wait blocks until ctx is done or one of the servers stops serving, whose error it returns.
+func (app *App) Wait(ctx context.Context) error {
+	var grpcErr, debugErr <-chan error
+	if app.GRPCServer != nil {
+		grpcErr = app.GRPCServer.Err()
+	}
+	if app.DebugServer != nil {
+		debugErr = app.DebugServer.Err()
+	}
+
+	// SIGHUP reloads the TLS certificate
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-app.HTTPServer.Err():
+			return fmt.Errorf("http server failed: %v", err)
+		case err := <-grpcErr:
+			return fmt.Errorf("grpc server failed: %v", err)
+		case err := <-debugErr:
+			return fmt.Errorf("debug server failed: %v", err)
+		case <-hup:
+			if err := app.HTTPServer.ReloadCertificate(); err != nil {
+				app.Logger.Errorf("failed to reload certificate: %v", err)
+			} else if app.Config.HTTP.CertFile != "" {
+				app.Logger.Infof("reloaded certificate %q", app.Config.HTTP.CertFile)
+			}
+		}
+	}
+}
+
+// Close shuts down the servers, waiting for pending requests until http.shutdown_timeout_seconds passed, and then
+// stops the background workers and closes the database. Everything is closed even if something fails, the first
+// error is returned.
 func (app *App) Close() error {
-	if app.HTTPServer != nil {
-		if err := app.HTTPServer.Shutdown(); err != nil {
-			return err
+	timeout := time.Duration(app.Config.HTTP.ShutdownTimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	check := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to close %s: %v", name, err))
 		}
 	}
 
+	// the HTTP and gRPC servers drain in parallel, so that neither waits for the deadline of the other
+	var grpcErr chan error
+	if app.GRPCServer != nil {
+		grpcErr = make(chan error, 1)
+		go func() { grpcErr <- app.GRPCServer.Shutdown(ctx) }()
+	}
+	if app.HTTPServer != nil {
+		check("http server", app.HTTPServer.Shutdown(ctx))
+	}
+	if grpcErr != nil {
+		check("grpc server", <-grpcErr)
+	}
+	// metrics are served until the other servers are drained
+	if app.DebugServer != nil {
+		check("debug server", app.DebugServer.Shutdown(ctx))
+	}
+
+	if app.SessionStore != nil {
+		app.SessionStore.StopCleanup()
+	}
+	if app.Dispatcher != nil {
+		check("webhook dispatcher", app.Dispatcher.Close())
+	}
+	if app.AccountWorker != nil {
+		check("account worker", app.AccountWorker.Close())
+	}
+	if app.EventService != nil {
+		check("event service", app.EventService.Close())
+	}
 	if app.DB != nil {
-		if err := app.DB.Close(); err != nil {
-			return err
-		}
+		check("db", app.DB.Close())
 	}
 
+	if app.TracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		check("tracer provider", app.TracerProvider.Shutdown(ctx))
+	}
+
+	if len(errs) > 0 {
+		for _, err := range errs[1:] {
+			app.Logger.Error(err.Error())
+		}
+		return errs[0]
+	}
 	return nil
 }
 
+// DefaultShutdownTimeout is how long the servers wait for pending requests on shutdown by default.
+const DefaultShutdownTimeout = 30 * time.Second
+
 type Config struct {
 	DB struct {
 		DSN                string `json:"dsn"`
@@ -168,25 +417,222 @@ type Config struct {
 	HTTP struct {
 		Addr string `json:"addr"`
 		// APIKey is the server's API key to access admin functionality.
-		APIKey             *string `json:"api_key,omitempty"`
-		Domain             string  `json:"domain"`
-		TLS                bool    `json:"tls"`
-		CORSAllowedOrigins string  `json:"cors_allowed_origins"`
-		AssetsDirectory    string  `json:"assets_directory"`
+		APIKey *string `json:"api_key,omitempty"`
+		Domain string  `json:"domain"`
+		TLS    bool    `json:"tls"`
+		// CORSAllowedOrigins is a comma separated list of the allowed origins which is used if cors.allowed_origins
+		// is not set.
+		//
+		// Deprecated: use cors.allowed_origins.
+		CORSAllowedOrigins string `json:"cors_allowed_origins"`
+		AssetsDirectory    string `json:"assets_directory"`
+		// CertFile and KeyFile are the certificate and key to serve HTTPS with, which requires TLS. They are reloaded
+		// on SIGHUP and once the files change.
+		CertFile string `json:"cert_file"`
+		KeyFile  string `json:"key_file"`
+		// ClientCAFile restricts the admin routes to clients with a certificate signed by one of its CAs.
+		ClientCAFile string `json:"client_ca_file"`
+		// RedirectAddr is the address which redirects plaintext requests to HTTPS, usually ":80".
+		RedirectAddr string `json:"redirect_addr"`
+		// HSTSMaxAgeSeconds is the max-age of the Strict-Transport-Security header sent with TLS, it is not sent if
+		// it is 0.
+		HSTSMaxAgeSeconds int `json:"hsts_max_age_seconds"`
+		// ShutdownDelaySeconds is how many seconds /readyz fails on shutdown before the server stops accepting
+		// connections, so that load balancers can drain traffic from it.
+		ShutdownDelaySeconds int `json:"shutdown_delay_seconds"`
+		// ShutdownTimeoutSeconds is how many seconds the servers wait for pending requests on shutdown, including the
+		// shutdown delay, before they are cut off. It defaults to 30.
+		ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
 	} `json:"http"`
 
+	// CORS is the CORS policy of the server, Routes override it for the routes under their path prefix.
+	CORS struct {
+		CORSConfig
+		Routes map[string]CORSConfig `json:"routes"`
+	} `json:"cors"`
+
+	GRPC struct {
+		// Addr is the address the gRPC API is served on, it is not served if Addr is empty.
+		Addr string `json:"addr"`
+	} `json:"grpc"`
+
+	Account struct {
+		// DeletionGracePeriodDays is how many days after a user requested the deletion of their account it is
+		// deleted. It defaults to 7.
+		DeletionGracePeriodDays int `json:"deletion_grace_period_days"`
+	} `json:"account"`
+
+	Debug struct {
+		// Addr is the address of the debug server which serves the Prometheus metrics at /metrics and the pprof
+		// profiles at /debug/pprof/. It defaults to ":6060", the debug server is disabled if Addr is empty.
+		Addr string `json:"addr"`
+	} `json:"debug"`
+
+	RateLimit struct {
+		// Store is where requests are counted: "memory", the default, counts them per server and "postgres" shares
+		// one budget per client between all servers.
+		Store string `json:"store"`
+		// TrustedProxies are the IP addresses and CIDR networks of the proxies whose X-Forwarded-For headers are
+		// trusted to identify clients.
+		TrustedProxies []string `json:"trusted_proxies"`
+		// Limits are the rate limits of the route groups "default", "login", "signup" and "api_key", which default to
+		// 60, 10, 5 and 120 requests per minute. A group is not limited if its requests are 0.
+		Limits map[string]RateLimitConfig `json:"limits"`
+	} `json:"rate_limit"`
+
+	Tracing struct {
+		// Exporter is where spans are exported to: "" disables tracing, "stdout" writes them to stdout and "otlp"
+		// sends them to an OTLP/HTTP collector.
+		Exporter string `json:"exporter"`
+		// Endpoint is the host and port of the OTLP collector, it defaults to localhost:4318.
+		Endpoint string `json:"endpoint"`
+		// Insecure sends spans to the OTLP collector without TLS.
+		Insecure bool `json:"insecure"`
+	} `json:"tracing"`
+
 	Log struct {
 		// If enabled the application logs to stderr.
 		Enabled bool   `json:"enabled"`
 		Level   string `json:"level"`
+		// Format is either "text", the default, "json" or "logfmt". The json and logfmt formats add the ID of the
+		// request, the user, the route, the status and the latency of requests as fields.
+		Format string `json:"format"`
 	} `json:"log"`
 }
 
+// The stores of the rate limit config.
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// RateLimitConfig allows a number of requests per client within a sliding window.
+type RateLimitConfig struct {
+	Requests      int `json:"requests"`
+	WindowSeconds int `json:"window_seconds"`
+}
+
+// CORSConfig is a CORS policy, the fields which are not set keep the default policy or, in cors.routes, the policy
+// of the server.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins like "https://todo.example.com", wildcards like "https://*.example.com" or
+	// "*" for any origin.
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers"`
+	ExposedHeaders []string `json:"exposed_headers"`
+	// AllowCredentials allows requests with the session cookie, it defaults to true unless any origin is allowed.
+	AllowCredentials *bool `json:"allow_credentials"`
+	// MaxAgeSeconds is how long browsers cache the answer to a preflight request, it defaults to 600.
+	MaxAgeSeconds *int `json:"max_age_seconds"`
+}
+
+// policy returns base with the fields of the config which are set.
+func (c CORSConfig) policy(base http.CORSPolicy) http.CORSPolicy {
+	policy := base
+	if c.AllowedOrigins != nil {
+		policy.AllowedOrigins = c.AllowedOrigins
+		policy.AllowCredentials = base.AllowCredentials && !policy.AllowsAnyOrigin()
+	}
+	if c.AllowedMethods != nil {
+		policy.AllowedMethods = c.AllowedMethods
+	}
+	if c.AllowedHeaders != nil {
+		policy.AllowedHeaders = c.AllowedHeaders
+	}
+	if c.ExposedHeaders != nil {
+		policy.ExposedHeaders = c.ExposedHeaders
+	}
+	if c.AllowCredentials != nil {
+		policy.AllowCredentials = *c.AllowCredentials
+	}
+	if c.MaxAgeSeconds != nil {
+		policy.MaxAge = time.Duration(*c.MaxAgeSeconds) * time.Second
+	}
+	return policy
+}
+
+// CORSPolicies returns the CORS policy of the server and the policies of the routes which override it.
+func (c Config) CORSPolicies() (http.CORSPolicy, map[string]http.CORSPolicy) {
+	config := c.CORS.CORSConfig
+	if config.AllowedOrigins == nil && c.HTTP.CORSAllowedOrigins != "" {
+		for _, origin := range strings.Split(c.HTTP.CORSAllowedOrigins, ",") {
+			config.AllowedOrigins = append(config.AllowedOrigins, strings.TrimSpace(origin))
+		}
+	}
+	policy := config.policy(http.DefaultCORSPolicy())
+
+	routes := make(map[string]http.CORSPolicy, len(c.CORS.Routes))
+	for prefix, route := range c.CORS.Routes {
+		routes[prefix] = route.policy(policy)
+	}
+	return policy, routes
+}
+
+// RateLimits returns the rate limits of the route groups.
+func (c Config) RateLimits() map[string]http.RateLimit {
+	limits := make(map[string]http.RateLimit, len(c.RateLimit.Limits))
+	for group, limit := range c.RateLimit.Limits {
+		limits[group] = http.RateLimit{Requests: limit.Requests, Window: time.Duration(limit.WindowSeconds) * time.Second}
+	}
+	return limits
+}
+
+// TrustedProxies returns the networks of the trusted proxies, single addresses are returned as networks of one.
+func (c Config) TrustedProxies() ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid rate_limit.trusted_proxies address %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate_limit.trusted_proxies network %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// NewLogger returns a logger of the configured format and level which writes to w, or discards everything if logging
+// is disabled.
+func (c Config) NewLogger(w io.Writer) todo.Logger {
+	if !c.Log.Enabled {
+		return todo.NewLogger()
+	}
+
+	switch c.Log.Format {
+	case todo.LogFormatJSON, todo.LogFormatLogfmt:
+		logger := todo.NewStructuredLogger(w, c.Log.Format)
+		logger.SetLevel(c.Log.Level)
+		return logger
+	}
+	logger := todo.NewLogger()
+	logger.SetLevel(c.Log.Level)
+	logger.SetOutput(w)
+	return logger
+}
+
 func DefaultConfig() Config {
 	var c Config
 	c.DB.DSN = ""
 	c.HTTP.Addr = "0.0.0.0:8058"
 	c.HTTP.Domain = "localhost"
+	c.HTTP.ShutdownTimeoutSeconds = int(DefaultShutdownTimeout / time.Second)
+	c.Debug.Addr = http.DefaultDebugAddr
+	c.RateLimit.Limits = make(map[string]RateLimitConfig)
+	for group, limit := range http.DefaultRateLimits() {
+		c.RateLimit.Limits[group] = RateLimitConfig{Requests: limit.Requests, WindowSeconds: int(limit.Window / time.Second)}
+	}
 	return c
 }
 
@@ -224,3 +670,124 @@ func LoadConfig(ctx context.Context, filename string, assetsDir string) (Config,
 	}
 	return config, nil
 }
+
+// Validate returns an error if the config cannot be used to run the server.
+func (c Config) Validate() error {
+	if c.DB.DSN == "" {
+		return errors.New("db.dsn is required")
+	} else if _, err := pgx.ParseConfig(c.DB.DSN); err != nil {
+		return fmt.Errorf("invalid db.dsn: %v", err)
+	}
+	if _, _, err := net.SplitHostPort(c.HTTP.Addr); err != nil {
+		return fmt.Errorf("invalid http.addr: %v", err)
+	}
+	if c.GRPC.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.GRPC.Addr); err != nil {
+			return fmt.Errorf("invalid grpc.addr: %v", err)
+		}
+	}
+	if c.Debug.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Debug.Addr); err != nil {
+			return fmt.Errorf("invalid debug.addr: %v", err)
+		}
+	}
+	if (c.HTTP.CertFile == "") != (c.HTTP.KeyFile == "") {
+		return errors.New("http.cert_file and http.key_file must be set together")
+	} else if c.HTTP.CertFile != "" && !c.HTTP.TLS {
+		return errors.New("http.cert_file requires http.tls")
+	}
+	if c.HTTP.ClientCAFile != "" && c.HTTP.CertFile == "" {
+		return errors.New("http.client_ca_file requires http.cert_file")
+	}
+	if c.HTTP.RedirectAddr != "" {
+		if c.HTTP.CertFile == "" {
+			return errors.New("http.redirect_addr requires http.cert_file")
+		} else if _, _, err := net.SplitHostPort(c.HTTP.RedirectAddr); err != nil {
+			return fmt.Errorf("invalid http.redirect_addr: %v", err)
+		}
+	}
+	if c.HTTP.HSTSMaxAgeSeconds < 0 {
+		return errors.New("http.hsts_max_age_seconds must not be negative")
+	}
+	if c.HTTP.ShutdownDelaySeconds < 0 {
+		return errors.New("http.shutdown_delay_seconds must not be negative")
+	}
+	if c.HTTP.ShutdownTimeoutSeconds <= 0 {
+		return errors.New("http.shutdown_timeout_seconds must be positive")
+	} else if c.HTTP.ShutdownTimeoutSeconds <= c.HTTP.ShutdownDelaySeconds {
+		return errors.New("http.shutdown_timeout_seconds must be greater than http.shutdown_delay_seconds")
+	}
+	if c.HTTP.APIKey != nil && *c.HTTP.APIKey == "" {
+		return errors.New("http.api_key must not be empty")
+	}
+	policy, routes := c.CORSPolicies()
+	if err := validateCORSPolicy("cors", policy); err != nil {
+		return err
+	}
+	for prefix, route := range routes {
+		if !strings.HasPrefix(prefix, "/") {
+			return fmt.Errorf("invalid cors.routes prefix %q, must start with /", prefix)
+		} else if err := validateCORSPolicy("cors.routes."+prefix, route); err != nil {
+			return err
+		}
+	}
+	switch strings.ToLower(c.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("invalid log.level %q, must be one of debug, info, warn or error", c.Log.Level)
+	}
+	switch c.Log.Format {
+	case "", todo.LogFormatText, todo.LogFormatJSON, todo.LogFormatLogfmt:
+	default:
+		return fmt.Errorf("invalid log.format %q, must be one of text, json or logfmt", c.Log.Format)
+	}
+	switch c.RateLimit.Store {
+	case "", RateLimitStoreMemory, RateLimitStorePostgres:
+	default:
+		return fmt.Errorf("invalid rate_limit.store %q, must be one of memory or postgres", c.RateLimit.Store)
+	}
+	if _, err := c.TrustedProxies(); err != nil {
+		return err
+	}
+	for group, limit := range c.RateLimit.Limits {
+		if !isRateLimitGroup(group) {
+			return fmt.Errorf("invalid rate_limit.limits group %q, must be one of %s", group, strings.Join(http.RateLimitGroups, ", "))
+		} else if limit.Requests < 0 {
+			return fmt.Errorf("rate_limit.limits.%s.requests must not be negative", group)
+		} else if limit.Requests > 0 && limit.WindowSeconds <= 0 {
+			return fmt.Errorf("rate_limit.limits.%s.window_seconds must be positive", group)
+		}
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		return fmt.Errorf("invalid tracing.exporter %q, must be one of stdout or otlp", c.Tracing.Exporter)
+	}
+	if c.Account.DeletionGracePeriodDays < 0 {
+		return errors.New("account.deletion_grace_period_days must not be negative")
+	}
+	return nil
+}
+
+func validateCORSPolicy(name string, policy http.CORSPolicy) error {
+	for _, origin := range policy.AllowedOrigins {
+		if err := http.ValidateCORSOrigin(origin); err != nil {
+			return fmt.Errorf("%s.allowed_origins: %v", name, err)
+		}
+	}
+	if policy.AllowsAnyOrigin() && policy.AllowCredentials {
+		return fmt.Errorf("%s.allow_credentials must be false if any origin is allowed", name)
+	} else if policy.MaxAge < 0 {
+		return fmt.Errorf("%s.max_age_seconds must not be negative", name)
+	}
+	return nil
+}
+
+func isRateLimitGroup(group string) bool {
+	for _, g := range http.RateLimitGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
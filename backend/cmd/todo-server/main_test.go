@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	valid := func() Config {
+		c := DefaultConfig()
+		c.DB.DSN = "host=localhost port=5432 user=dbuser password=dbpassword dbname=todo sslmode=disable"
+		return c
+	}
+	if err := valid().Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	empty := ""
+	tt := map[string]func(c *Config){
+		"db.dsn is required":       func(c *Config) { c.DB.DSN = "" },
+		"invalid db.dsn":           func(c *Config) { c.DB.DSN = "postgres://localhost:notaport/todo" },
+		"invalid http.addr":        func(c *Config) { c.HTTP.Addr = "localhost" },
+		"invalid grpc.addr":        func(c *Config) { c.GRPC.Addr = "8090" },
+		"http.api_key":             func(c *Config) { c.HTTP.APIKey = &empty },
+		"invalid log.level":        func(c *Config) { c.Log.Level = "verbose" },
+		"invalid log.format":       func(c *Config) { c.Log.Format = "xml" },
+		"invalid tracing.exporter": func(c *Config) { c.Tracing.Exporter = "jaeger" },
+		"account.deletion_grace_period_days": func(c *Config) {
+			c.Account.DeletionGracePeriodDays = -1
+		},
+		"http.cert_file and http.key_file": func(c *Config) {
+			c.HTTP.CertFile = "server.crt"
+		},
+		"http.cert_file requires http.tls": func(c *Config) {
+			c.HTTP.CertFile, c.HTTP.KeyFile = "server.crt", "server.key"
+		},
+		"http.client_ca_file requires": func(c *Config) {
+			c.HTTP.ClientCAFile = "ca.crt"
+		},
+		"invalid http.redirect_addr": func(c *Config) {
+			c.HTTP.TLS, c.HTTP.CertFile, c.HTTP.KeyFile = true, "server.crt", "server.key"
+			c.HTTP.RedirectAddr = "80"
+		},
+		"http.hsts_max_age_seconds": func(c *Config) {
+			c.HTTP.HSTSMaxAgeSeconds = -1
+		},
+		"invalid rate_limit.store": func(c *Config) {
+			c.RateLimit.Store = "redis"
+		},
+		"invalid rate_limit.trusted_proxies network": func(c *Config) {
+			c.RateLimit.TrustedProxies = []string{"10.0.0.1", "10.0.0.0/33"}
+		},
+		"invalid rate_limit.limits group": func(c *Config) {
+			c.RateLimit.Limits["signin"] = RateLimitConfig{Requests: 1, WindowSeconds: 1}
+		},
+		"rate_limit.limits.login.window_seconds": func(c *Config) {
+			c.RateLimit.Limits["login"] = RateLimitConfig{Requests: 1}
+		},
+		"http.shutdown_delay_seconds": func(c *Config) {
+			c.HTTP.ShutdownDelaySeconds = -1
+		},
+		"http.shutdown_timeout_seconds must be positive": func(c *Config) {
+			c.HTTP.ShutdownTimeoutSeconds = 0
+		},
+		"http.shutdown_timeout_seconds must be greater": func(c *Config) {
+			c.HTTP.ShutdownDelaySeconds = c.HTTP.ShutdownTimeoutSeconds
+		},
+		"cors.allowed_origins: invalid origin": func(c *Config) {
+			c.HTTP.CORSAllowedOrigins = "localhost:3000"
+		},
+		"a wildcard must be the first label": func(c *Config) {
+			c.CORS.AllowedOrigins = []string{"https://app.*.example.com"}
+		},
+		"cors.allow_credentials must be false": func(c *Config) {
+			credentials := true
+			c.CORS.AllowedOrigins, c.CORS.AllowCredentials = []string{"*"}, &credentials
+		},
+		"invalid cors.routes prefix": func(c *Config) {
+			c.CORS.Routes = map[string]CORSConfig{"api/feeds": {}}
+		},
+		"cors.routes./api.max_age_seconds": func(c *Config) {
+			maxAge := -1
+			c.CORS.Routes = map[string]CORSConfig{"/api": {MaxAgeSeconds: &maxAge}}
+		},
+	}
+	for want, f := range tt {
+		t.Run(want, func(t *testing.T) {
+			c := valid()
+			f(&c)
+			if err := c.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+				t.Fatalf("want %q got %v", want, err)
+			}
+		})
+	}
+}
+
+func TestConfig_CORSPolicies(t *testing.T) {
+	c := DefaultConfig()
+	c.HTTP.CORSAllowedOrigins = "https://todo.example.com, https://*.todo.example.com"
+	c.CORS.Routes = map[string]CORSConfig{"/api/openapi.json": {AllowedOrigins: []string{"*"}}}
+
+	policy, routes := c.CORSPolicies()
+	if !policy.AllowCredentials || len(policy.AllowedOrigins) != 2 || policy.AllowedOrigins[1] != "https://*.todo.example.com" {
+		t.Fatalf("want the deprecated origins with credentials got %+v", policy)
+	}
+	route := routes["/api/openapi.json"]
+	if !route.AllowsAnyOrigin() || route.AllowCredentials || route.MaxAge != policy.MaxAge {
+		t.Fatalf("want any origin without credentials and inherited max age got %+v", route)
+	}
+
+	// cors.allowed_origins replaces the deprecated origins
+	c.CORS.AllowedOrigins = []string{"https://other.example.com"}
+	if policy, _ := c.CORSPolicies(); len(policy.AllowedOrigins) != 1 || policy.AllowedOrigins[0] != "https://other.example.com" {
+		t.Fatalf("want cors.allowed_origins got %v", policy.AllowedOrigins)
+	}
+}
+
+func TestAdminCommand_CheckConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(filename, []byte(`{"db":{"dsn":"host=localhost dbname=todo"},"http":{"addr":":8080","cors_allowed_origins":"*"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := &AdminCommand{Stdout: &stdout, Stderr: &stderr}
+	if err := cmd.Run(context.Background(), "check-config", []string{"-config", filename}); err != nil {
+		t.Fatal(err)
+	} else if want := "config " + filename + " is valid\n"; stdout.String() != want {
+		t.Fatalf("want %q got %q", want, stdout.String())
+	} else if !strings.Contains(stderr.String(), "allows any origin") {
+		t.Fatalf("want warning got %q", stderr.String())
+	}
+
+	if err := cmd.Run(context.Background(), "user", []string{"rename"}); err != ErrUsage {
+		t.Fatalf("want usage got %v", err)
+	} else if err := cmd.Run(context.Background(), "apikey", []string{"rotate", "-config", filename}); err != ErrUsage {
+		t.Fatalf("want usage got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cmokbel1/todo-app/backend/client"
+)
+
+// defaultURL is the URL of the server when neither a flag, the environment nor a login specify it.
+const defaultURL = "http://localhost:8058"
+
+// Config is the login stored by the login command.
+type Config struct {
+	URL    string `json:"url"`
+	APIKey string `json:"apiKey"`
+}
+
+// configFile returns the path of the stored login, which is read from the TODO_CLI_CONFIG environment variable or
+// defaults to todo/config.json in the user's config directory.
+func configFile() (string, error) {
+	if filename := os.Getenv("TODO_CLI_CONFIG"); filename != "" {
+		return filename, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot locate the config directory, set TODO_CLI_CONFIG: %w", err)
+	}
+	return filepath.Join(dir, "todo", "config.json"), nil
+}
+
+// readConfig returns the stored login, which is empty if the user has not logged in.
+func readConfig() (*Config, error) {
+	filename, err := configFile()
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if b, err := os.ReadFile(filename); errors.Is(err, os.ErrNotExist) {
+		return &config, nil
+	} else if err != nil {
+		return nil, err
+	} else if err := json.Unmarshal(b, &config); err != nil {
+		return nil, fmt.Errorf("invalid config %q: %w", filename, err)
+	}
+	return &config, nil
+}
+
+// writeConfig stores the login. The file is only readable by the user since it contains the API key.
+func writeConfig(config *Config) error {
+	filename, err := configFile()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	} else if err := os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// removeConfig removes the stored login.
+func removeConfig() error {
+	filename, err := configFile()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// applyConfig fills in the URL and API key of the client from the stored login unless the flags or the environment
+// specified them.
+func applyConfig(c *client.Client) error {
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+
+	if c.URL == "" {
+		c.URL = config.URL
+	}
+	if c.URL == "" {
+		c.URL = defaultURL
+	}
+	if c.APIKey == "" {
+		c.APIKey = config.APIKey
+	}
+	if c.APIKey == "" {
+		return errors.New("not logged in, run todo login or use -api-key or TODO_API_KEY")
+	}
+	return nil
+}
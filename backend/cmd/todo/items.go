@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// ListsCommand prints the lists with the number of their open and completed items.
+type ListsCommand struct {
+	command
+}
+
+func (cmd *ListsCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("lists")
+	if err := cmd.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+	if cmd.JSON {
+		return cmd.printJSON(lists)
+	}
+
+	w := tabwriter.NewWriter(cmd.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tOPEN\tDONE")
+	for _, list := range lists {
+		done := 0
+		for _, item := range list.Items {
+			if item.Completed {
+				done++
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%d\n", list.ID, list.Name, len(list.Items)-done, done)
+	}
+	return w.Flush()
+}
+
+// ItemsCommand prints the open items of all lists or of a single list.
+type ItemsCommand struct {
+	command
+	// List is the name or ID of the list whose items are printed, all lists if it is empty.
+	List string
+	// All includes the completed items.
+	All bool
+}
+
+func (cmd *ItemsCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("items")
+	fs.StringVar(&cmd.List, "list", "", "name or ID of the list (default all lists)")
+	fs.BoolVar(&cmd.All, "all", false, "include completed items")
+	if err := cmd.parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+	if cmd.List != "" {
+		list := findList(lists, cmd.List)
+		if list == nil {
+			return todo.Err(todo.ENOTFOUND, "could not find list %q", cmd.List)
+		}
+		lists = []*todo.List{list}
+	}
+
+	items := make([]*todo.Item, 0)
+	for _, list := range lists {
+		for _, item := range list.Items {
+			if cmd.All || !item.Completed {
+				items = append(items, item)
+			}
+		}
+	}
+	return cmd.printItems(lists, items)
+}
+
+// AddCommand adds an item written in the todo.txt syntax.
+type AddCommand struct {
+	command
+	// List is the list of items which do not name a +list.
+	List string
+}
+
+func (cmd *AddCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("add")
+	fs.StringVar(&cmd.List, "list", format.TodoTxtDefaultList, "name or ID of the list of items without a +list")
+	if err := cmd.parse(fs, args, 1); err != nil {
+		return err
+	}
+
+	q, err := parseQuickAdd(strings.Join(fs.Args(), " "), cmd.Now())
+	if err != nil {
+		return err
+	} else if q.List == "" {
+		q.List = cmd.List
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+	list, lists, err := cmd.findOrCreateList(ctx, lists, q.List)
+	if err != nil {
+		return err
+	}
+
+	item := &todo.Item{ListID: list.ID, Name: q.Name}
+	if err := cmd.Client.CreateItem(ctx, item); err != nil {
+		return err
+	}
+	return cmd.printItems(lists, []*todo.Item{item})
+}
+
+// CompleteCommand marks items as completed or, with Undo, as open.
+type CompleteCommand struct {
+	command
+	Undo bool
+}
+
+func (cmd *CompleteCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("complete")
+	fs.BoolVar(&cmd.Undo, "undo", false, "mark the items as open")
+	if err := cmd.parse(fs, args, 1); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	completed := !cmd.Undo
+	items := make([]*todo.Item, 0)
+	for _, id := range ids {
+		item, err := findItem(lists, id)
+		if err != nil {
+			return err
+		}
+		if item, err = cmd.Client.UpdateItem(ctx, item.ListID, item.ID, todo.ItemUpdate{Completed: &completed}); err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	return cmd.printItems(lists, items)
+}
+
+// EditCommand replaces the name of an item with text in the todo.txt syntax. If the text names another +list the
+// item is moved to it.
+type EditCommand struct {
+	command
+}
+
+func (cmd *EditCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("edit")
+	if err := cmd.parse(fs, args, 2); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args()[:1])
+	if err != nil {
+		return err
+	}
+	q, err := parseQuickAdd(strings.Join(fs.Args()[1:], " "), cmd.Now())
+	if err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := findItem(lists, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if q.List != "" {
+		var list *todo.List
+		if list, lists, err = cmd.findOrCreateList(ctx, lists, q.List); err != nil {
+			return err
+		} else if list.ID != item.ListID {
+			item.Name = q.Name
+			if item, err = cmd.moveItem(ctx, item, list); err != nil {
+				return err
+			}
+			return cmd.printItems(lists, []*todo.Item{item})
+		}
+	}
+
+	if item, err = cmd.Client.UpdateItem(ctx, item.ListID, item.ID, todo.ItemUpdate{Name: &q.Name}); err != nil {
+		return err
+	}
+	return cmd.printItems(lists, []*todo.Item{item})
+}
+
+// MoveCommand moves an item to another list, which is created if it does not exist.
+type MoveCommand struct {
+	command
+}
+
+func (cmd *MoveCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("move")
+	if err := cmd.parse(fs, args, 2); err != nil {
+		return err
+	} else if fs.NArg() > 2 {
+		fs.Usage()
+		return ErrUsage
+	}
+	ids, err := parseIDs(fs.Args()[:1])
+	if err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+	item, err := findItem(lists, ids[0])
+	if err != nil {
+		return err
+	}
+	list, lists, err := cmd.findOrCreateList(ctx, lists, strings.TrimPrefix(fs.Arg(1), "+"))
+	if err != nil {
+		return err
+	}
+
+	if list.ID != item.ListID {
+		if item, err = cmd.moveItem(ctx, item, list); err != nil {
+			return err
+		}
+	}
+	return cmd.printItems(lists, []*todo.Item{item})
+}
+
+// DeleteCommand deletes items.
+type DeleteCommand struct {
+	command
+}
+
+func (cmd *DeleteCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("delete")
+	if err := cmd.parse(fs, args, 1); err != nil {
+		return err
+	}
+	ids, err := parseIDs(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	lists, err := cmd.Client.FindLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	items := make([]*todo.Item, 0)
+	for _, id := range ids {
+		item, err := findItem(lists, id)
+		if err != nil {
+			return err
+		} else if err := cmd.Client.DeleteItem(ctx, item.ListID, item.ID); err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	return cmd.printItems(lists, items)
+}
+
+// moveItem recreates the item in another list since items cannot be moved between lists. The ID of the item
+// changes.
+func (cmd *command) moveItem(ctx context.Context, item *todo.Item, to *todo.List) (*todo.Item, error) {
+	other := &todo.Item{ListID: to.ID, Name: item.Name, Completed: item.Completed}
+	if err := cmd.Client.CreateItem(ctx, other); err != nil {
+		return nil, err
+	} else if err := cmd.Client.DeleteItem(ctx, item.ListID, item.ID); err != nil {
+		return nil, err
+	}
+	return other, nil
+}
+
+// findOrCreateList returns the list with the name or ID and the lists including it. A list which does not exist
+// is created.
+func (cmd *command) findOrCreateList(ctx context.Context, lists []*todo.List, name string) (*todo.List, []*todo.List, error) {
+	if list := findList(lists, name); list != nil {
+		return list, lists, nil
+	}
+
+	list := &todo.List{Name: name}
+	if err := cmd.Client.CreateList(ctx, list); err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(cmd.Stderr, "created list %q\n", list.Name)
+	return list, append(lists, list), nil
+}
+
+// findList returns the list with the ID or name, nil if there is none. Names are compared case-insensitively and
+// also match the +project of a list whose name contains whitespace.
+func findList(lists []*todo.List, name string) *todo.List {
+	if id, err := strconv.Atoi(name); err == nil {
+		for _, list := range lists {
+			if list.ID == id {
+				return list
+			}
+		}
+	}
+	for _, list := range lists {
+		if strings.EqualFold(list.Name, name) || strings.EqualFold(format.TodoTxtProject(list.Name), name) {
+			return list
+		}
+	}
+	return nil
+}
+
+// findItem returns the item with the ID from any of the lists.
+func findItem(lists []*todo.List, id int) (*todo.Item, error) {
+	for _, list := range lists {
+		for _, item := range list.Items {
+			if item.ID == id {
+				return item, nil
+			}
+		}
+	}
+	return nil, todo.Err(todo.ENOTFOUND, "could not find item with id %d", id)
+}
+
+func parseIDs(args []string) ([]int, error) {
+	ids := make([]int, len(args))
+	for i, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil || id <= 0 {
+			return nil, todo.Err(todo.EINVALID, "invalid item id %q", arg)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// printItems prints the items as a table with the names of their lists or as JSON.
+func (cmd *command) printItems(lists []*todo.List, items []*todo.Item) error {
+	if cmd.JSON {
+		return cmd.printJSON(items)
+	}
+
+	names := make(map[int]string)
+	for _, list := range lists {
+		names[list.ID] = list.Name
+	}
+
+	w := tabwriter.NewWriter(cmd.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tLIST\tDONE\tNAME")
+	for _, item := range items {
+		done := ""
+		if item.Completed {
+			done = "x"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", item.ID, names[item.ListID], done, item.Name)
+	}
+	return w.Flush()
+}
+
+func (cmd *command) printJSON(v interface{}) error {
+	enc := json.NewEncoder(cmd.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
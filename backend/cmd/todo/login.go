@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// LoginCommand logs in with the name and password of an account, or verifies an API key, and stores the URL of
+// the server and the API key of the account so that other commands do not need them.
+type LoginCommand struct {
+	command
+	// Name of the account, it is prompted for if it is empty.
+	Name string
+}
+
+func (cmd *LoginCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("login")
+	fs.StringVar(&cmd.Name, "name", "", "name of the account (default prompt)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		fs.Usage()
+		return ErrUsage
+	}
+
+	config, err := readConfig()
+	if err != nil {
+		return err
+	}
+	if cmd.Client.URL == "" {
+		cmd.Client.URL = config.URL
+	}
+	if cmd.Client.URL == "" {
+		cmd.Client.URL = defaultURL
+	}
+
+	user, err := cmd.login(ctx)
+	if err != nil {
+		return err
+	}
+	if err := writeConfig(&Config{URL: cmd.Client.URL, APIKey: cmd.Client.APIKey}); err != nil {
+		return err
+	}
+
+	if cmd.JSON {
+		return cmd.printJSON(user)
+	}
+	fmt.Fprintf(cmd.Stdout, "logged in to %s as %s\n", cmd.Client.URL, user.Name)
+	return nil
+}
+
+// login authenticates with the API key of the client or otherwise with the name and password read from Stdin,
+// and sets the API key of the client.
+func (cmd *LoginCommand) login(ctx context.Context) (*todo.User, error) {
+	if cmd.Client.APIKey != "" {
+		return cmd.Client.Me(ctx)
+	}
+
+	r := bufio.NewReader(cmd.Stdin)
+	name := cmd.Name
+	if name == "" {
+		fmt.Fprint(cmd.Stderr, "Name: ")
+		var err error
+		if name, err = readLine(r); err != nil {
+			return nil, err
+		}
+	}
+	fmt.Fprint(cmd.Stderr, "Password: ")
+	password, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := cmd.Client.Login(ctx, name, password)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.Client.APIKey, err = cmd.Client.FindAPIKey(ctx); err != nil {
+		return nil, err
+	}
+	// the session is not needed any more, the API key is used from now on
+	if err := cmd.Client.Logout(ctx); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+	return strings.TrimRight(line, "\r\n"), err
+}
+
+// LogoutCommand removes the stored login. The API key of the account stays valid.
+type LogoutCommand struct {
+	command
+}
+
+func (cmd *LogoutCommand) Run(ctx context.Context, args []string) error {
+	fs := cmd.flagSet("logout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		fs.Usage()
+		return ErrUsage
+	}
+
+	if err := removeConfig(); err != nil {
+		return err
+	}
+	fmt.Fprintln(cmd.Stdout, "logged out")
+	return nil
+}
@@ -0,0 +1,183 @@
+// Command todo is a command line client for the todo server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/client"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var (
+	version = "NA"
+	commit  = "NA"
+	date    = "NA"
+)
+
+// ErrUsage is returned when the command line arguments are invalid. The usage has already been printed.
+var ErrUsage = errors.New("usage")
+
+func main() {
+	todo.Build.Version = version
+	todo.Build.Commit = commit
+	todo.Build.Date = date
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	go func() { <-c; cancel() }()
+
+	if err := NewMain().Run(ctx, os.Args[1:]); errors.Is(err, ErrUsage) || errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Main runs the commands. Its streams and clock are replaced in tests.
+type Main struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Now    func() time.Time
+}
+
+func NewMain() *Main {
+	return &Main{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Now:    time.Now,
+	}
+}
+
+// Run executes the subcommand named by the first argument.
+func (m *Main) Run(ctx context.Context, args []string) error {
+	var cmd string
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+
+	switch cmd {
+	case "login":
+		return (&LoginCommand{command: m.command()}).Run(ctx, args)
+	case "logout":
+		return (&LogoutCommand{command: m.command()}).Run(ctx, args)
+	case "lists":
+		return (&ListsCommand{command: m.command()}).Run(ctx, args)
+	case "items":
+		return (&ItemsCommand{command: m.command()}).Run(ctx, args)
+	case "add":
+		return (&AddCommand{command: m.command()}).Run(ctx, args)
+	case "complete":
+		return (&CompleteCommand{command: m.command()}).Run(ctx, args)
+	case "edit":
+		return (&EditCommand{command: m.command()}).Run(ctx, args)
+	case "move":
+		return (&MoveCommand{command: m.command()}).Run(ctx, args)
+	case "delete":
+		return (&DeleteCommand{command: m.command()}).Run(ctx, args)
+	case "todotxt-sync":
+		cmd := NewTodoTxtSyncCommand()
+		cmd.Stdout, cmd.Stderr = m.Stdout, m.Stderr
+		return cmd.Run(ctx, args)
+	case "version":
+		fmt.Fprintln(m.Stdout, todo.BuildDetails())
+		return nil
+	default:
+		fmt.Fprint(m.Stderr, usage)
+		return ErrUsage
+	}
+}
+
+// command is embedded by the commands which talk to the server.
+type command struct {
+	Client *client.Client
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Now    func() time.Time
+	// JSON prints the results as JSON instead of a table.
+	JSON bool
+}
+
+func (m *Main) command() command {
+	return command{
+		Client: client.New("", ""),
+		Stdin:  m.Stdin,
+		Stdout: m.Stdout,
+		Stderr: m.Stderr,
+		Now:    m.Now,
+	}
+}
+
+// flagSet returns the flags of a command with the flags shared by all commands which talk to the server.
+func (cmd *command) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("todo "+name, flag.ContinueOnError)
+	fs.SetOutput(cmd.Stderr)
+	clientFlags(fs, cmd.Client)
+	fs.BoolVar(&cmd.JSON, "json", false, "print JSON instead of a table")
+	return fs
+}
+
+// parse parses the flags and loads the stored login. It fails unless the number of remaining arguments is at
+// least min.
+func (cmd *command) parse(fs *flag.FlagSet, args []string, min int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() < min {
+		fs.Usage()
+		return ErrUsage
+	}
+	return applyConfig(cmd.Client)
+}
+
+const usage = `todo is a command line client for the todo server.
+
+Usage:
+
+	todo <command> [arguments]
+
+The commands are:
+
+	login          log in and store the API key of the account
+	logout         remove the stored API key
+	lists          print the lists
+	items          print the open items of all lists or of a single list
+	add            add an item, e.g. todo add buy milk due:tomorrow +groceries
+	complete       mark items as completed, or as open with -undo
+	edit           change the name of an item or move it with a +list
+	move           move an item to another list
+	delete         delete items
+	todotxt-sync   two-way sync between a todo.txt file and a server account
+	version        print the version
+
+Items are added with the todo.txt syntax: the first +list names the list of the item, which is created if it does
+not exist, and due:<date> accepts today, tomorrow, a weekday, a number of days or weeks like 3d or 2w and
+YYYY-MM-DD. Flags must precede the arguments of a command and -json prints JSON instead of a table.
+
+The server URL and API key are read from the TODO_URL and TODO_API_KEY environment variables unless they are
+specified with the -url and -api-key flags, and otherwise from the login stored in the file named by TODO_CLI_CONFIG,
+which defaults to todo/config.json in the user's config directory.
+`
+
+// clientFlags registers the flags shared by all commands which talk to the server.
+func clientFlags(fs *flag.FlagSet, c *client.Client) {
+	fs.StringVar(&c.URL, "url", os.Getenv("TODO_URL"), "base URL of the todo server (default the URL of the login or "+defaultURL+")")
+	fs.StringVar(&c.APIKey, "api-key", os.Getenv("TODO_API_KEY"), "API key of the account (default the API key of the login)")
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
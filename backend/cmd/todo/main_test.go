@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	todohttp "github.com/cmokbel1/todo-app/backend/http"
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// now is a Monday.
+var now = time.Date(2026, time.October, 19, 9, 30, 0, 0, time.UTC)
+
+// testServer serves the API with in-memory services and stores the login in a temporary directory.
+type testServer struct {
+	*httptest.Server
+	Users *inmem.UserService
+	Items *inmem.ItemListService
+	Sync  *syncService
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	t.Setenv("TODO_URL", "")
+	t.Setenv("TODO_API_KEY", "")
+	t.Setenv("TODO_CLI_CONFIG", filepath.Join(t.TempDir(), "todo", "config.json"))
+
+	logger := todo.NewLogger()
+	logger.SetOutput(io.Discard)
+
+	s := todohttp.NewServer()
+	s.Logger = logger
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = todohttp.NewSessionManager()
+	users, items := inmem.NewUserService(), inmem.NewItemListService()
+	changes := newSyncService(items)
+	s.UserService = users
+	s.ItemListService = items
+	s.SyncService = changes
+
+	ts := &testServer{Server: httptest.NewServer(s.Handler()), Users: users, Items: items, Sync: changes}
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// login creates a user and stores their login.
+func (ts *testServer) login(t *testing.T) *todo.User {
+	t.Helper()
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := ts.Users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	} else if err := writeConfig(&Config{URL: ts.URL, APIKey: user.APIKey}); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// run runs the command line and returns what it printed to stdout.
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	m := &Main{
+		Stdin:  strings.NewReader(stdin),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Now:    func() time.Time { return now },
+	}
+	err := m.Run(context.Background(), args)
+	return stdout.String(), err
+}
+
+func mustRun(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := run(t, "", args...)
+	if err != nil {
+		t.Fatalf("todo %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+func runJSON(t *testing.T, v interface{}, args ...string) {
+	t.Helper()
+	out := mustRun(t, append([]string{args[0], "-json"}, args[1:]...)...)
+	if err := json.Unmarshal([]byte(out), v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ts := newTestServer(t)
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := ts.Users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := run(t, "", "lists"); err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Fatalf("want not logged in got %v", err)
+	}
+	if _, err := run(t, "wrong\n", "login", "-url", ts.URL, "-name", "george"); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("want unauthorized got %v", err)
+	}
+
+	out, err := run(t, "george\npassword\n", "login", "-url", ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := "logged in to " + ts.URL + " as george\n"; out != want {
+		t.Fatalf("want %q got %q", want, out)
+	}
+
+	config, err := readConfig()
+	if err != nil {
+		t.Fatal(err)
+	} else if config.URL != ts.URL || config.APIKey != user.APIKey {
+		t.Fatalf("unexpected config %+v", config)
+	}
+	filename, _ := configFile()
+	if fi, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	} else if fi.Mode().Perm() != 0o600 {
+		t.Fatalf("want mode 0600 got %v", fi.Mode().Perm())
+	}
+
+	// the stored login is used by other commands
+	mustRun(t, "lists")
+
+	mustRun(t, "logout")
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Fatalf("want config removed got %v", err)
+	}
+
+	// an API key is verified before it is stored
+	if _, err := run(t, "", "login", "-url", ts.URL, "-api-key", "invalid"); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("want unauthorized got %v", err)
+	}
+	mustRun(t, "login", "-url", ts.URL, "-api-key", user.APIKey)
+}
+
+func TestItems(t *testing.T) {
+	ts := newTestServer(t)
+	ts.login(t)
+
+	out := mustRun(t, "add", "buy", "milk", "due:tomorrow", "+groceries")
+	if !strings.Contains(out, "groceries") || !strings.Contains(out, "buy milk due:2026-10-20") {
+		t.Fatalf("unexpected output %q", out)
+	}
+
+	var items []*todo.Item
+	runJSON(t, &items, "items", "-list", "Groceries")
+	if len(items) != 1 || items[0].Name != "buy milk due:2026-10-20" {
+		t.Fatalf("unexpected items %v", items)
+	}
+	milk := items[0]
+
+	var call []*todo.Item
+	runJSON(t, &call, "add", "call", "mom", "due:fri")
+	if len(call) != 1 || call[0].Name != "call mom due:2026-10-23" {
+		t.Fatalf("unexpected items %v", call)
+	}
+
+	var lists []*todo.List
+	runJSON(t, &lists, "lists")
+	if len(lists) != 2 || lists[0].Name != "groceries" || lists[1].Name != "Inbox" {
+		t.Fatalf("unexpected lists %v", lists)
+	}
+
+	// completed items are only printed with -all
+	mustRun(t, "complete", strconv.Itoa(milk.ID))
+	if out := mustRun(t, "items"); strings.Contains(out, "milk") || !strings.Contains(out, "call mom") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	if out := mustRun(t, "items", "-all"); !strings.Contains(out, "milk") {
+		t.Fatalf("unexpected output %q", out)
+	}
+	mustRun(t, "complete", "-undo", strconv.Itoa(milk.ID))
+
+	// editing the +list moves the item, its ID changes
+	var edited []*todo.Item
+	runJSON(t, &edited, "edit", strconv.Itoa(milk.ID), "buy oat milk", "+inbox")
+	if len(edited) != 1 || edited[0].Name != "buy oat milk" || edited[0].ListID != lists[1].ID || edited[0].Completed {
+		t.Fatalf("unexpected items %v", edited)
+	}
+
+	var moved []*todo.Item
+	runJSON(t, &moved, "move", strconv.Itoa(edited[0].ID), "errands")
+	if len(moved) != 1 || moved[0].Name != "buy oat milk" {
+		t.Fatalf("unexpected items %v", moved)
+	}
+	runJSON(t, &items, "items", "-list", "errands")
+	if len(items) != 1 || items[0].ID != moved[0].ID {
+		t.Fatalf("unexpected items %v", items)
+	}
+
+	mustRun(t, "edit", strconv.Itoa(moved[0].ID), "buy oat milk today")
+	mustRun(t, "delete", strconv.Itoa(moved[0].ID), strconv.Itoa(call[0].ID))
+	runJSON(t, &items, "items", "-all")
+	if len(items) != 0 {
+		t.Fatalf("want no items got %v", items)
+	}
+
+	if _, err := run(t, "", "complete", "999"); todo.ErrCode(err) != todo.ENOTFOUND {
+		t.Fatalf("want not found got %v", err)
+	} else if _, err := run(t, "", "delete", "milk"); todo.ErrCode(err) != todo.EINVALID {
+		t.Fatalf("want invalid got %v", err)
+	} else if _, err := run(t, "", "add", "milk", "due:someday"); todo.ErrCode(err) != todo.EINVALID {
+		t.Fatalf("want invalid got %v", err)
+	} else if _, err := run(t, "", "add"); err != ErrUsage {
+		t.Fatalf("want usage got %v", err)
+	}
+}
+
+func TestParseQuickAdd(t *testing.T) {
+	tt := []struct {
+		Text string
+		Name string
+		List string
+	}{
+		{Text: "buy milk due:tomorrow +groceries", Name: "buy milk due:2026-10-20", List: "groceries"},
+		{Text: "+home fix the sink +plumbing @phone", Name: "fix the sink +plumbing @phone", List: "home"},
+		{Text: "pay rent due:today", Name: "pay rent due:2026-10-19"},
+		{Text: "review due:Monday", Name: "review due:2026-10-26"},
+		{Text: "review due:wed", Name: "review due:2026-10-21"},
+		{Text: "taxes due:2w", Name: "taxes due:2026-11-02"},
+		{Text: "taxes due:10d", Name: "taxes due:2026-10-29"},
+		{Text: "taxes due:2027-04-15", Name: "taxes due:2027-04-15"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Text, func(t *testing.T) {
+			q, err := parseQuickAdd(tc.Text, now)
+			if err != nil {
+				t.Fatal(err)
+			} else if q.Name != tc.Name || q.List != tc.List {
+				t.Fatalf("want %q in %q got %q in %q", tc.Name, tc.List, q.Name, q.List)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "+groceries", "milk due:", "milk due:3x", "milk due:2026-13-01"} {
+		if _, err := parseQuickAdd(text, now); todo.ErrCode(err) != todo.EINVALID {
+			t.Errorf("%q: want invalid got %v", text, err)
+		}
+	}
+}
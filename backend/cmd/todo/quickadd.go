@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// quickAdd is an item written in the todo.txt syntax, e.g. "buy milk due:tomorrow +groceries".
+type quickAdd struct {
+	// Name is the text without the +list. Relative due dates are replaced by the date they refer to so that the
+	// name stays correct after today.
+	Name string
+	// List is the name of the first +list, empty if the text does not name a list.
+	List string
+}
+
+// parseQuickAdd parses the text of an item. Further +projects and @contexts are kept in the name.
+func parseQuickAdd(text string, now time.Time) (quickAdd, error) {
+	var q quickAdd
+	var words []string
+	for _, word := range strings.Fields(text) {
+		switch {
+		case len(word) > 1 && word[0] == '+' && q.List == "":
+			q.List = word[1:]
+			continue
+		case strings.HasPrefix(word, "due:"):
+			due, err := parseDue(strings.TrimPrefix(word, "due:"), now)
+			if err != nil {
+				return q, err
+			}
+			word = "due:" + due.Format(format.TodoTxtDate)
+		}
+		words = append(words, word)
+	}
+
+	if q.Name = strings.Join(words, " "); q.Name == "" {
+		return q, todo.Err(todo.EINVALID, "name required")
+	}
+	return q, nil
+}
+
+// parseDue returns the date of a due:<date> tag relative to now. The date is today, tomorrow, the next weekday by
+// its full or abbreviated name, a number of days or weeks from today such as 3d or 2w or a date as YYYY-MM-DD.
+func parseDue(s string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	s = strings.ToLower(s)
+
+	switch s {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if name := strings.ToLower(d.String()); s == name || s == name[:3] {
+			days := (int(d) - int(today.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), nil
+		}
+	}
+
+	if i := len(s) - 1; i > 0 {
+		if n, err := strconv.Atoi(s[:i]); err == nil && n >= 0 {
+			switch s[i] {
+			case 'd':
+				return today.AddDate(0, 0, n), nil
+			case 'w':
+				return today.AddDate(0, 0, 7*n), nil
+			}
+		}
+	}
+
+	if due, err := time.ParseInLocation(format.TodoTxtDate, s, now.Location()); err == nil {
+		return due, nil
+	}
+	return time.Time{}, todo.Err(todo.EINVALID, "invalid due date %q, use today, tomorrow, a weekday, 3d, 2w or YYYY-MM-DD", s)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/client"
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// TodoTxtSyncCommand performs a two-way sync between a local todo.txt file and a server account.
+//
+// Synced tasks are tagged with "tid:<id>" where id is the ID of the server's Item. The state of every task after
+// the previous sync is kept in a state file next to the todo.txt file, which is how changes made on either side
+// are told apart. When a task was changed on both sides the local change wins.
+type TodoTxtSyncCommand struct {
+	Client *client.Client
+	// File is the path of the todo.txt file.
+	File string
+	// StateFile is the path of the sync state, it defaults to File with a ".sync" suffix.
+	StateFile string
+
+	Stdout io.Writer
+	Stderr io.Writer
+	Now    func() time.Time
+}
+
+func NewTodoTxtSyncCommand() *TodoTxtSyncCommand {
+	return &TodoTxtSyncCommand{
+		Client: client.New("", ""),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		Now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (cmd *TodoTxtSyncCommand) Run(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("todo todotxt-sync", flag.ContinueOnError)
+	fs.SetOutput(cmd.Stderr)
+	clientFlags(fs, cmd.Client)
+	fs.StringVar(&cmd.File, "file", envOr("TODO_FILE", "todo.txt"), "path of the todo.txt file")
+	fs.StringVar(&cmd.StateFile, "state", "", "path of the sync state file (default the todo.txt path with a .sync suffix)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	} else if fs.NArg() > 0 {
+		fs.Usage()
+		return ErrUsage
+	}
+
+	if err := applyConfig(cmd.Client); err != nil {
+		return err
+	}
+	return cmd.Sync(ctx)
+}
+
+// todoTxtState is the state of the file after the previous sync.
+type todoTxtState struct {
+	// Token is the sync token of the server's change log.
+	Token string `json:"token"`
+	// Lists are the names of the server's lists by ID.
+	Lists map[int]string `json:"lists"`
+	// Tasks are the synced tasks by Item ID without their tid tag.
+	Tasks map[int]string `json:"tasks"`
+}
+
+// todoTxtEntry is a line of the todo.txt file.
+type todoTxtEntry struct {
+	// id of the Item, zero if the task has not been synced.
+	id int
+	// line is the task without its tid tag.
+	line string
+	drop bool
+}
+
+func (e *todoTxtEntry) task() format.TodoTxtTask {
+	return format.ParseTodoTxtTask(e.line)
+}
+
+// Sync pulls the changes made on the server since the previous sync, pushes the local changes and writes the
+// merged tasks to the file.
+func (cmd *TodoTxtSyncCommand) Sync(ctx context.Context) error {
+	stateFile := cmd.StateFile
+	if stateFile == "" {
+		stateFile = cmd.File + ".sync"
+	}
+
+	state, err := readTodoTxtState(stateFile)
+	if err != nil {
+		return err
+	}
+	entries, err := readTodoTxtEntries(cmd.File)
+	if err != nil {
+		return err
+	}
+
+	changes, err := cmd.Client.FindChanges(ctx, state.Token)
+	if err != nil {
+		return fmt.Errorf("pull changes: %w", err)
+	}
+
+	serverItems := make(map[int]*todo.Item)
+	for _, list := range changes.Lists {
+		state.Lists[list.ID] = list.Name
+	}
+	for _, item := range changes.Items {
+		serverItems[item.ID] = item
+	}
+	serverDeleted := make(map[int]bool)
+	for _, t := range changes.Tombstones {
+		if t.Entity == todo.EntityList {
+			delete(state.Lists, t.ID)
+		} else {
+			serverDeleted[t.ID] = true
+		}
+	}
+	serverLine := func(item *todo.Item) string {
+		return format.NewTodoTxtTask(state.Lists[item.ListID], item).String()
+	}
+
+	now := cmd.Now()
+	var mutations []*todo.Mutation
+	var creates []int
+	seen := make(map[int]bool)
+	pulled := 0
+	for i := range entries {
+		e := &entries[i]
+		if e.id == 0 {
+			creates = append(creates, i)
+			continue
+		}
+
+		seen[e.id] = true
+		snapshot, known := state.Tasks[e.id]
+		changed := !known || e.line != snapshot
+		switch {
+		case serverDeleted[e.id] && changed:
+			// the local change wins over the deletion on the server
+			e.id = 0
+			creates = append(creates, i)
+		case serverDeleted[e.id]:
+			e.drop = true
+			pulled++
+		case !changed:
+			if item, ok := serverItems[e.id]; ok && serverLine(item) != e.line {
+				e.line = serverLine(item)
+				pulled++
+			}
+		case known && e.task().List() != format.ParseTodoTxtTask(snapshot).List():
+			// items cannot be moved between lists so they are recreated in the new list
+			mutations = append(mutations, &todo.Mutation{Entity: todo.EntityItem, Op: todo.OpDelete, ID: e.id, UpdatedAt: now})
+			e.id = 0
+			creates = append(creates, i)
+		default:
+			item := e.task().Item()
+			mutations = append(mutations, &todo.Mutation{
+				ClientID:  strconv.Itoa(i),
+				Entity:    todo.EntityItem,
+				Op:        todo.OpUpdate,
+				ID:        e.id,
+				Name:      &item.Name,
+				Completed: &item.Completed,
+				UpdatedAt: now,
+			})
+		}
+	}
+
+	// tasks which were removed from the file since the previous sync
+	for id := range state.Tasks {
+		if !seen[id] && !serverDeleted[id] {
+			mutations = append(mutations, &todo.Mutation{Entity: todo.EntityItem, Op: todo.OpDelete, ID: id, UpdatedAt: now})
+		}
+	}
+
+	// items which were created on the server since the previous sync
+	sort.Slice(changes.Items, func(i, j int) bool { return changes.Items[i].ID < changes.Items[j].ID })
+	for _, item := range changes.Items {
+		if _, known := state.Tasks[item.ID]; !known && !seen[item.ID] {
+			entries = append(entries, todoTxtEntry{id: item.ID, line: serverLine(item)})
+			pulled++
+		}
+	}
+
+	listIDs, err := cmd.createLists(ctx, state, entries, creates, now)
+	if err != nil {
+		return err
+	}
+	for _, i := range creates {
+		task := entries[i].task()
+		item := task.Item()
+		mutations = append(mutations, &todo.Mutation{
+			ClientID:  strconv.Itoa(i),
+			Entity:    todo.EntityItem,
+			Op:        todo.OpCreate,
+			ListID:    listIDs[task.List()],
+			Name:      &item.Name,
+			Completed: &item.Completed,
+			UpdatedAt: now,
+		})
+	}
+
+	results, err := cmd.push(ctx, mutations)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		i, err := strconv.Atoi(result.ClientID)
+		if err != nil {
+			// deletes are not tied to an entry
+			i = -1
+		}
+
+		switch {
+		case result.Status == todo.MutationError:
+			fmt.Fprintf(cmd.Stderr, "failed to sync task: %s\n", result.Error)
+		case i >= 0 && result.Item != nil:
+			entries[i].id = result.Item.ID
+			entries[i].line = serverLine(result.Item)
+		}
+	}
+
+	state.Token = changes.Token
+	state.Tasks = make(map[int]string)
+	for _, e := range entries {
+		if !e.drop && e.id > 0 {
+			state.Tasks[e.id] = e.line
+		}
+	}
+
+	if err := writeTodoTxtEntries(cmd.File, entries); err != nil {
+		return err
+	} else if err := writeTodoTxtState(stateFile, state); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.Stdout, "pulled %d changes, pushed %d changes\n", pulled, len(mutations))
+	return nil
+}
+
+// createLists returns the IDs of the lists by the list of their tasks and creates the lists of new tasks which do not
+// exist yet. Lists are matched by their +project, which is how "Grocery List" is written in the file.
+func (cmd *TodoTxtSyncCommand) createLists(ctx context.Context, state *todoTxtState, entries []todoTxtEntry, creates []int, now time.Time) (map[string]int, error) {
+	listIDs := make(map[string]int)
+	for id, name := range state.Lists {
+		key := format.TodoTxtProject(name)
+		if key == "" {
+			key = format.TodoTxtDefaultList
+		}
+		if other, ok := listIDs[key]; !ok || id < other {
+			listIDs[key] = id
+		}
+	}
+
+	var mutations []*todo.Mutation
+	for _, i := range creates {
+		name := entries[i].task().List()
+		if _, ok := listIDs[name]; !ok {
+			listIDs[name] = 0
+			mutations = append(mutations, &todo.Mutation{ClientID: name, Entity: todo.EntityList, Op: todo.OpCreate, Name: &name, UpdatedAt: now})
+		}
+	}
+
+	results, err := cmd.push(ctx, mutations)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		if result.Status != todo.MutationApplied || result.List == nil {
+			return nil, fmt.Errorf("create list %q: %s", result.ClientID, result.Error)
+		}
+		listIDs[result.ClientID] = result.List.ID
+		state.Lists[result.List.ID] = result.List.Name
+	}
+	return listIDs, nil
+}
+
+// todoTxtPushSize is the number of mutations pushed per request.
+const todoTxtPushSize = 500
+
+func (cmd *TodoTxtSyncCommand) push(ctx context.Context, mutations []*todo.Mutation) ([]*todo.MutationResult, error) {
+	var results []*todo.MutationResult
+	for len(mutations) > 0 {
+		n := len(mutations)
+		if n > todoTxtPushSize {
+			n = todoTxtPushSize
+		}
+
+		batch, err := cmd.Client.PushMutations(ctx, mutations[:n])
+		if err != nil {
+			return nil, fmt.Errorf("push changes: %w", err)
+		}
+		results = append(results, batch...)
+		mutations = mutations[n:]
+	}
+	return results, nil
+}
+
+// todoTxtIDTag matches the tid tag which links a task to its Item.
+var todoTxtIDTag = regexp.MustCompile(`(?:^|\s)tid:(\d+)\b`)
+
+func readTodoTxtEntries(filename string) ([]todoTxtEntry, error) {
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []todoTxtEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var e todoTxtEntry
+		if m := todoTxtIDTag.FindStringSubmatchIndex(line); m != nil {
+			e.id, _ = strconv.Atoi(line[m[2]:m[3]])
+			line = strings.TrimSpace(line[:m[0]] + line[m[1]:])
+		}
+		e.line = line
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func writeTodoTxtEntries(filename string, entries []todoTxtEntry) error {
+	var b strings.Builder
+	for _, e := range entries {
+		if e.drop {
+			continue
+		}
+		b.WriteString(e.line)
+		if e.id > 0 {
+			fmt.Fprintf(&b, " tid:%d", e.id)
+		}
+		b.WriteString("\n")
+	}
+	return writeFileAtomic(filename, []byte(b.String()))
+}
+
+func readTodoTxtState(filename string) (*todoTxtState, error) {
+	state := &todoTxtState{}
+	if b, err := os.ReadFile(filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	} else if err == nil {
+		if err := json.Unmarshal(b, state); err != nil {
+			return nil, fmt.Errorf("invalid sync state %q: %w", filename, err)
+		}
+	}
+
+	if state.Lists == nil {
+		state.Lists = make(map[int]string)
+	}
+	if state.Tasks == nil {
+		state.Tasks = make(map[int]string)
+	}
+	return state, nil
+}
+
+func writeTodoTxtState(filename string, state *todoTxtState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, b)
+}
+
+// writeFileAtomic replaces the file so that it is never left partially written.
+func writeFileAtomic(filename string, b []byte) error {
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
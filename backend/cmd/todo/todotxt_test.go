@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// syncService is a todo.SyncService which logs the changes made through it to the in-memory lists and items.
+type syncService struct {
+	mu    sync.Mutex
+	items *inmem.ItemListService
+	log   []todo.Tombstone
+}
+
+func newSyncService(items *inmem.ItemListService) *syncService {
+	return &syncService{items: items}
+}
+
+func (s *syncService) FindChanges(ctx context.Context, since string) (*todo.ChangeSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token := 0
+	if since != "" {
+		var err error
+		if token, err = strconv.Atoi(since); err != nil || token > len(s.log) {
+			return nil, todo.Err(todo.EINVALID, "invalid sync token %q", since)
+		}
+	}
+
+	set := &todo.ChangeSet{Lists: []*todo.List{}, Items: []*todo.Item{}, Tombstones: []*todo.Tombstone{}, Token: strconv.Itoa(len(s.log))}
+	for _, change := range s.log[token:] {
+		if change.Entity == todo.EntityList {
+			list, err := s.items.FindListByID(ctx, change.ID)
+			if err != nil {
+				return nil, err
+			}
+			set.Lists = append(set.Lists, list)
+		} else if item, err := s.items.FindItemByID(ctx, change.ID); todo.ErrCode(err) == todo.ENOTFOUND {
+			tombstone := change
+			set.Tombstones = append(set.Tombstones, &tombstone)
+		} else if err != nil {
+			return nil, err
+		} else {
+			set.Items = append(set.Items, item)
+		}
+	}
+	return set, nil
+}
+
+func (s *syncService) PushMutations(ctx context.Context, mutations []*todo.Mutation) ([]*todo.MutationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make([]*todo.MutationResult, 0, len(mutations))
+	for _, m := range mutations {
+		result := &todo.MutationResult{ClientID: m.ClientID, Status: todo.MutationApplied}
+		var err error
+		switch {
+		case m.Entity == todo.EntityList && m.Op == todo.OpCreate:
+			result.List = &todo.List{Name: *m.Name}
+			if err = s.items.CreateList(ctx, result.List); err == nil {
+				s.log = append(s.log, todo.Tombstone{Entity: todo.EntityList, ID: result.List.ID})
+			}
+		case m.Entity == todo.EntityItem && m.Op == todo.OpCreate:
+			result.Item = &todo.Item{ListID: m.ListID, Name: *m.Name, Completed: m.Completed != nil && *m.Completed}
+			if err = s.items.CreateItem(ctx, result.Item); err == nil {
+				s.log = append(s.log, todo.Tombstone{Entity: todo.EntityItem, ID: result.Item.ID})
+			}
+		case m.Entity == todo.EntityItem && m.Op == todo.OpUpdate:
+			if result.Item, err = s.items.UpdateItem(ctx, m.ID, todo.ItemUpdate{Name: m.Name, Completed: m.Completed}); err == nil {
+				s.log = append(s.log, todo.Tombstone{Entity: todo.EntityItem, ID: m.ID})
+			}
+		case m.Entity == todo.EntityItem && m.Op == todo.OpDelete:
+			if err = s.items.DeleteItem(ctx, m.ID); err == nil {
+				s.log = append(s.log, todo.Tombstone{Entity: todo.EntityItem, ID: m.ID})
+			}
+		default:
+			err = todo.Err(todo.EINVALID, "unsupported mutation")
+		}
+		if err != nil {
+			result = &todo.MutationResult{ClientID: m.ClientID, Status: todo.MutationError, Error: todo.ErrMessage(err)}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func TestTodoTxtSync(t *testing.T) {
+	ts := newTestServer(t)
+	user := ts.login(t)
+	ctx := todo.NewContextWithUser(context.Background(), user)
+
+	// the list was created on the server, its name is not a valid +project
+	name, milk := "Grocery List", "buy milk"
+	results, err := ts.Sync.PushMutations(ctx, []*todo.Mutation{{Entity: todo.EntityList, Op: todo.OpCreate, Name: &name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := results[0].List
+	if _, err := ts.Sync.PushMutations(ctx, []*todo.Mutation{{Entity: todo.EntityItem, Op: todo.OpCreate, ListID: list.ID, Name: &milk}}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "todo.txt")
+	mustRun(t, "todotxt-sync", "-file", filename)
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	} else if got := string(b); !strings.Contains(got, "buy milk +Grocery_List tid:") {
+		t.Fatalf("unexpected file %q", got)
+	}
+
+	// a new task of the project is added to the list instead of a new one
+	if err := os.WriteFile(filename, append(b, "buy eggs +Grocery_List\n"...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mustRun(t, "todotxt-sync", "-file", filename)
+
+	lists, err := ts.Items.FindLists(ctx, todo.ListFilter{})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lists) != 1 || lists[0].ID != list.ID {
+		t.Fatalf("want only list %d got %v", list.ID, lists)
+	} else if got := len(lists[0].Items); got != 2 {
+		t.Fatalf("want 2 items got %d", got)
+	}
+}
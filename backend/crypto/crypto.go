@@ -2,6 +2,7 @@ package crypto
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 
 	"github.com/alexedwards/argon2id"
 	"github.com/cmokbel1/todo-app/backend/todo"
@@ -9,6 +10,7 @@ import (
 
 const alphabet = "`0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
 const passwordByteLen = 16
+const tokenByteLen = 32
 
 // RandomString creates a random 16 byte string using crypto/rand.
 func RandomString() string {
@@ -26,6 +28,16 @@ func RandomString() string {
 	return string(bytes)
 }
 
+// RandomToken creates a random hex encoded 32 byte token using crypto/rand. Unlike RandomString it is safe to use
+// in a URL.
+func RandomToken() string {
+	bytes := make([]byte, tokenByteLen)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
+
 // CreateHash creates a hash from a string
 func CreateHash(pw string) (string, error) {
 	hash, err := argon2id.CreateHash(pw, argon2id.DefaultParams)
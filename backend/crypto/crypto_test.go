@@ -39,3 +39,16 @@ func TestRandomString(t *testing.T) {
 		set[str] = struct{}{}
 	}
 }
+
+func TestRandomToken(t *testing.T) {
+	set := make(map[string]struct{})
+	for i := 0; i < 5000; i++ {
+		str := crypto.RandomToken()
+		if _, ok := set[str]; ok {
+			t.Fatalf("random token collision: %v", str)
+		} else if len(str) != 64 {
+			t.Fatalf("want random token len %v got %v", 64, len(str))
+		}
+		set[str] = struct{}{}
+	}
+}
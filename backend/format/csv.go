@@ -0,0 +1,131 @@
+package format
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// CSV has one row per item with the columns list, item and completed. Every list starts with a row with an empty
+// item, which is whether the list is completed. Consecutive rows with the same list name belong to the same list
+// unless a row with an empty item starts another one, so lists with the same name are kept apart.
+var CSV = register(&Format{
+	Name:        "csv",
+	ContentType: "text/csv; charset=utf-8",
+	Extension:   ".csv",
+	Encode:      encodeCSV,
+	Decode:      decodeCSV,
+})
+
+var csvHeader = []string{"list", "item", "completed"}
+
+func encodeCSV(w io.Writer, next ListReader) error {
+	cw := csv.NewWriter(w)
+	next = flushed(next, func() error {
+		cw.Flush()
+		return cw.Error()
+	})
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	err := eachList(next, func(list *todo.List) error {
+		if err := cw.Write([]string{list.Name, "", strconv.FormatBool(list.Completed)}); err != nil {
+			return err
+		}
+		for _, item := range list.Items {
+			if err := cw.Write([]string{list.Name, item.Name, strconv.FormatBool(item.Completed)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func decodeCSV(r io.Reader) ([]*todo.List, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, todo.Err(todo.EINVALID, "csv header required")
+	} else if err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid csv: %v", err)
+	}
+
+	// columns are matched by name so that they can be in any order
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := columns[name]; !ok {
+			return nil, todo.Err(todo.EINVALID, "csv header must contain the columns %s", strings.Join(csvHeader, ", "))
+		}
+	}
+
+	var lists []*todo.List
+	var list *todo.List
+	// rows is the number of rows of list, a row with an empty item after them starts another list
+	rows := 0
+	var errs rowErrors
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			errs.add(row, "%v", err)
+			continue
+		}
+
+		field := func(name string) string {
+			if i := columns[name]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		name := field("list")
+		if name == "" {
+			errs.add(row, "list name required")
+			continue
+		}
+
+		completed := false
+		if v := field("completed"); v != "" {
+			if completed, err = strconv.ParseBool(v); err != nil {
+				errs.add(row, "completed must be true or false, got %q", v)
+				continue
+			}
+		}
+
+		item := field("item")
+		if list == nil || list.Name != name || (item == "" && rows > 0) {
+			list = &todo.List{Name: name, Items: make([]*todo.Item, 0)}
+			lists = append(lists, list)
+			rows = 0
+		}
+		rows++
+
+		if item != "" {
+			list.Items = append(list.Items, &todo.Item{Name: item, Completed: completed})
+		} else {
+			list.Completed = completed
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
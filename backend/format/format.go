@@ -0,0 +1,166 @@
+// Package format encodes and decodes Lists to and from the file formats supported for import and export.
+package format
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Format is a file format for Lists.
+type Format struct {
+	// Name identifies the format in the format query parameter, e.g. "csv".
+	Name        string
+	ContentType string
+	// Extension is the file extension including the leading dot.
+	Extension string
+
+	// Encode writes the lists and their items to w as they are read, what has been encoded is written to w before
+	// the next lists are read. Encode is nil for formats which only support import.
+	Encode func(w io.Writer, next ListReader) error
+	// Decode reads the lists and their items from r. Invalid input is reported as RowErrors. Decode is nil for
+	// formats which only support export.
+	Decode func(r io.Reader) ([]*todo.List, error)
+}
+
+// ListReader returns the next lists to encode, and no lists once all of them have been read. It lets many lists be
+// encoded a page at a time rather than loading all of them first.
+type ListReader func() ([]*todo.List, error)
+
+// Lists returns a ListReader of lists which are already loaded.
+func Lists(lists []*todo.List) ListReader {
+	return func() ([]*todo.List, error) {
+		next := lists
+		lists = nil
+		return next, nil
+	}
+}
+
+// flushed returns a ListReader which calls flush before reading from next, so that the lists encoded so far are
+// written while the next ones are loaded.
+func flushed(next ListReader, flush func() error) ListReader {
+	return func() ([]*todo.List, error) {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		return next()
+	}
+}
+
+// eachList calls fn with every list read from next.
+func eachList(next ListReader, fn func(list *todo.List) error) error {
+	for {
+		lists, err := next()
+		if err != nil {
+			return err
+		} else if len(lists) == 0 {
+			return nil
+		}
+		for _, list := range lists {
+			if err := fn(list); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+var formats = make(map[string]*Format)
+
+func register(f *Format) *Format {
+	formats[f.Name] = f
+	return f
+}
+
+// Lookup returns the Format with the given name.
+func Lookup(name string) (*Format, error) {
+	if f, ok := formats[name]; ok {
+		return f, nil
+	}
+	return nil, todo.Err(todo.EINVALID, "unknown format %q, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all formats in alphabetical order.
+func Names() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RowError is an error in a single row of the decoded input. Rows start at 1, for formats without rows such as
+// JSON the row is the position of the list.
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("row %d: %s", e.Row, e.Message)
+}
+
+// RowErrors are all the errors found while decoding.
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0].Error(), len(e)-1)
+}
+
+// Is and As let RowErrors be handled as invalid errors by callers which do not report the rows.
+func (e RowErrors) Is(target error) bool {
+	return e.invalid().Is(target)
+}
+
+func (e RowErrors) As(target interface{}) bool {
+	if t, ok := target.(**todo.Error); ok {
+		*t = e.invalid()
+		return true
+	}
+	return false
+}
+
+func (e RowErrors) invalid() *todo.Error {
+	return &todo.Error{Code: todo.EINVALID, Message: e.Error()}
+}
+
+// ValidateLists returns RowErrors for all the lists and items which cannot be imported. The row is the position of
+// the list.
+func ValidateLists(lists []*todo.List) error {
+	var errs rowErrors
+	for i, list := range lists {
+		if list == nil {
+			errs.add(i+1, "list required")
+			continue
+		} else if list.Name == "" {
+			errs.add(i+1, "list name required")
+		}
+		for j, item := range list.Items {
+			if item == nil || item.Name == "" {
+				errs.add(i+1, "item %d: name required", j+1)
+			}
+		}
+	}
+	return errs.err()
+}
+
+// rowErrors collects RowErrors while decoding.
+type rowErrors RowErrors
+
+func (e *rowErrors) add(row int, format string, args ...interface{}) {
+	*e = append(*e, RowError{Row: row, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the collected errors or nil if there were none.
+func (e rowErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return RowErrors(e)
+}
@@ -0,0 +1,480 @@
+package format_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func testLists() []*todo.List {
+	return []*todo.List{
+		{Name: "Groceries", Items: []*todo.Item{
+			{Name: "milk"},
+			{Name: "eggs, large", Completed: true},
+		}},
+		{Name: "Empty", Items: []*todo.Item{}},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, name := range []string{"json", "csv", "markdown"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := format.Lookup(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			if err := f.Encode(&buf, format.Lists(testLists())); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := f.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := testLists(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("want %v got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := format.Markdown.Encode(&buf, format.Lists(testLists())); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "# Groceries\n\n- [ ] milk\n- [x] eggs, large\n\n# Empty\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	// the completion of lists with items is kept, lists with the same name are kept apart
+	lists := []*todo.List{
+		{Name: "Groceries", Completed: true, Items: []*todo.Item{{Name: "milk", Completed: true}}},
+		{Name: "Groceries", Items: []*todo.Item{{Name: "eggs"}}},
+	}
+	var buf bytes.Buffer
+	if err := format.CSV.Encode(&buf, format.Lists(lists)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "list,item,completed\nGroceries,,true\nGroceries,milk,true\nGroceries,,false\nGroceries,eggs,false\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+	got, err := format.CSV.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, lists) {
+		t.Fatalf("want %v got %v", lists, got)
+	}
+}
+
+func TestValidateLists(t *testing.T) {
+	lists := []*todo.List{
+		{Name: "Groceries", Items: []*todo.Item{{Name: "milk"}, {Name: ""}}},
+		nil,
+		{Name: "", Items: []*todo.Item{{Name: ""}}},
+	}
+	err := format.ValidateLists(lists)
+	var rowErrs format.RowErrors
+	if !errors.As(err, &rowErrs) {
+		t.Fatalf("want row errors got %v", err)
+	}
+	want := format.RowErrors{
+		{Row: 1, Message: "item 2: name required"},
+		{Row: 2, Message: "list required"},
+		{Row: 3, Message: "list name required"},
+		{Row: 3, Message: "item 1: name required"},
+	}
+	if !reflect.DeepEqual(rowErrs, want) {
+		t.Fatalf("want %v got %v", want, rowErrs)
+	}
+	// the errors are invalid errors for callers which do not report the rows
+	if !errors.Is(err, todo.Invalid) || todo.ErrCode(err) != todo.EINVALID {
+		t.Fatalf("want invalid error got %v", err)
+	}
+}
+
+func TestEncodePages(t *testing.T) {
+	// the lists are read until the reader returns none
+	pages := [][]*todo.List{testLists()[:1], testLists()[1:]}
+	next := func() ([]*todo.List, error) {
+		if len(pages) == 0 {
+			return nil, nil
+		}
+		page := pages[0]
+		pages = pages[1:]
+		return page, nil
+	}
+	var buf bytes.Buffer
+	if err := format.JSON.Encode(&buf, next); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := format.JSON.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := testLists(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tt := []struct {
+		Format *format.Format
+		Input  string
+		Want   format.RowErrors
+	}{
+		{
+			Format: format.CSV,
+			Input:  "list,item,completed\nGroceries,milk,yes please\n,eggs,false\nGroceries,bread,\n",
+			Want: format.RowErrors{
+				{Row: 2, Message: `completed must be true or false, got "yes please"`},
+				{Row: 3, Message: "list name required"},
+			},
+		},
+		{
+			Format: format.Markdown,
+			Input:  "- [ ] orphan\n# Groceries\n- [x]\nsome text\n",
+			Want: format.RowErrors{
+				{Row: 1, Message: "item must follow a heading"},
+				{Row: 3, Message: "item name required"},
+				{Row: 4, Message: `expected a heading or a checklist item like "- [ ] name"`},
+			},
+		},
+		{
+			Format: format.JSON,
+			Input:  `[{"name": "Groceries", "items": [{"name": ""}]}, {"name": ""}]`,
+			Want: format.RowErrors{
+				{Row: 1, Message: "item 1: name required"},
+				{Row: 2, Message: "list name required"},
+			},
+		},
+		{
+			Format: format.Todoist,
+			Input:  "TYPE,CONTENT\ntask,\nsection,\ntask,milk\n",
+			Want:   format.RowErrors{{Row: 2, Message: "task content required"}},
+		},
+		{
+			Format: format.Trello,
+			Input:  `{"name": "Board", "lists": [{"id": "l1"}], "cards": [{"id": "c1", "idList": "l1", "name": " "}]}`,
+			Want:   format.RowErrors{{Row: 1, Message: "card name required"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Format.Name, func(t *testing.T) {
+			_, err := tc.Format.Decode(strings.NewReader(tc.Input))
+			var got format.RowErrors
+			if !errors.As(err, &got) {
+				t.Fatalf("want row errors got %v", err)
+			} else if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("want %v got %v", tc.Want, got)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if _, err := format.Lookup("xml"); todo.ErrCode(err) != todo.EINVALID {
+		t.Fatalf("want invalid got %v", err)
+	}
+}
+
+func TestParseTodoTxtTask(t *testing.T) {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+
+	tt := []struct {
+		Line string
+		Want format.TodoTxtTask
+	}{
+		{
+			Line: "(A) Call Mom +Family @phone",
+			Want: format.TodoTxtTask{Priority: "A", Description: "Call Mom +Family @phone"},
+		},
+		{
+			Line: "(B) 2011-03-01 Schedule checkup due:2011-03-10",
+			Want: format.TodoTxtTask{Priority: "B", CreatedAt: date("2011-03-01"), Description: "Schedule checkup due:2011-03-10"},
+		},
+		{
+			Line: "x 2011-03-03 2011-03-01 Review proposal +Work",
+			Want: format.TodoTxtTask{Completed: true, CompletedAt: date("2011-03-03"), CreatedAt: date("2011-03-01"), Description: "Review proposal +Work"},
+		},
+		{
+			Line: "x 2011-03-02 Buy milk",
+			Want: format.TodoTxtTask{Completed: true, CompletedAt: date("2011-03-02"), Description: "Buy milk"},
+		},
+		{
+			// neither a priority nor a completion marker
+			Line: "xylophone lesson (A) 2011-03-01",
+			Want: format.TodoTxtTask{Description: "xylophone lesson (A) 2011-03-01"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Line, func(t *testing.T) {
+			got := format.ParseTodoTxtTask(tc.Line)
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("want %+v got %+v", tc.Want, got)
+			} else if got.String() != tc.Line {
+				t.Fatalf("want line %q got %q", tc.Line, got.String())
+			}
+		})
+	}
+}
+
+func TestTodoTxtRoundTrip(t *testing.T) {
+	input := "(A) 2011-03-01 Call Mom +Family @phone\n" +
+		"x 2011-03-03 2011-03-01 Review proposal +Work pri:B\n" +
+		"Water plants @home key:value\n" +
+		"(C) Plan trip +Family +Travel\n"
+
+	lists, err := format.TodoTxt.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, list := range lists {
+		names = append(names, list.Name)
+	}
+	if want := []string{"Family", "Work", format.TodoTxtDefaultList}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("want lists %v got %v", want, names)
+	} else if got, want := lists[0].Items[0].Name, "(A) Call Mom +Family @phone"; got != want {
+		t.Fatalf("want item name %q got %q", want, got)
+	}
+
+	var buf bytes.Buffer
+	if err := format.TodoTxt.Encode(&buf, format.Lists(lists)); err != nil {
+		t.Fatal(err)
+	}
+
+	// tasks are grouped by list
+	want := "(A) 2011-03-01 Call Mom +Family @phone\n" +
+		"(C) Plan trip +Family +Travel\n" +
+		"x 2011-03-03 2011-03-01 Review proposal +Work pri:B\n" +
+		"Water plants @home key:value\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestNewTodoTxtTask(t *testing.T) {
+	item := &todo.Item{Name: "Buy milk", CreatedAt: time.Date(2022, 5, 1, 15, 4, 5, 0, time.UTC)}
+	if got, want := format.NewTodoTxtTask("Weekly groceries", item).String(), "2022-05-01 Buy milk +Weekly_groceries"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestEncodeICal(t *testing.T) {
+	created := time.Date(2022, 5, 1, 15, 4, 5, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	lists := []*todo.List{{Name: "Groceries", Items: []*todo.Item{
+		{ID: 1, Name: "milk; eggs, bread", CreatedAt: created, UpdatedAt: updated},
+		{ID: 2, Name: "pay rent", Completed: true, CreatedAt: created, UpdatedAt: updated},
+	}}}
+
+	var buf bytes.Buffer
+	if err := format.ICal.Encode(&buf, format.Lists(lists)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"X-WR-CALNAME:Groceries\r\n",
+		"BEGIN:VTODO\r\nUID:item-1@todo-app\r\nDTSTAMP:20220501T160405Z\r\nSUMMARY:milk\\; eggs\\, bread\r\n" +
+			"CATEGORIES:Groceries\r\nSTATUS:NEEDS-ACTION\r\nCREATED:20220501T150405Z\r\n",
+		"STATUS:COMPLETED\r\nCOMPLETED:20220501T160405Z\r\nPERCENT-COMPLETE:100\r\n",
+		"END:VTODO\r\nEND:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("want %q in %q", want, got)
+		}
+	}
+}
+
+func TestEncodeICalFolding(t *testing.T) {
+	name := strings.Repeat("ü", 60)
+	lists := []*todo.List{{Name: "List", Items: []*todo.Item{{ID: 1, Name: name}}}}
+
+	var buf bytes.Buffer
+	if err := format.ICal.Encode(&buf, format.Lists(lists)); err != nil {
+		t.Fatal(err)
+	}
+
+	var summary string
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line %d is %d octets long", i+1, len(line))
+		} else if !utf8.ValidString(line) {
+			t.Fatalf("line %d splits a character: %q", i+1, line)
+		}
+
+		if strings.HasPrefix(line, "SUMMARY:") {
+			summary = line
+		} else if summary != "" && strings.HasPrefix(line, " ") {
+			summary += line[1:]
+		} else if summary != "" {
+			break
+		}
+	}
+	if want := "SUMMARY:" + name; summary != want {
+		t.Fatalf("want unfolded %q got %q", want, summary)
+	}
+}
+
+func TestDecodeTodoist(t *testing.T) {
+	csv := "\ufeffTYPE,CONTENT,PRIORITY,INDENT\ntask,milk,4,1\nsection,Dairy,,\ntask,\"eggs, large\",4,2\n\n"
+
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for _, name := range []string{"Groceries [2203306141].csv", "Work [2203306142].csv"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(csv)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	added := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	completed := time.Date(2022, 5, 2, 10, 0, 0, 0, time.UTC)
+	items := func() []*todo.Item {
+		return []*todo.Item{{Name: "milk"}, {Name: "eggs, large"}}
+	}
+	fromSync := []*todo.List{
+		{Name: "Groceries", Items: []*todo.Item{
+			{Name: "milk", CreatedAt: added},
+			{Name: "eggs", Completed: true, CreatedAt: added, UpdatedAt: completed},
+		}},
+		{Name: format.TodoistDefaultList, Items: []*todo.Item{{Name: "orphan"}}},
+	}
+
+	tt := []struct {
+		Name  string
+		Input string
+		Want  []*todo.List
+	}{
+		{
+			Name:  "CSV",
+			Input: csv,
+			Want:  []*todo.List{{Name: format.TodoistDefaultList, Items: items()}},
+		},
+		{
+			Name:  "Zip",
+			Input: zipped.String(),
+			Want:  []*todo.List{{Name: "Groceries", Items: items()}, {Name: "Work", Items: items()}},
+		},
+		{
+			Name: "SyncV9",
+			Input: `{"projects": [{"id": "1", "name": "Groceries"}, {"id": "2", "name": "Old", "is_deleted": true}],
+				"items": [
+					{"project_id": "1", "content": "milk", "added_at": "2022-05-01T10:00:00Z"},
+					{"project_id": "1", "content": "eggs", "checked": true, "added_at": "2022-05-01T10:00:00Z", "completed_at": "2022-05-02T10:00:00Z"},
+					{"project_id": "1", "content": "gone", "is_deleted": true},
+					{"project_id": "2", "content": "deleted with its project"},
+					{"project_id": "3", "content": "orphan"}
+				]}`,
+			Want: fromSync,
+		},
+		{
+			Name: "SyncV8",
+			Input: `{"projects": [{"id": 1, "name": "Groceries", "is_deleted": 0}],
+				"items": [
+					{"project_id": 1, "content": "milk", "checked": 0, "date_added": "Sun 01 May 2022 10:00:00 +0000"},
+					{"project_id": 1, "content": "eggs", "checked": 1, "date_added": "Sun 01 May 2022 10:00:00 +0000", "completed_at": "2022-05-02T10:00:00Z"},
+					{"project_id": 3, "content": "orphan", "checked": 0, "date_added": null}
+				]}`,
+			Want: fromSync,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := format.Todoist.Decode(strings.NewReader(tc.Input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.Want) {
+				t.Fatalf("want %v got %v", tc.Want, got)
+			}
+		})
+	}
+
+	if _, err := format.Todoist.Decode(strings.NewReader("name,done\nmilk,false\n")); todo.ErrCode(err) != todo.EINVALID {
+		t.Fatalf("want invalid for a csv without the Todoist columns got %v", err)
+	}
+}
+
+func TestDecodeTrello(t *testing.T) {
+	input := `{
+		"name": "Groceries",
+		"lists": [
+			{"id": "l2", "name": "Done", "pos": 2},
+			{"id": "l1", "name": "To do", "pos": 1},
+			{"id": "l3", "name": "Archived", "closed": true, "pos": 3}
+		],
+		"cards": [
+			{"id": "626e5a200000000000000002", "idList": "l2", "name": "eggs", "dueComplete": true, "pos": 1,
+				"dateLastActivity": "2022-05-02T10:00:00.000Z"},
+			{"id": "626e5a200000000000000001", "idList": "l1", "name": "milk", "pos": 1},
+			{"id": "626e5a200000000000000003", "idList": "l1", "name": "bread", "closed": true, "pos": 2},
+			{"id": "626e5a200000000000000004", "idList": "l3", "name": "cheese", "pos": 1}
+		],
+		"checklists": [
+			{"id": "k1", "idCard": "626e5a200000000000000001", "pos": 1, "checkItems": [
+				{"id": "626e5a210000000000000006", "name": "skimmed", "state": "incomplete", "pos": 2},
+				{"id": "626e5a210000000000000005", "name": "oat", "state": "complete", "pos": 1}
+			]}
+		]
+	}`
+
+	got, err := format.Trello.Decode(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	want := []*todo.List{{Name: "Groceries", Items: []*todo.Item{
+		{Name: "milk", CreatedAt: created},
+		{Name: "milk: oat", Completed: true, CreatedAt: created.Add(time.Second)},
+		{Name: "milk: skimmed", CreatedAt: created.Add(time.Second)},
+		{Name: "eggs", Completed: true, CreatedAt: created, UpdatedAt: time.Date(2022, 5, 2, 10, 0, 0, 0, time.UTC)},
+	}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v got %v", want, got)
+	}
+}
+
+func TestImportOnly(t *testing.T) {
+	for _, f := range []*format.Format{format.Todoist, format.Trello} {
+		if f.Encode != nil || f.Decode == nil {
+			t.Fatalf("want %s to be import only", f.Name)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// ICal is an RFC 5545 iCalendar with a VTODO for every item. The name of the list is the category of its items.
+// It can only be exported.
+var ICal = register(&Format{
+	Name:        "ical",
+	ContentType: "text/calendar; charset=utf-8",
+	Extension:   ".ics",
+	Encode:      encodeICal,
+})
+
+// icalDateTime is the layout of UTC date-times in iCalendar.
+const icalDateTime = "20060102T150405Z"
+
+// icalLineLength is the maximum length of a content line in octets, excluding the line break.
+const icalLineLength = 75
+
+func encodeICal(w io.Writer, next ListReader) error {
+	bw := bufio.NewWriter(w)
+	next = flushed(next, bw.Flush)
+	line := func(name, value string) {
+		writeICalLine(bw, name+":"+value)
+	}
+
+	// the calendar is named after the list if there is only one, which is known once a second list is read
+	var lists []*todo.List
+	for len(lists) < 2 {
+		batch, err := next()
+		if err != nil {
+			return err
+		} else if len(batch) == 0 {
+			break
+		}
+		lists = append(lists, batch...)
+	}
+	calendar := "Todos"
+	if len(lists) == 1 {
+		calendar = lists[0].Name
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//todo-app//todo-app "+todo.Build.Version+"//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", icalText(calendar))
+	writeList := func(list *todo.List) error {
+		for _, item := range list.Items {
+			line("BEGIN", "VTODO")
+			line("UID", ICalUID(item))
+			// without a METHOD, DTSTAMP is the time the item was last modified
+			line("DTSTAMP", icalTime(item.UpdatedAt))
+			line("SUMMARY", icalText(item.Name))
+			line("CATEGORIES", icalText(list.Name))
+			if item.Completed {
+				line("STATUS", "COMPLETED")
+				line("COMPLETED", icalTime(item.UpdatedAt))
+				line("PERCENT-COMPLETE", "100")
+			} else {
+				line("STATUS", "NEEDS-ACTION")
+			}
+			line("CREATED", icalTime(item.CreatedAt))
+			line("LAST-MODIFIED", icalTime(item.UpdatedAt))
+			line("END", "VTODO")
+		}
+		return nil
+	}
+	if err := eachList(Lists(lists), writeList); err != nil {
+		return err
+	} else if len(lists) > 1 {
+		if err := eachList(next, writeList); err != nil {
+			return err
+		}
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// ICalUID returns the globally unique identifier of an item in iCalendar.
+func ICalUID(item *todo.Item) string {
+	return fmt.Sprintf("item-%d@todo-app", item.ID)
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format(icalDateTime)
+}
+
+// icalText escapes a TEXT value.
+func icalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// writeICalLine writes a content line folded after every 75 octets without splitting UTF-8 characters.
+func writeICalLine(w *bufio.Writer, s string) {
+	limit := icalLineLength
+	for len(s) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(s[i]) {
+			i--
+		}
+		w.WriteString(s[:i] + "\r\n ")
+		s = s[i:]
+		// continuation lines start with a space which counts towards the limit
+		limit = icalLineLength - 1
+	}
+	w.WriteString(s + "\r\n")
+}
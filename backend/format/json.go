@@ -0,0 +1,68 @@
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// JSON is an array of lists as returned by the API.
+var JSON = register(&Format{
+	Name:        "json",
+	ContentType: "application/json",
+	Extension:   ".json",
+	Encode:      encodeJSON,
+	Decode:      decodeJSON,
+})
+
+// encodeJSON writes the array one list at a time, indented the same as a json.Encoder would.
+func encodeJSON(w io.Writer, next ListReader) error {
+	bw := bufio.NewWriter(w)
+	next = flushed(next, bw.Flush)
+	n := 0
+	err := eachList(next, func(list *todo.List) error {
+		b, err := json.MarshalIndent(list, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			bw.WriteString("[\n  ")
+		} else {
+			bw.WriteString(",\n  ")
+		}
+		n++
+		_, err = bw.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		bw.WriteString("[]\n")
+	} else {
+		bw.WriteString("\n]\n")
+	}
+	return bw.Flush()
+}
+
+func decodeJSON(r io.Reader) ([]*todo.List, error) {
+	var lists []*todo.List
+	if err := json.NewDecoder(r).Decode(&lists); err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid json: %v", err)
+	}
+
+	if err := ValidateLists(lists); err != nil {
+		return nil, err
+	}
+
+	for _, list := range lists {
+		list.ID, list.UserID = 0, 0
+		for _, item := range list.Items {
+			item.ID, item.UserID, item.ListID = 0, 0, 0
+		}
+	}
+	return lists, nil
+}
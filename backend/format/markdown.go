@@ -0,0 +1,102 @@
+package format
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Markdown has a heading for every list followed by a checklist of its items, e.g.
+//
+//	# Groceries
+//
+//	- [ ] milk
+//	- [x] eggs
+var Markdown = register(&Format{
+	Name:        "markdown",
+	ContentType: "text/markdown; charset=utf-8",
+	Extension:   ".md",
+	Encode:      encodeMarkdown,
+	Decode:      decodeMarkdown,
+})
+
+var (
+	markdownHeading = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	markdownItem    = regexp.MustCompile(`^\s*[-*+]\s+\[([ xX])\]\s*(.*?)\s*$`)
+)
+
+func encodeMarkdown(w io.Writer, next ListReader) error {
+	bw := bufio.NewWriter(w)
+	next = flushed(next, bw.Flush)
+	first := true
+	err := eachList(next, func(list *todo.List) error {
+		if !first {
+			bw.WriteString("\n")
+		}
+		first = false
+		fmt.Fprintf(bw, "# %s\n", singleLine(list.Name))
+		if len(list.Items) > 0 {
+			bw.WriteString("\n")
+		}
+		for _, item := range list.Items {
+			check := " "
+			if item.Completed {
+				check = "x"
+			}
+			fmt.Fprintf(bw, "- [%s] %s\n", check, singleLine(item.Name))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func decodeMarkdown(r io.Reader) ([]*todo.List, error) {
+	var lists []*todo.List
+	var list *todo.List
+	var errs rowErrors
+
+	scanner := bufio.NewScanner(r)
+	for row := 1; scanner.Scan(); row++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if m := markdownHeading.FindStringSubmatch(line); m != nil {
+			if m[1] == "" {
+				errs.add(row, "list name required")
+			}
+			list = &todo.List{Name: m[1], Items: make([]*todo.Item, 0)}
+			lists = append(lists, list)
+		} else if m := markdownItem.FindStringSubmatch(line); m != nil {
+			if list == nil {
+				errs.add(row, "item must follow a heading")
+			} else if m[2] == "" {
+				errs.add(row, "item name required")
+			} else {
+				list.Items = append(list.Items, &todo.Item{Name: m[2], Completed: m[1] != " "})
+			}
+		} else {
+			errs.add(row, "expected a heading or a checklist item like \"- [ ] name\"")
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid markdown: %v", err)
+	} else if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
+
+// singleLine replaces line breaks so that a name cannot span multiple lines of a line based format.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
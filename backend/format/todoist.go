@@ -0,0 +1,266 @@
+package format
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Todoist reads Todoist backups, which can only be imported. A backup is either the zip file of CSV files
+// downloaded from the Todoist backups page, a single CSV file of a project or the JSON returned by the Todoist
+// sync API. Every project is a list and every task, including sub-tasks, is an item.
+//
+// CSV files do not contain completed tasks nor creation times. A single CSV file does not contain the name of its
+// project either, so its tasks go to the TodoistDefaultList.
+var Todoist = register(&Format{
+	Name:   "todoist",
+	Decode: decodeTodoist,
+})
+
+// TodoistDefaultList is the list of tasks whose project is unknown.
+const TodoistDefaultList = "Todoist"
+
+func decodeTodoist(r io.Reader) ([]*todo.List, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch trimmed := bytes.TrimSpace(b); {
+	case bytes.HasPrefix(b, []byte("PK\x03\x04")):
+		return decodeTodoistZip(b)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return decodeTodoistJSON(trimmed)
+	default:
+		return decodeTodoistCSV(TodoistDefaultList, bytes.NewReader(b))
+	}
+}
+
+// todoistProjectID matches the ID which Todoist appends to the file names of projects, e.g. "Work [2203306141]".
+var todoistProjectID = regexp.MustCompile(`\s*\[\d+\]$`)
+
+func decodeTodoistZip(b []byte) ([]*todo.List, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid zip file: %v", err)
+	}
+
+	var lists []*todo.List
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !strings.EqualFold(path.Ext(f.Name), ".csv") {
+			continue
+		}
+
+		name := strings.TrimSuffix(path.Base(f.Name), path.Ext(f.Name))
+		name = todoistProjectID.ReplaceAllString(name, "")
+		if name == "" {
+			name = TodoistDefaultList
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, todo.Err(todo.EINVALID, "invalid zip file: %v", err)
+		}
+		projectLists, err := decodeTodoistCSV(name, rc)
+		rc.Close()
+		if err != nil {
+			return nil, todo.Err(todo.EINVALID, "%s: %s", f.Name, todo.ErrMessage(err))
+		}
+		lists = append(lists, projectLists...)
+	}
+
+	if len(lists) == 0 {
+		return nil, todo.Err(todo.EINVALID, "the zip file does not contain any projects")
+	}
+	return lists, nil
+}
+
+// decodeTodoistCSV reads the CSV export of a single project. Only rows of the type "task" are imported, sections
+// and comments are skipped.
+func decodeTodoistCSV(name string, r io.Reader) ([]*todo.List, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, todo.Err(todo.EINVALID, "csv header required")
+	} else if err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid csv: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, column := range header {
+		// Excel prepends a byte order mark to the first column
+		columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))] = i
+	}
+	for _, column := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[column]; !ok {
+			return nil, todo.Err(todo.EINVALID, "csv header must contain the columns TYPE and CONTENT")
+		}
+	}
+
+	list := &todo.List{Name: name, Items: make([]*todo.Item, 0)}
+	var errs rowErrors
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			errs.add(row, "%v", err)
+			continue
+		}
+
+		field := func(column string) string {
+			if i := columns[column]; i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		if !strings.EqualFold(field("TYPE"), "task") {
+			continue
+		} else if content := singleLine(field("CONTENT")); content == "" {
+			errs.add(row, "task content required")
+		} else {
+			list.Items = append(list.Items, &todo.Item{Name: content})
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return []*todo.List{list}, nil
+}
+
+type todoistBackup struct {
+	Projects []struct {
+		ID        todoistID   `json:"id"`
+		Name      string      `json:"name"`
+		IsDeleted todoistFlag `json:"is_deleted"`
+	} `json:"projects"`
+	Items []struct {
+		ProjectID todoistID   `json:"project_id"`
+		Content   string      `json:"content"`
+		Checked   todoistFlag `json:"checked"`
+		IsDeleted todoistFlag `json:"is_deleted"`
+		// AddedAt is named DateAdded in version 8 of the sync API.
+		AddedAt     todoistTime `json:"added_at"`
+		DateAdded   todoistTime `json:"date_added"`
+		CompletedAt todoistTime `json:"completed_at"`
+	} `json:"items"`
+}
+
+// todoistID is a string in version 9 of the sync API and a number before.
+type todoistID string
+
+func (id *todoistID) UnmarshalJSON(b []byte) error {
+	*id = todoistID(strings.Trim(string(b), `"`))
+	return nil
+}
+
+// todoistFlag is a boolean in version 9 of the sync API and 0 or 1 before.
+type todoistFlag bool
+
+func (f *todoistFlag) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "true", "1":
+		*f = true
+	case "false", "0", "null":
+		*f = false
+	default:
+		return errors.New("expected a boolean, got " + string(b))
+	}
+	return nil
+}
+
+// todoistTime is either RFC 3339 or, before version 9 of the sync API, a date like "Fri 26 Sep 2014 08:25:05 +0000".
+type todoistTime time.Time
+
+func (t *todoistTime) UnmarshalJSON(b []byte) error {
+	var s string
+	if string(b) == "null" {
+		return nil
+	} else if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	} else if s == "" {
+		return nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, "Mon 02 Jan 2006 15:04:05 -0700"} {
+		if v, err := time.Parse(layout, s); err == nil {
+			*t = todoistTime(v.UTC())
+			return nil
+		}
+	}
+	return errors.New("invalid time " + string(b))
+}
+
+func decodeTodoistJSON(b []byte) ([]*todo.List, error) {
+	var backup todoistBackup
+	if err := json.Unmarshal(b, &backup); err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid json: %v", err)
+	}
+
+	var lists []*todo.List
+	byID := make(map[todoistID]*todo.List)
+	// the tasks of deleted projects are deleted with them, they are not orphans
+	deleted := make(map[todoistID]bool)
+	for _, p := range backup.Projects {
+		if p.IsDeleted {
+			deleted[p.ID] = true
+			continue
+		}
+		list := &todo.List{Name: strings.TrimSpace(singleLine(p.Name)), Items: make([]*todo.Item, 0)}
+		if list.Name == "" {
+			list.Name = TodoistDefaultList
+		}
+		byID[p.ID] = list
+		lists = append(lists, list)
+	}
+
+	var errs rowErrors
+	for i, task := range backup.Items {
+		if bool(task.IsDeleted) || deleted[task.ProjectID] {
+			continue
+		}
+
+		name := strings.TrimSpace(singleLine(task.Content))
+		if name == "" {
+			errs.add(i+1, "task content required")
+			continue
+		}
+
+		list, ok := byID[task.ProjectID]
+		if !ok {
+			list = &todo.List{Name: TodoistDefaultList, Items: make([]*todo.Item, 0)}
+			byID[task.ProjectID] = list
+			lists = append(lists, list)
+		}
+
+		createdAt := time.Time(task.AddedAt)
+		if createdAt.IsZero() {
+			createdAt = time.Time(task.DateAdded)
+		}
+		list.Items = append(list.Items, &todo.Item{
+			Name:      name,
+			Completed: bool(task.Checked),
+			CreatedAt: createdAt,
+			// a completed task was last modified when it was completed
+			UpdatedAt: time.Time(task.CompletedAt),
+		})
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
@@ -0,0 +1,230 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// TodoTxt is the todo.txt format, see https://github.com/todotxt/todo.txt. The first +project of a task is the
+// list it belongs to and tasks without a project belong to the TodoTxtDefaultList. Priorities, +projects,
+// @contexts and key:value extensions are kept verbatim in the item name so that a round trip is lossless.
+var TodoTxt = register(&Format{
+	Name:        "todotxt",
+	ContentType: "text/plain; charset=utf-8",
+	Extension:   ".txt",
+	Encode:      encodeTodoTxt,
+	Decode:      decodeTodoTxt,
+})
+
+// TodoTxtDefaultList is the list of tasks without a +project.
+const TodoTxtDefaultList = "Inbox"
+
+// TodoTxtDate is the layout of dates in todo.txt.
+const TodoTxtDate = "2006-01-02"
+
+var (
+	todoTxtPriority = regexp.MustCompile(`^\(([A-Z])\) `)
+	todoTxtDateWord = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// TodoTxtTask is a single line of a todo.txt file.
+type TodoTxtTask struct {
+	Completed bool
+	// Priority is a single upper case letter or empty.
+	Priority string
+	// CompletedAt and CreatedAt are zero if the task does not specify them.
+	CompletedAt time.Time
+	CreatedAt   time.Time
+	// Description is the remainder of the line including +projects, @contexts and key:value extensions.
+	Description string
+}
+
+// ParseTodoTxtTask parses a single line of a todo.txt file.
+func ParseTodoTxtTask(line string) TodoTxtTask {
+	var t TodoTxtTask
+	rest := strings.TrimSpace(line)
+
+	if strings.HasPrefix(rest, "x ") {
+		t.Completed = true
+		rest = strings.TrimLeft(rest[2:], " ")
+	} else if m := todoTxtPriority.FindStringSubmatch(rest); m != nil {
+		t.Priority = m[1]
+		rest = strings.TrimLeft(rest[len(m[0]):], " ")
+	}
+
+	// a completed task has the completion date first, the creation date is only allowed after it
+	var dates []time.Time
+	for len(dates) < 2 {
+		word := rest
+		if i := strings.IndexByte(rest, ' '); i >= 0 {
+			word = rest[:i]
+		}
+		if !todoTxtDateWord.MatchString(word) {
+			break
+		}
+		date, err := time.Parse(TodoTxtDate, word)
+		if err != nil {
+			break
+		}
+		dates = append(dates, date)
+		rest = strings.TrimLeft(rest[len(word):], " ")
+		if !t.Completed {
+			break
+		}
+	}
+
+	switch {
+	case t.Completed && len(dates) == 2:
+		t.CompletedAt, t.CreatedAt = dates[0], dates[1]
+	case t.Completed && len(dates) == 1:
+		t.CompletedAt = dates[0]
+	case len(dates) == 1:
+		t.CreatedAt = dates[0]
+	}
+
+	t.Description = rest
+	return t
+}
+
+// String returns the task as a line of a todo.txt file.
+func (t TodoTxtTask) String() string {
+	var b strings.Builder
+	if t.Completed {
+		b.WriteString("x ")
+		if !t.CompletedAt.IsZero() {
+			b.WriteString(t.CompletedAt.Format(TodoTxtDate) + " ")
+		}
+	} else if t.Priority != "" {
+		b.WriteString("(" + t.Priority + ") ")
+	}
+
+	// the creation date can only be written for completed tasks if there is a completion date
+	if !t.CreatedAt.IsZero() && (!t.Completed || !t.CompletedAt.IsZero()) {
+		b.WriteString(t.CreatedAt.Format(TodoTxtDate) + " ")
+	}
+	b.WriteString(t.Description)
+	return b.String()
+}
+
+// Projects returns the +projects of the task in the order they appear.
+func (t TodoTxtTask) Projects() []string {
+	var projects []string
+	for _, word := range strings.Fields(t.Description) {
+		if len(word) > 1 && word[0] == '+' {
+			projects = append(projects, word[1:])
+		}
+	}
+	return projects
+}
+
+// List returns the name of the list the task belongs to.
+func (t TodoTxtTask) List() string {
+	if projects := t.Projects(); len(projects) > 0 {
+		return projects[0]
+	}
+	return TodoTxtDefaultList
+}
+
+// Item returns the task as an Item. The priority of an incomplete task is kept as a prefix of the name.
+func (t TodoTxtTask) Item() *todo.Item {
+	name := t.Description
+	if t.Priority != "" {
+		name = "(" + t.Priority + ") " + name
+	}
+	return &todo.Item{Name: name, Completed: t.Completed, CreatedAt: t.CreatedAt, UpdatedAt: t.CompletedAt}
+}
+
+// NewTodoTxtTask returns the task for an Item in the named list. The +project of the list is appended to the
+// description unless the name already contains it.
+func NewTodoTxtTask(list string, item *todo.Item) TodoTxtTask {
+	t := TodoTxtTask{Completed: item.Completed, CreatedAt: dateOf(item.CreatedAt), Description: singleLine(item.Name)}
+	if item.Completed {
+		t.CompletedAt = dateOf(item.UpdatedAt)
+	} else if m := todoTxtPriority.FindStringSubmatch(t.Description); m != nil {
+		t.Priority = m[1]
+		t.Description = t.Description[len(m[0]):]
+	}
+
+	if project := TodoTxtProject(list); project != "" {
+		found := false
+		for _, p := range t.Projects() {
+			found = found || p == project
+		}
+		if !found {
+			t.Description += " +" + project
+		}
+	}
+	return t
+}
+
+// TodoTxtProject returns the +project name of a list, which is empty for the TodoTxtDefaultList. Whitespace is
+// not allowed in project names and is replaced by underscores.
+func TodoTxtProject(list string) string {
+	if list == TodoTxtDefaultList {
+		return ""
+	}
+	return strings.Join(strings.Fields(list), "_")
+}
+
+func dateOf(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func encodeTodoTxt(w io.Writer, next ListReader) error {
+	bw := bufio.NewWriter(w)
+	next = flushed(next, bw.Flush)
+	err := eachList(next, func(list *todo.List) error {
+		for _, item := range list.Items {
+			bw.WriteString(NewTodoTxtTask(list.Name, item).String() + "\n")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func decodeTodoTxt(r io.Reader) ([]*todo.List, error) {
+	var lists []*todo.List
+	byName := make(map[string]*todo.List)
+	var errs rowErrors
+
+	scanner := bufio.NewScanner(r)
+	for row := 1; scanner.Scan(); row++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		task := ParseTodoTxtTask(line)
+		if task.Description == "" {
+			errs.add(row, "task description required")
+			continue
+		}
+
+		name := task.List()
+		list, ok := byName[name]
+		if !ok {
+			list = &todo.List{Name: name, Items: make([]*todo.Item, 0)}
+			byName[name] = list
+			lists = append(lists, list)
+		}
+		list.Items = append(list.Items, task.Item())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid todo.txt: %v", err)
+	} else if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
@@ -0,0 +1,136 @@
+package format
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Trello reads the JSON export of a Trello board, it can only be imported. The board is a list and every card is an
+// item which is completed when its due date is marked complete. The check items of a card's checklists follow the
+// card as items of their own named "<card>: <check item>". Archived lists and cards are skipped.
+var Trello = register(&Format{
+	Name:   "trello",
+	Decode: decodeTrello,
+})
+
+type trelloBoard struct {
+	Name  string `json:"name"`
+	Lists []struct {
+		ID     string  `json:"id"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string  `json:"id"`
+		IDList      string  `json:"idList"`
+		Name        string  `json:"name"`
+		Closed      bool    `json:"closed"`
+		DueComplete bool    `json:"dueComplete"`
+		Pos         float64 `json:"pos"`
+		// DateLastActivity is the time the card was last modified.
+		DateLastActivity time.Time `json:"dateLastActivity"`
+	} `json:"cards"`
+	Checklists []struct {
+		IDCard     string  `json:"idCard"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			ID    string  `json:"id"`
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+}
+
+func decodeTrello(r io.Reader) ([]*todo.List, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, todo.Err(todo.EINVALID, "invalid json: %v", err)
+	}
+
+	list := &todo.List{Name: strings.TrimSpace(singleLine(board.Name)), Items: make([]*todo.Item, 0)}
+	if list.Name == "" {
+		return nil, todo.Err(todo.EINVALID, "board name required")
+	}
+
+	listPos := make(map[string]float64)
+	for _, l := range board.Lists {
+		if !l.Closed {
+			listPos[l.ID] = l.Pos
+		}
+	}
+
+	// cards are in the order of the board, column by column
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool {
+		if a, b := listPos[cards[i].IDList], listPos[cards[j].IDList]; a != b {
+			return a < b
+		}
+		return cards[i].Pos < cards[j].Pos
+	})
+
+	checklists := board.Checklists
+	sort.SliceStable(checklists, func(i, j int) bool { return checklists[i].Pos < checklists[j].Pos })
+
+	var errs rowErrors
+	for i, card := range cards {
+		if _, ok := listPos[card.IDList]; !ok || card.Closed {
+			continue
+		}
+
+		name := strings.TrimSpace(singleLine(card.Name))
+		if name == "" {
+			errs.add(i+1, "card name required")
+			continue
+		}
+		list.Items = append(list.Items, &todo.Item{
+			Name:      name,
+			Completed: card.DueComplete,
+			CreatedAt: trelloCreatedAt(card.ID),
+			UpdatedAt: card.DateLastActivity.UTC(),
+		})
+
+		for _, checklist := range checklists {
+			if checklist.IDCard != card.ID {
+				continue
+			}
+
+			checkItems := checklist.CheckItems
+			sort.SliceStable(checkItems, func(i, j int) bool { return checkItems[i].Pos < checkItems[j].Pos })
+			for _, checkItem := range checkItems {
+				checkName := strings.TrimSpace(singleLine(checkItem.Name))
+				if checkName == "" {
+					continue
+				}
+				list.Items = append(list.Items, &todo.Item{
+					Name:      name + ": " + checkName,
+					Completed: checkItem.State == "complete",
+					CreatedAt: trelloCreatedAt(checkItem.ID),
+				})
+			}
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return []*todo.List{list}, nil
+}
+
+// trelloCreatedAt returns the creation time of a Trello object which is the timestamp at the start of its ID.
+func trelloCreatedAt(id string) time.Time {
+	if len(id) < 8 {
+		return time.Time{}
+	}
+	seconds, err := strconv.ParseInt(id[:8], 16, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
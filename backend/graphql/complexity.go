@@ -0,0 +1,143 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// listFields are the fields which return lists, the complexity of their selections is multiplied by their size.
+var listFields = map[string]bool{
+	"lists":       true,
+	"items":       true,
+	"importLists": true,
+}
+
+// analysis is the result of analyze.
+type analysis struct {
+	mutation bool
+	// depth is the maximum number of nested fields.
+	depth int
+	// complexity is the maximum number of fields resolved, every field counts for one and the selections of list
+	// fields count for their limit argument up to maxSize, which is what the resolvers return at most. The selections
+	// of importLists count for the number of imported lists.
+	complexity int
+}
+
+// analyze computes the depth and complexity of the operation of a query. Introspection fields are not counted.
+func analyze(query, operationName string, variables map[string]interface{}, maxSize int) (*analysis, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return nil, err
+	}
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return &analysis{}, nil
+	}
+
+	w := &walker{doc: doc, op: op, variables: variables, maxSize: maxSize, fragments: make(map[string]bool)}
+	depth, complexity := w.walk(op.SelectionSet)
+	return &analysis{mutation: op.Operation == ast.Mutation, depth: depth, complexity: complexity}, nil
+}
+
+type walker struct {
+	doc       *ast.QueryDocument
+	op        *ast.OperationDefinition
+	variables map[string]interface{}
+	maxSize   int
+	// fragments are the fragments being walked, to stop on fragments spreading themselves.
+	fragments map[string]bool
+}
+
+// walk returns the depth and complexity of a selection set.
+func (w *walker) walk(set ast.SelectionSet) (depth, complexity int) {
+	for _, sel := range set {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name, "__") {
+				continue
+			}
+			d, c = w.walk(sel.SelectionSet)
+			if listFields[sel.Name] {
+				c *= w.size(sel)
+			}
+			d, c = d+1, c+1
+		case *ast.InlineFragment:
+			d, c = w.walk(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			def := w.doc.Fragments.ForName(sel.Name)
+			if def == nil || w.fragments[sel.Name] {
+				continue
+			}
+			w.fragments[sel.Name] = true
+			d, c = w.walk(def.SelectionSet)
+			delete(w.fragments, sel.Name)
+		}
+		if d > depth {
+			depth = d
+		}
+		complexity += c
+	}
+	return depth, complexity
+}
+
+// size returns the maximum number of values of a list field, which is its limit argument up to maxSize.
+func (w *walker) size(field *ast.Field) int {
+	if field.Name == "importLists" {
+		return w.length(w.arg(field, "lists"))
+	}
+	if n := w.int(w.arg(field, "limit")); n > 0 && n < w.maxSize {
+		return n
+	}
+	return w.maxSize
+}
+
+// arg returns the value of the argument of a field, a variable is replaced by its default value if it is not set.
+// The value of a variable which is set is nil, it is read from variables.
+func (w *walker) arg(field *ast.Field, name string) (*ast.Value, interface{}) {
+	arg := field.Arguments.ForName(name)
+	if arg == nil || arg.Value == nil {
+		return nil, nil
+	} else if arg.Value.Kind != ast.Variable {
+		return arg.Value, nil
+	}
+
+	if v, ok := w.variables[arg.Value.Raw]; ok {
+		return nil, v
+	} else if def := w.op.VariableDefinitions.ForName(arg.Value.Raw); def != nil && def.DefaultValue != nil {
+		return def.DefaultValue, nil
+	}
+	return nil, nil
+}
+
+// int returns the integer of an argument, or 0 if it is not an integer.
+func (w *walker) int(value *ast.Value, variable interface{}) int {
+	if value != nil && value.Kind == ast.IntValue {
+		n, _ := strconv.Atoi(value.Raw)
+		return n
+	}
+	// numbers of JSON variables are decoded as float64
+	if v, ok := variable.(float64); ok {
+		return int(v)
+	}
+	return 0
+}
+
+// length returns the number of values of a list argument, a single value is coerced to a list of one.
+func (w *walker) length(value *ast.Value, variable interface{}) int {
+	if value != nil {
+		if value.Kind == ast.ListValue {
+			return len(value.Children)
+		}
+		return 1
+	}
+	if v, ok := variable.([]interface{}); ok {
+		return len(v)
+	} else if variable != nil {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,253 @@
+// Package graphql serves a GraphQL API of the users, lists and items.
+//
+// Queries are limited in depth and complexity before they are executed, the items of lists are loaded with a single
+// query per request and clients may send the hash of a query instead of the query itself with automatic persisted
+// queries.
+package graphql
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const (
+	// DefaultMaxDepth is the default maximum depth of the selections of a query.
+	DefaultMaxDepth = 10
+	// DefaultMaxComplexity is the default maximum complexity of a query, see analyze.
+	DefaultMaxComplexity = 50000
+	// DefaultMaxListSize is the default maximum number of lists or items returned by a list field.
+	DefaultMaxListSize = 100
+	// maxPersistedQueries is the number of persisted queries kept, the oldest one is dropped first.
+	maxPersistedQueries = 1000
+	// maxBodySize is the maximum size of a request body.
+	maxBodySize = 1 << 20
+)
+
+// Handler serves GraphQL requests of the authenticated user of the request context.
+type Handler struct {
+	ItemListService todo.ItemListService
+	Logger          todo.Logger
+
+	MaxDepth      int
+	MaxComplexity int
+	// MaxListSize is the maximum number of lists or items returned by a list field, also if its limit is larger or
+	// it has none. It bounds the complexity of queries.
+	MaxListSize int
+
+	schema  *graphql.Schema
+	queries *persistedQueries
+}
+
+func NewHandler(items todo.ItemListService, logger todo.Logger) *Handler {
+	h := &Handler{
+		ItemListService: items,
+		Logger:          logger,
+		MaxDepth:        DefaultMaxDepth,
+		MaxComplexity:   DefaultMaxComplexity,
+		MaxListSize:     DefaultMaxListSize,
+		queries:         newPersistedQueries(maxPersistedQueries),
+	}
+	h.schema = graphql.MustParseSchema(schema, &resolver{h})
+	return h
+}
+
+// request is the body of a GraphQL request, or the URL query parameters of a GET request.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    struct {
+		PersistedQuery *struct {
+			Version    int    `json:"version"`
+			SHA256Hash string `json:"sha256Hash"`
+		} `json:"persistedQuery"`
+	} `json:"extensions"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				h.errorResponse(w, http.StatusBadRequest, todo.Err(todo.EINVALID, "invalid variables"))
+				return
+			}
+		}
+		if v := q.Get("extensions"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Extensions); err != nil {
+				h.errorResponse(w, http.StatusBadRequest, todo.Err(todo.EINVALID, "invalid extensions"))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
+			h.errorResponse(w, http.StatusBadRequest, todo.Err(todo.EINVALID, "invalid body"))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		h.errorResponse(w, http.StatusMethodNotAllowed, todo.Err(todo.EINVALID, "method not allowed"))
+		return
+	}
+
+	if err := h.resolveQuery(&req); err != nil {
+		h.response(w, http.StatusOK, &graphql.Response{Errors: []*gqlerrors.QueryError{err}})
+		return
+	} else if req.Query == "" {
+		h.errorResponse(w, http.StatusBadRequest, todo.Err(todo.EINVALID, "query required"))
+		return
+	}
+
+	// queries which cannot be analyzed cannot be limited, they are rejected rather than executed
+	a, err := analyze(req.Query, req.OperationName, req.Variables, h.listSize(nil))
+	if err != nil {
+		h.errorResponse(w, http.StatusBadRequest, todo.Err(todo.EINVALID, "invalid query: %v", err))
+		return
+	} else if a.mutation && r.Method == http.MethodGet {
+		w.Header().Set("Allow", "POST")
+		h.errorResponse(w, http.StatusMethodNotAllowed, todo.Err(todo.EINVALID, "mutations require POST"))
+		return
+	} else if h.MaxDepth > 0 && a.depth > h.MaxDepth {
+		h.errorResponse(w, http.StatusOK, todo.Err(todo.EINVALID, "query depth %d exceeds the maximum of %d", a.depth, h.MaxDepth))
+		return
+	} else if h.MaxComplexity > 0 && a.complexity > h.MaxComplexity {
+		h.errorResponse(w, http.StatusOK, todo.Err(todo.EINVALID, "query complexity %d exceeds the maximum of %d", a.complexity, h.MaxComplexity))
+		return
+	}
+
+	ctx := newContextWithLoader(r.Context(), newItemLoader())
+	h.response(w, http.StatusOK, h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// listSize returns the number of values a list field with the limit argument returns at most, which is MaxListSize
+// if limit is nil, not positive or larger.
+func (h *Handler) listSize(limit *int32) int {
+	max := h.MaxListSize
+	if max <= 0 {
+		max = DefaultMaxListSize
+	}
+	if limit != nil && *limit > 0 && int(*limit) < max {
+		return int(*limit)
+	}
+	return max
+}
+
+// resolveQuery implements automatic persisted queries: a request with the hash of a query but without the query is
+// resolved from the persisted queries, a request with both persists the query.
+func (h *Handler) resolveQuery(req *request) *gqlerrors.QueryError {
+	pq := req.Extensions.PersistedQuery
+	if pq == nil {
+		return nil
+	} else if pq.Version != 1 {
+		return queryError("PersistedQueryNotSupported", "PERSISTED_QUERY_NOT_SUPPORTED")
+	}
+
+	if req.Query == "" {
+		if req.Query = h.queries.get(pq.SHA256Hash); req.Query == "" {
+			return queryError("PersistedQueryNotFound", "PERSISTED_QUERY_NOT_FOUND")
+		}
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(req.Query))
+	if hex.EncodeToString(sum[:]) != pq.SHA256Hash {
+		return queryError("provided sha does not match query", todo.EINVALID)
+	}
+	h.queries.put(pq.SHA256Hash, req.Query)
+	return nil
+}
+
+func queryError(msg, code string) *gqlerrors.QueryError {
+	return &gqlerrors.QueryError{Message: msg, Extensions: map[string]interface{}{"code": code}}
+}
+
+func (h *Handler) errorResponse(w http.ResponseWriter, code int, err error) {
+	h.response(w, code, &graphql.Response{Errors: []*gqlerrors.QueryError{queryError(todo.ErrMessage(err), todo.ErrCode(err))}})
+}
+
+func (h *Handler) response(w http.ResponseWriter, code int, resp *graphql.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		h.Logger.E(err)
+	}
+}
+
+// Error is returned by resolvers, its code is returned in the extensions of the error.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// error converts an error returned by a service. Internal errors are logged and their details are not returned.
+func (h *Handler) error(err error) error {
+	var e *todo.Error
+	if !errors.As(err, &e) || e.Code == todo.EINTERNAL {
+		if !errors.Is(err, context.Canceled) {
+			h.Logger.E(err)
+		}
+		return &Error{Code: todo.EINTERNAL, Message: todo.Internal.Message}
+	} else if e.Code == todo.EUNAUTHORIZED {
+		return &Error{Code: e.Code, Message: todo.Unauthorized.Message}
+	}
+	return &Error{Code: e.Code, Message: e.Message}
+}
+
+// persistedQueries stores up to max queries by their SHA-256 hash.
+type persistedQueries struct {
+	mu      sync.Mutex
+	max     int
+	queries map[string]string
+	// hashes is the insertion order of the queries.
+	hashes []string
+}
+
+func newPersistedQueries(max int) *persistedQueries {
+	return &persistedQueries{max: max, queries: make(map[string]string)}
+}
+
+func (p *persistedQueries) get(hash string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.queries[hash]
+}
+
+func (p *persistedQueries) put(hash, query string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.queries[hash]; ok {
+		return
+	}
+	if len(p.hashes) >= p.max {
+		delete(p.queries, p.hashes[0])
+		p.hashes = p.hashes[1:]
+	}
+	p.queries[hash] = query
+	p.hashes = append(p.hashes, hash)
+}
@@ -0,0 +1,285 @@
+package graphql_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/graphql"
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// countingItemListService counts the calls to FindItems.
+type countingItemListService struct {
+	todo.ItemListService
+	findItems int
+}
+
+func (s *countingItemListService) FindItems(ctx context.Context, f todo.ItemFilter) ([]*todo.Item, error) {
+	s.findItems++
+	return s.ItemListService.FindItems(ctx, f)
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string `json:"message"`
+		Extensions struct {
+			Code string `json:"code"`
+		} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func (r *response) code() string {
+	if len(r.Errors) == 0 {
+		return ""
+	}
+	return r.Errors[0].Extensions.Code
+}
+
+type testHandler struct {
+	*graphql.Handler
+	Lists *countingItemListService
+	user  *todo.User
+}
+
+func newTestHandler(t *testing.T) *testHandler {
+	t.Helper()
+	logger := todo.NewLogger()
+	logger.SetOutput(ioutil.Discard)
+
+	lists := &countingItemListService{ItemListService: inmem.NewItemListService()}
+	return &testHandler{
+		Handler: graphql.NewHandler(lists, logger),
+		Lists:   lists,
+		user:    &todo.User{ID: 1, Name: "george"},
+	}
+}
+
+func (h *testHandler) ctx() context.Context {
+	return todo.NewContextWithUser(context.Background(), h.user)
+}
+
+// do executes the request as the user of the handler.
+func (h *testHandler) do(t *testing.T, r *http.Request) (int, *response) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r.WithContext(todo.NewContextWithUser(r.Context(), h.user)))
+
+	var resp response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, &resp
+}
+
+func (h *testHandler) post(t *testing.T, body interface{}) *response {
+	t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, resp := h.do(t, httptest.NewRequest(http.MethodPost, "/api/graphql", bytes.NewReader(b)))
+	if code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, code)
+	}
+	return resp
+}
+
+func (h *testHandler) query(t *testing.T, query string, variables map[string]interface{}) *response {
+	t.Helper()
+	return h.post(t, map[string]interface{}{"query": query, "variables": variables})
+}
+
+func TestHandler_Mutations(t *testing.T) {
+	h := newTestHandler(t)
+
+	resp := h.query(t, `mutation { createList(name: "groceries") { id name items { id } } }`, nil)
+	if len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	var list struct {
+		ID    int
+		Name  string
+		Items []struct{ ID int }
+	}
+	if err := json.Unmarshal(resp.Data["createList"], &list); err != nil {
+		t.Fatal(err)
+	} else if list.ID == 0 || list.Name != "groceries" || list.Items == nil {
+		t.Fatalf("unexpected list %+v", list)
+	}
+
+	resp = h.query(t, `mutation($listId: Int!) { createItem(listId: $listId, name: "milk") { id listId } }`, map[string]interface{}{"listId": list.ID})
+	if len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	resp = h.query(t, `query($id: Int!) { list(id: $id) { items { name completed } } }`, map[string]interface{}{"id": list.ID})
+	if got, want := string(resp.Data["list"]), `{"items":[{"name":"milk","completed":false}]}`; got != want {
+		t.Fatalf("want %s got %s", want, got)
+	}
+
+	resp = h.query(t, `mutation { createList(name: "") { id } }`, nil)
+	if resp.code() != todo.EINVALID {
+		t.Fatalf("want %s got %+v", todo.EINVALID, resp.Errors)
+	}
+	resp = h.query(t, `mutation { deleteList(id: 999) }`, nil)
+	if resp.code() != todo.ENOTFOUND {
+		t.Fatalf("want %s got %+v", todo.ENOTFOUND, resp.Errors)
+	}
+
+	// mutations cannot be sent with GET
+	q := url.Values{"query": {`mutation { createList(name: "other") { id } }`}}
+	if code, _ := h.do(t, httptest.NewRequest(http.MethodGet, "/api/graphql?"+q.Encode(), nil)); code != http.StatusMethodNotAllowed {
+		t.Fatalf("want %d got %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+func TestHandler_BatchesItems(t *testing.T) {
+	h := newTestHandler(t)
+	for _, name := range []string{"groceries", "chores", "errands"} {
+		list := &todo.List{Name: name}
+		if err := h.Lists.CreateList(h.ctx(), list); err != nil {
+			t.Fatal(err)
+		}
+		if err := h.Lists.CreateItem(h.ctx(), &todo.Item{ListID: list.ID, Name: name + " item"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp := h.query(t, `{ me { name lists { name items { name } } } }`, nil)
+	if len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	} else if h.Lists.findItems != 1 {
+		t.Fatalf("want 1 FindItems call got %d", h.Lists.findItems)
+	}
+
+	var me struct {
+		Lists []struct {
+			Name  string
+			Items []struct{ Name string }
+		}
+	}
+	if err := json.Unmarshal(resp.Data["me"], &me); err != nil {
+		t.Fatal(err)
+	} else if len(me.Lists) != 3 {
+		t.Fatalf("want 3 lists got %+v", me.Lists)
+	}
+	for _, list := range me.Lists {
+		if len(list.Items) != 1 || list.Items[0].Name != list.Name+" item" {
+			t.Fatalf("unexpected items of %q: %+v", list.Name, list.Items)
+		}
+	}
+}
+
+func TestHandler_Limits(t *testing.T) {
+	h := newTestHandler(t)
+	h.MaxDepth = 3
+	h.MaxComplexity = 50
+
+	if resp := h.query(t, `{ me { lists(limit: 2) { items { name } } } }`, nil); resp.code() != todo.EINVALID || !strings.Contains(resp.Errors[0].Message, "depth") {
+		t.Fatalf("want depth error got %+v", resp.Errors)
+	}
+	// fragments count as if their fields were inlined
+	if resp := h.query(t, `{ ...Lists } fragment Lists on Query { lists { items { id name } } }`, nil); resp.code() != todo.EINVALID || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Fatalf("want complexity error got %+v", resp.Errors)
+	}
+	// the limit argument of a list lowers its complexity, also the default value of a variable
+	if resp := h.query(t, `query($limit: Int) { lists(limit: $limit) { items(limit: 5) { id name } } }`, map[string]interface{}{"limit": 2}); len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	if resp := h.query(t, `query($limit: Int = 2) { lists(limit: $limit) { items(limit: 5) { id name } } }`, nil); len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+	// lists without a limit count for the maximum list size, imports for their number of lists
+	if resp := h.query(t, `{ lists(limit: 2) { items { id name } } }`, nil); resp.code() != todo.EINVALID || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Fatalf("want complexity error got %+v", resp.Errors)
+	}
+	lists := make([]interface{}, 50)
+	for i := range lists {
+		lists[i] = map[string]interface{}{"name": "list"}
+	}
+	if resp := h.query(t, `mutation($lists: [ListInput!]!) { importLists(lists: $lists) { id } }`, map[string]interface{}{"lists": lists}); resp.code() != todo.EINVALID || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Fatalf("want complexity error got %+v", resp.Errors)
+	}
+	// introspection is not limited
+	if resp := h.query(t, `{ __schema { types { name fields { name type { name ofType { name } } } } } }`, nil); len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+}
+
+func TestHandler_MaxListSize(t *testing.T) {
+	h := newTestHandler(t)
+	h.MaxListSize = 2
+
+	list := &todo.List{Name: "groceries"}
+	if err := h.Lists.CreateList(h.ctx(), list); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := h.Lists.CreateList(h.ctx(), &todo.List{Name: "other"}); err != nil {
+			t.Fatal(err)
+		} else if err := h.Lists.CreateItem(h.ctx(), &todo.Item{ListID: list.ID, Name: "milk"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the resolvers return no more values than the complexity of the query counted
+	for _, query := range []string{`{ lists { id } }`, `{ lists(limit: 10) { id } }`} {
+		resp := h.query(t, query, nil)
+		var lists []struct{ ID int }
+		if err := json.Unmarshal(resp.Data["lists"], &lists); err != nil {
+			t.Fatal(err)
+		} else if len(lists) != 2 {
+			t.Fatalf("%s: want 2 lists got %d", query, len(lists))
+		}
+	}
+	resp := h.query(t, `query($id: Int!) { list(id: $id) { items { id } } }`, map[string]interface{}{"id": list.ID})
+	var got struct{ Items []struct{ ID int } }
+	if err := json.Unmarshal(resp.Data["list"], &got); err != nil {
+		t.Fatal(err)
+	} else if len(got.Items) != 2 {
+		t.Fatalf("want 2 items got %d", len(got.Items))
+	}
+
+	// queries which cannot be analyzed are rejected
+	b, _ := json.Marshal(map[string]interface{}{"query": `{ lists { id `})
+	if code, resp := h.do(t, httptest.NewRequest(http.MethodPost, "/api/graphql", bytes.NewReader(b))); code != http.StatusBadRequest || resp.code() != todo.EINVALID {
+		t.Fatalf("want %d got %d %+v", http.StatusBadRequest, code, resp.Errors)
+	}
+}
+
+func TestHandler_PersistedQueries(t *testing.T) {
+	h := newTestHandler(t)
+	query := `{ me { name } }`
+	sum := sha256.Sum256([]byte(query))
+	extensions := map[string]interface{}{"persistedQuery": map[string]interface{}{"version": 1, "sha256Hash": hex.EncodeToString(sum[:])}}
+
+	if resp := h.post(t, map[string]interface{}{"extensions": extensions}); resp.code() != "PERSISTED_QUERY_NOT_FOUND" {
+		t.Fatalf("want PERSISTED_QUERY_NOT_FOUND got %+v", resp.Errors)
+	}
+	if resp := h.post(t, map[string]interface{}{"query": `{ me { id } }`, "extensions": extensions}); resp.code() != todo.EINVALID {
+		t.Fatalf("want %s got %+v", todo.EINVALID, resp.Errors)
+	}
+	if resp := h.post(t, map[string]interface{}{"query": query, "extensions": extensions}); len(resp.Errors) != 0 {
+		t.Fatal(resp.Errors)
+	}
+
+	// the query is now known by its hash, also with GET
+	b, _ := json.Marshal(extensions)
+	q := url.Values{"extensions": {string(b)}}
+	code, resp := h.do(t, httptest.NewRequest(http.MethodGet, "/api/graphql?"+q.Encode(), nil))
+	if code != http.StatusOK || len(resp.Errors) != 0 {
+		t.Fatalf("unexpected response %d %+v", code, resp.Errors)
+	} else if got, want := string(resp.Data["me"]), `{"name":"george"}`; got != want {
+		t.Fatalf("want %s got %s", want, got)
+	}
+}
@@ -0,0 +1,312 @@
+package graphql
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// resolver resolves the Query and Mutation types.
+type resolver struct {
+	*Handler
+}
+
+type listsArgs struct {
+	Completed *bool
+	Offset    *int32
+	Limit     *int32
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return &userResolver{resolver: r, user: user}, nil
+}
+
+func (r *resolver) Lists(ctx context.Context, args listsArgs) ([]*listResolver, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return r.findLists(ctx, user, args)
+}
+
+// findLists finds the lists without their items, which are loaded together once the first list's items are
+// resolved.
+func (r *resolver) findLists(ctx context.Context, user *todo.User, args listsArgs) ([]*listResolver, error) {
+	f := todo.ListFilter{UserID: &user.ID, Completed: args.Completed, ExcludeItems: true}
+	if args.Offset != nil {
+		f.Offset = int(*args.Offset)
+	}
+	// the limit is bounded as the complexity of the query assumed
+	f.Limit = r.listSize(args.Limit)
+
+	lists, err := r.ItemListService.FindLists(ctx, f)
+	if err != nil {
+		return nil, r.error(err)
+	}
+
+	loader := loaderFromContext(ctx)
+	resolvers := make([]*listResolver, len(lists))
+	for i, list := range lists {
+		loader.expect(list.ID)
+		resolvers[i] = &listResolver{resolver: r, list: list}
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) List(ctx context.Context, args struct{ ID int32 }) (*listResolver, error) {
+	list, err := r.ItemListService.FindListByID(ctx, int(args.ID))
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return &listResolver{resolver: r, list: list, loaded: true}, nil
+}
+
+func (r *resolver) Item(ctx context.Context, args struct{ ID int32 }) (*itemResolver, error) {
+	item, err := r.ItemListService.FindItemByID(ctx, int(args.ID))
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return &itemResolver{item: item}, nil
+}
+
+func (r *resolver) CreateList(ctx context.Context, args struct {
+	Name      string
+	Completed *bool
+}) (*listResolver, error) {
+	list := &todo.List{Name: args.Name, Completed: args.Completed != nil && *args.Completed}
+	if err := r.ItemListService.CreateList(ctx, list); err != nil {
+		return nil, r.error(err)
+	}
+	list.Items = make([]*todo.Item, 0)
+	return &listResolver{resolver: r, list: list, loaded: true}, nil
+}
+
+func (r *resolver) UpdateList(ctx context.Context, args struct {
+	ID        int32
+	Name      *string
+	Completed *bool
+}) (*listResolver, error) {
+	list, err := r.ItemListService.UpdateList(ctx, int(args.ID), todo.ListUpdate{Name: args.Name, Completed: args.Completed})
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return &listResolver{resolver: r, list: list}, nil
+}
+
+func (r *resolver) DeleteList(ctx context.Context, args struct{ ID int32 }) (int32, error) {
+	if err := r.ItemListService.DeleteList(ctx, int(args.ID)); err != nil {
+		return 0, r.error(err)
+	}
+	return args.ID, nil
+}
+
+func (r *resolver) CreateItem(ctx context.Context, args struct {
+	ListID    int32
+	Name      string
+	Completed *bool
+}) (*itemResolver, error) {
+	item := &todo.Item{ListID: int(args.ListID), Name: args.Name, Completed: args.Completed != nil && *args.Completed}
+	if err := r.ItemListService.CreateItem(ctx, item); err != nil {
+		return nil, r.error(err)
+	}
+	return &itemResolver{item: item}, nil
+}
+
+func (r *resolver) UpdateItem(ctx context.Context, args struct {
+	ID        int32
+	Name      *string
+	Completed *bool
+}) (*itemResolver, error) {
+	item, err := r.ItemListService.UpdateItem(ctx, int(args.ID), todo.ItemUpdate{Name: args.Name, Completed: args.Completed})
+	if err != nil {
+		return nil, r.error(err)
+	}
+	return &itemResolver{item: item}, nil
+}
+
+func (r *resolver) DeleteItem(ctx context.Context, args struct{ ID int32 }) (int32, error) {
+	if err := r.ItemListService.DeleteItem(ctx, int(args.ID)); err != nil {
+		return 0, r.error(err)
+	}
+	return args.ID, nil
+}
+
+type listInput struct {
+	Name      string
+	Completed *bool
+	Items     *[]*itemInput
+}
+
+type itemInput struct {
+	Name      string
+	Completed *bool
+}
+
+func (r *resolver) ImportLists(ctx context.Context, args struct{ Lists []*listInput }) ([]*listResolver, error) {
+	lists := make([]*todo.List, len(args.Lists))
+	for i, in := range args.Lists {
+		list := &todo.List{Name: in.Name, Completed: in.Completed != nil && *in.Completed, Items: make([]*todo.Item, 0)}
+		if in.Items != nil {
+			for _, item := range *in.Items {
+				list.Items = append(list.Items, &todo.Item{Name: item.Name, Completed: item.Completed != nil && *item.Completed})
+			}
+		}
+		lists[i] = list
+	}
+
+	if err := r.ItemListService.ImportLists(ctx, lists); err != nil {
+		return nil, r.error(err)
+	}
+
+	resolvers := make([]*listResolver, len(lists))
+	for i, list := range lists {
+		resolvers[i] = &listResolver{resolver: r, list: list, loaded: true}
+	}
+	return resolvers, nil
+}
+
+type userResolver struct {
+	*resolver
+	user *todo.User
+}
+
+func (r *userResolver) ID() int32      { return int32(r.user.ID) }
+func (r *userResolver) Name() string   { return r.user.Name }
+func (r *userResolver) Email() *string { return r.user.Email }
+func (r *userResolver) DeleteAt() *graphql.Time {
+	if r.user.DeleteAt == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.user.DeleteAt}
+}
+func (r *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.user.CreatedAt} }
+func (r *userResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.user.UpdatedAt} }
+
+func (r *userResolver) Lists(ctx context.Context, args listsArgs) ([]*listResolver, error) {
+	return r.findLists(ctx, r.user, args)
+}
+
+type listResolver struct {
+	*resolver
+	list *todo.List
+	// loaded is set if the Items of the list are known, otherwise they are loaded when they are resolved.
+	loaded bool
+}
+
+func (r *listResolver) ID() int32               { return int32(r.list.ID) }
+func (r *listResolver) UserID() int32           { return int32(r.list.UserID) }
+func (r *listResolver) Name() string            { return r.list.Name }
+func (r *listResolver) Completed() bool         { return r.list.Completed }
+func (r *listResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.list.CreatedAt} }
+func (r *listResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.list.UpdatedAt} }
+
+func (r *listResolver) Items(ctx context.Context, args struct {
+	Completed *bool
+	Limit     *int32
+}) ([]*itemResolver, error) {
+	items := r.list.Items
+	if !r.loaded {
+		var err error
+		if items, err = loaderFromContext(ctx).load(ctx, r.ItemListService, r.list.ID); err != nil {
+			return nil, r.error(err)
+		}
+	}
+
+	limit := r.listSize(args.Limit)
+	resolvers := make([]*itemResolver, 0, len(items))
+	for _, item := range items {
+		if len(resolvers) == limit {
+			break
+		} else if args.Completed == nil || item.Completed == *args.Completed {
+			resolvers = append(resolvers, &itemResolver{item: item})
+		}
+	}
+	return resolvers, nil
+}
+
+type itemResolver struct {
+	item *todo.Item
+}
+
+func (r *itemResolver) ID() int32               { return int32(r.item.ID) }
+func (r *itemResolver) UserID() int32           { return int32(r.item.UserID) }
+func (r *itemResolver) ListID() int32           { return int32(r.item.ListID) }
+func (r *itemResolver) Name() string            { return r.item.Name }
+func (r *itemResolver) Completed() bool         { return r.item.Completed }
+func (r *itemResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.item.CreatedAt} }
+func (r *itemResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.item.UpdatedAt} }
+
+// itemLoader loads the items of all lists resolved by a request with a single query instead of one query per list.
+// Lists whose items will be resolved are registered with expect, the first load loads the items of all of them.
+type itemLoader struct {
+	mu       sync.Mutex
+	expected map[int]bool
+	items    map[int][]*todo.Item
+}
+
+func newItemLoader() *itemLoader {
+	return &itemLoader{expected: make(map[int]bool), items: make(map[int][]*todo.Item)}
+}
+
+func (l *itemLoader) expect(listID int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.items[listID]; !ok {
+		l.expected[listID] = true
+	}
+}
+
+// load returns the items of the list, loading the items of all expected lists if they are not loaded yet.
+func (l *itemLoader) load(ctx context.Context, svc todo.ItemListService, listID int) ([]*todo.Item, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if items, ok := l.items[listID]; ok {
+		return items, nil
+	}
+
+	l.expected[listID] = true
+	ids := make([]int, 0, len(l.expected))
+	for id := range l.expected {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	f := todo.ItemFilter{ListIDs: ids}
+	if user := todo.UserFromContext(ctx); user != nil {
+		f.UserID = &user.ID
+	}
+	items, err := svc.FindItems(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		l.items[id] = make([]*todo.Item, 0)
+		delete(l.expected, id)
+	}
+	for _, item := range items {
+		l.items[item.ListID] = append(l.items[item.ListID], item)
+	}
+	return l.items[listID], nil
+}
+
+type loaderKey struct{}
+
+func newContextWithLoader(ctx context.Context, l *itemLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, l)
+}
+
+// loaderFromContext returns the loader of the request. A new loader is returned if there is none so that resolvers
+// also work outside of a request.
+func loaderFromContext(ctx context.Context) *itemLoader {
+	if l, ok := ctx.Value(loaderKey{}).(*itemLoader); ok {
+		return l
+	}
+	return newItemLoader()
+}
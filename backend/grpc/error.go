@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code returns the gRPC status code for an error code.
+func Code(err error) codes.Code {
+	switch todo.ErrCode(err) {
+	case "":
+		return codes.OK
+	case todo.ECONFLICT:
+		return codes.AlreadyExists
+	case todo.EINVALID:
+		return codes.InvalidArgument
+	case todo.ENOTFOUND:
+		return codes.NotFound
+	case todo.EUNAUTHORIZED:
+		return codes.Unauthenticated
+	}
+	return codes.Internal
+}
+
+// ErrCodeFromCode returns the corresponding error code for a gRPC status code.
+func ErrCodeFromCode(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return ""
+	case codes.AlreadyExists, codes.Aborted, codes.FailedPrecondition:
+		return todo.ECONFLICT
+	case codes.InvalidArgument, codes.OutOfRange:
+		return todo.EINVALID
+	case codes.NotFound:
+		return todo.ENOTFOUND
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return todo.EUNAUTHORIZED
+	}
+	return todo.EINTERNAL
+}
+
+// Error converts the status error returned by a call into an Error, so that clients can use todo.ErrCode.
+func Error(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	return &todo.Error{Code: ErrCodeFromCode(st.Code()), Message: st.Message()}
+}
+
+// status converts an error into a status error. Internal errors are logged and their details are not returned.
+func (s *Server) status(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	code := Code(err)
+	msg := todo.ErrMessage(err)
+	if code == codes.Internal {
+		s.Logger.E(err)
+	} else if code == codes.Unauthenticated {
+		msg = todo.Unauthorized.Message
+	}
+	return status.Error(code, msg)
+}
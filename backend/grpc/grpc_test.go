@@ -0,0 +1,216 @@
+package grpc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	todogrpc "github.com/cmokbel1/todo-app/backend/grpc"
+	"github.com/cmokbel1/todo-app/backend/grpc/pb"
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testServer serves in-memory services over an in-memory connection.
+type testServer struct {
+	Users  *inmem.UserService
+	Events *inmem.EventService
+	Lists  pb.ItemListServiceClient
+	Admin  pb.UserServiceClient
+	conn   *grpc.ClientConn
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	logger := todo.NewLogger()
+	logger.SetOutput(ioutil.Discard)
+
+	ts := &testServer{Users: inmem.NewUserService(), Events: inmem.NewEventService()}
+	s := todogrpc.NewServer()
+	s.APIKey = "admin"
+	s.Logger = logger
+	s.UserService = ts.Users
+	s.ItemListService = inmem.NewItemListService()
+	s.EventService = ts.Events
+
+	ln := bufconn.Listen(1024 * 1024)
+	s.Open(ln)
+	t.Cleanup(func() { s.Close() })
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return ln.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ts.conn = conn
+	ts.Lists = pb.NewItemListServiceClient(conn)
+	ts.Admin = pb.NewUserServiceClient(conn)
+	return ts
+}
+
+// createUser creates a user and returns a context authenticated with their API key.
+func (ts *testServer) createUser(t *testing.T, name string) (*todo.User, context.Context) {
+	t.Helper()
+	user := &todo.User{Name: name, Password: "password"}
+	if err := ts.Users.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	return user, metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+user.APIKey)
+}
+
+func TestItemListService(t *testing.T) {
+	ts := newTestServer(t)
+	_, ctx := ts.createUser(t, "george")
+	_, other := ts.createUser(t, "martha")
+
+	list, err := ts.Lists.CreateList(ctx, &pb.CreateListRequest{Name: "groceries"})
+	if err != nil {
+		t.Fatal(err)
+	} else if list.Id == 0 || list.Name != "groceries" || list.CreatedAt.AsTime().IsZero() {
+		t.Fatalf("unexpected list %v", list)
+	}
+
+	item, err := ts.Lists.CreateItem(ctx, &pb.CreateItemRequest{ListId: list.Id, Name: "milk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	completed := true
+	if item, err = ts.Lists.UpdateItem(ctx, &pb.UpdateItemRequest{Id: item.Id, Completed: &completed}); err != nil {
+		t.Fatal(err)
+	} else if !item.Completed || item.Name != "milk" {
+		t.Fatalf("unexpected item %v", item)
+	}
+
+	if got, err := ts.Lists.GetList(ctx, &pb.GetListRequest{Id: list.Id}); err != nil {
+		t.Fatal(err)
+	} else if len(got.Items) != 1 || got.Items[0].Id != item.Id {
+		t.Fatalf("unexpected list %v", got)
+	}
+	if resp, err := ts.Lists.ListItems(ctx, &pb.ListItemsRequest{ListId: &list.Id, Completed: &completed}); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Items) != 1 {
+		t.Fatalf("want 1 item got %v", resp.Items)
+	}
+
+	// other users cannot see the list
+	if resp, err := ts.Lists.ListLists(other, &pb.ListListsRequest{}); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Lists) != 0 {
+		t.Fatalf("want no lists got %v", resp.Lists)
+	}
+	if _, err := ts.Lists.GetList(other, &pb.GetListRequest{Id: list.Id}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("want unauthenticated got %v", err)
+	}
+
+	if _, err := ts.Lists.DeleteItem(ctx, &pb.DeleteItemRequest{Id: item.Id}); err != nil {
+		t.Fatal(err)
+	} else if _, err := ts.Lists.GetItem(ctx, &pb.GetItemRequest{Id: item.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("want not found got %v", err)
+	} else if todo.ErrCode(todogrpc.Error(err)) != todo.ENOTFOUND {
+		t.Fatalf("want %s got %v", todo.ENOTFOUND, todogrpc.Error(err))
+	}
+	if _, err := ts.Lists.CreateList(ctx, &pb.CreateListRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("want invalid argument got %v", err)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	ts := newTestServer(t)
+	user, ctx := ts.createUser(t, "george")
+
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+	for _, ctx := range []context.Context{context.Background(), invalid} {
+		if _, err := ts.Lists.ListLists(ctx, &pb.ListListsRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("want unauthenticated got %v", err)
+		}
+	}
+
+	if me, err := ts.Admin.GetCurrentUser(ctx, &pb.GetCurrentUserRequest{}); err != nil {
+		t.Fatal(err)
+	} else if me.Id != int64(user.ID) || me.Name != "george" {
+		t.Fatalf("unexpected user %v", me)
+	}
+
+	// admin calls require the server API key, not the API key of a user
+	if _, err := ts.Admin.ListUsers(ctx, &pb.ListUsersRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("want unauthenticated got %v", err)
+	}
+	admin := metadata.AppendToOutgoingContext(context.Background(), "todo-api-key", "admin")
+	if _, err := ts.Admin.CreateUser(admin, &pb.CreateUserRequest{Name: "george", Password: "password"}); status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("want already exists got %v", err)
+	}
+	if resp, err := ts.Admin.ListUsers(admin, &pb.ListUsersRequest{}); err != nil {
+		t.Fatal(err)
+	} else if len(resp.Users) != 1 {
+		t.Fatalf("want 1 user got %v", resp.Users)
+	}
+
+	// the previous API key stops working once it is rotated
+	resp, err := ts.Admin.RotateAPIKey(ctx, &pb.RotateAPIKeyRequest{})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := ts.Admin.GetCurrentUser(ctx, &pb.GetCurrentUserRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("want unauthenticated got %v", err)
+	}
+	rotated := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+resp.ApiKey)
+	if _, err := ts.Admin.GetCurrentUser(rotated, &pb.GetCurrentUserRequest{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestItemListService_WatchList(t *testing.T) {
+	ts := newTestServer(t)
+	user, ctx := ts.createUser(t, "george")
+
+	list, err := ts.Lists.CreateList(ctx, &pb.CreateListRequest{Name: "groceries"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	stream, err := ts.Lists.WatchList(ctx, &pb.WatchListRequest{Id: list.Id})
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := stream.Header(); err != nil {
+		t.Fatal(err)
+	}
+
+	// events of other lists are not sent
+	ts.Events.PublishEvent(user.ID, todo.Event{Type: todo.EventItemCreated, Item: &todo.Item{ID: 1, ListID: int(list.Id) + 1, Name: "other"}})
+	ts.Events.PublishEvent(user.ID, todo.Event{Type: todo.EventItemCreated, Item: &todo.Item{ID: 2, ListID: int(list.Id), Name: "milk"}})
+	ts.Events.PublishEvent(user.ID, todo.Event{Type: todo.EventListDeleted, List: &todo.List{ID: int(list.Id)}})
+
+	if event, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	} else if event.Type != todo.EventItemCreated || event.Item.GetName() != "milk" {
+		t.Fatalf("unexpected event %v", event)
+	}
+	if event, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	} else if event.Type != todo.EventListDeleted || event.List.GetId() != list.Id {
+		t.Fatalf("unexpected event %v", event)
+	}
+	// the stream ends once the list is deleted
+	if _, err := stream.Recv(); err == nil {
+		t.Fatal("want end of stream")
+	}
+
+	if stream, err := ts.Lists.WatchList(ctx, &pb.WatchListRequest{Id: 999}); err != nil {
+		t.Fatal(err)
+	} else if _, err := stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Fatalf("want not found got %v", err)
+	}
+}
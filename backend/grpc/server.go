@@ -0,0 +1,193 @@
+// Package grpc serves the ItemListService and UserService as the protobuf services defined in pb/todo.proto for
+// internal services which talk gRPC. Calls are authenticated with the API key of a user as a bearer token in the
+// authorization metadata, the admin calls with the server API key in the todo-api-key metadata.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative pb/todo.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/grpc/pb"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// adminMethods are the calls which require the server API key instead of the API key of a user.
+var adminMethods = map[string]bool{
+	pb.UserService_CreateUser_FullMethodName: true,
+	pb.UserService_ListUsers_FullMethodName:  true,
+	pb.UserService_DeleteUser_FullMethodName: true,
+}
+
+type Server struct {
+	ln     net.Listener
+	server *grpc.Server
+	errc   chan error
+
+	// ctx is canceled on close to end WatchList streams.
+	ctx    context.Context
+	cancel func()
+
+	// config values
+	Addr string
+	// APIKey is the server's API key which authorizes the admin calls.
+	APIKey string
+
+	Logger          todo.Logger
+	ItemListService todo.ItemListService
+	UserService     todo.UserService
+	EventService    todo.EventService
+}
+
+func NewServer() *Server {
+	s := &Server{
+		Logger:       todo.NewLogger(),
+		EventService: todo.NopEventService(),
+		errc:         make(chan error, 1),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Listen listens on Addr and serves in the background.
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	s.Open(ln)
+	return nil
+}
+
+// Open serves on the listener in the background. It is used by tests with an in-memory listener.
+func (s *Server) Open(ln net.Listener) {
+	if s.APIKey == "" {
+		s.Logger.Warn("API key is empty, admin calls are disabled")
+	}
+
+	s.ln = ln
+	s.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	pb.RegisterItemListServiceServer(s.server, &itemListServer{Server: s})
+	pb.RegisterUserServiceServer(s.server, &userServer{Server: s})
+	go func() {
+		if err := s.server.Serve(ln); err != nil {
+			s.errc <- err
+		}
+	}()
+}
+
+// Err receives the error of the server if it stops serving before it is closed.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// Close ends all streams and waits for pending calls to finish.
+func (s *Server) Close() error {
+	return s.Shutdown(context.Background())
+}
+
+// Shutdown ends all streams and waits for pending calls to finish until ctx is done, then it cancels them.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.cancel()
+	if s.server == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-stopped
+		return ctx.Err()
+	}
+}
+
+// Port returns the TCP port of the running server, 0 if it is not listening on TCP.
+func (s *Server) Port() int {
+	if s.ln == nil {
+		return 0
+	}
+	if addr, ok := s.ln.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, s.status(err)
+	}
+	return resp, nil
+}
+
+func (s *Server) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return s.status(err)
+	}
+	if err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx}); err != nil {
+		return s.status(err)
+	}
+	return nil
+}
+
+// serverStream overrides the context of a stream with the authenticated one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *serverStream) Context() context.Context { return ss.ctx }
+
+// authenticate returns the context with the user whose API key is the bearer token of the call. Admin calls
+// require the server API key instead.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if adminMethods[method] {
+		if s.APIKey == "" || subtle.ConstantTimeCompare([]byte(first(md, "todo-api-key")), []byte(s.APIKey)) != 1 {
+			s.Logger.Warnf("invalid API key for %s", method)
+			return nil, todo.Unauthorized
+		}
+		return ctx, nil
+	}
+
+	apiKey := strings.TrimPrefix(first(md, "authorization"), "Bearer ")
+	if apiKey == "" {
+		return nil, todo.Unauthorized
+	}
+	user, err := s.UserService.FindUserByAPIKey(ctx, apiKey)
+	if todo.ErrCode(err) == todo.ENOTFOUND {
+		return nil, todo.Unauthorized
+	} else if err != nil {
+		return nil, err
+	}
+	return todo.NewContextWithUser(ctx, user), nil
+}
+
+// first returns the first value of the metadata key.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
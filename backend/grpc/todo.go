@@ -0,0 +1,213 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/cmokbel1/todo-app/backend/grpc/pb"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// itemListServer implements pb.ItemListServiceServer with the ItemListService of the Server.
+type itemListServer struct {
+	pb.UnimplementedItemListServiceServer
+	*Server
+}
+
+func (s *itemListServer) CreateList(ctx context.Context, req *pb.CreateListRequest) (*pb.List, error) {
+	list := &todo.List{Name: req.Name, Completed: req.Completed}
+	if err := s.ItemListService.CreateList(ctx, list); err != nil {
+		return nil, err
+	}
+	return marshalList(list), nil
+}
+
+func (s *itemListServer) GetList(ctx context.Context, req *pb.GetListRequest) (*pb.List, error) {
+	list, err := s.ItemListService.FindListByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, err
+	}
+	return marshalList(list), nil
+}
+
+func (s *itemListServer) ListLists(ctx context.Context, req *pb.ListListsRequest) (*pb.ListListsResponse, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.ItemListService.FindLists(ctx, todo.ListFilter{
+		UserID:    &user.ID,
+		Name:      req.Name,
+		Completed: req.Completed,
+		Offset:    int(req.Offset),
+		Limit:     int(req.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListListsResponse{Lists: make([]*pb.List, len(lists))}
+	for i, list := range lists {
+		resp.Lists[i] = marshalList(list)
+	}
+	return resp, nil
+}
+
+func (s *itemListServer) UpdateList(ctx context.Context, req *pb.UpdateListRequest) (*pb.List, error) {
+	list, err := s.ItemListService.UpdateList(ctx, int(req.Id), todo.ListUpdate{Name: req.Name, Completed: req.Completed})
+	if err != nil {
+		return nil, err
+	}
+	return marshalList(list), nil
+}
+
+func (s *itemListServer) DeleteList(ctx context.Context, req *pb.DeleteListRequest) (*emptypb.Empty, error) {
+	if err := s.ItemListService.DeleteList(ctx, int(req.Id)); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *itemListServer) CreateItem(ctx context.Context, req *pb.CreateItemRequest) (*pb.Item, error) {
+	item := &todo.Item{ListID: int(req.ListId), Name: req.Name, Completed: req.Completed}
+	if err := s.ItemListService.CreateItem(ctx, item); err != nil {
+		return nil, err
+	}
+	return marshalItem(item), nil
+}
+
+func (s *itemListServer) GetItem(ctx context.Context, req *pb.GetItemRequest) (*pb.Item, error) {
+	item, err := s.ItemListService.FindItemByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, err
+	}
+	return marshalItem(item), nil
+}
+
+func (s *itemListServer) ListItems(ctx context.Context, req *pb.ListItemsRequest) (*pb.ListItemsResponse, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f := todo.ItemFilter{
+		UserID:    &user.ID,
+		Name:      req.Name,
+		Completed: req.Completed,
+		Offset:    int(req.Offset),
+		Limit:     int(req.Limit),
+	}
+	if req.ListId != nil {
+		listID := int(*req.ListId)
+		f.ListID = &listID
+	}
+
+	items, err := s.ItemListService.FindItems(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListItemsResponse{Items: make([]*pb.Item, len(items))}
+	for i, item := range items {
+		resp.Items[i] = marshalItem(item)
+	}
+	return resp, nil
+}
+
+func (s *itemListServer) UpdateItem(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Item, error) {
+	item, err := s.ItemListService.UpdateItem(ctx, int(req.Id), todo.ItemUpdate{Name: req.Name, Completed: req.Completed})
+	if err != nil {
+		return nil, err
+	}
+	return marshalItem(item), nil
+}
+
+func (s *itemListServer) DeleteItem(ctx context.Context, req *pb.DeleteItemRequest) (*emptypb.Empty, error) {
+	if err := s.ItemListService.DeleteItem(ctx, int(req.Id)); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchList streams the events of the list until it is deleted, the call is canceled or the server is closed.
+func (s *itemListServer) WatchList(req *pb.WatchListRequest, stream pb.ItemListService_WatchListServer) error {
+	ctx := stream.Context()
+	id := int(req.Id)
+
+	// ensure the list exists and belongs to the user before subscribing
+	if _, err := s.ItemListService.FindListByID(ctx, id); err != nil {
+		return err
+	}
+
+	sub, err := s.EventService.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	// headers are sent right away so that clients know the subscription is in place
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.ctx.Done():
+			return nil
+		case event, ok := <-sub.C():
+			if !ok {
+				return todo.Err(todo.EINTERNAL, "subscription closed")
+			}
+			if msg := listEvent(id, event); msg != nil {
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+			}
+			if event.Type == todo.EventListDeleted && event.List.ID == id {
+				return nil
+			}
+		}
+	}
+}
+
+// listEvent converts an event into a message if it concerns the list.
+func listEvent(listID int, event todo.Event) *pb.Event {
+	if v := event.List; v != nil && v.ID == listID {
+		return &pb.Event{Type: event.Type, List: marshalList(v)}
+	} else if v := event.Item; v != nil && v.ListID == listID {
+		return &pb.Event{Type: event.Type, Item: marshalItem(v)}
+	}
+	return nil
+}
+
+func marshalList(list *todo.List) *pb.List {
+	msg := &pb.List{
+		Id:        int64(list.ID),
+		UserId:    int64(list.UserID),
+		Name:      list.Name,
+		Completed: list.Completed,
+		Items:     make([]*pb.Item, len(list.Items)),
+		CreatedAt: timestamppb.New(list.CreatedAt),
+		UpdatedAt: timestamppb.New(list.UpdatedAt),
+	}
+	for i, item := range list.Items {
+		msg.Items[i] = marshalItem(item)
+	}
+	return msg
+}
+
+func marshalItem(item *todo.Item) *pb.Item {
+	return &pb.Item{
+		Id:        int64(item.ID),
+		UserId:    int64(item.UserID),
+		ListId:    int64(item.ListID),
+		Name:      item.Name,
+		Completed: item.Completed,
+		CreatedAt: timestamppb.New(item.CreatedAt),
+		UpdatedAt: timestamppb.New(item.UpdatedAt),
+	}
+}
@@ -0,0 +1,89 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/cmokbel1/todo-app/backend/grpc/pb"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userServer implements pb.UserServiceServer with the UserService of the Server.
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	*Server
+}
+
+func (s *userServer) GetCurrentUser(ctx context.Context, req *pb.GetCurrentUserRequest) (*pb.User, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return marshalUser(user), nil
+}
+
+func (s *userServer) UpdateCurrentUser(ctx context.Context, req *pb.UpdateCurrentUserRequest) (*pb.User, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user, err = s.UserService.UpdateUser(ctx, user.ID, todo.UserUpdate{Name: req.Name, Email: req.Email}); err != nil {
+		return nil, err
+	}
+	return marshalUser(user), nil
+}
+
+func (s *userServer) RotateAPIKey(ctx context.Context, req *pb.RotateAPIKeyRequest) (*pb.RotateAPIKeyResponse, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user, err = s.UserService.RotateAPIKey(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return &pb.RotateAPIKeyResponse{ApiKey: user.APIKey}, nil
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	user := &todo.User{Name: req.Name, Password: req.Password, Email: req.Email}
+	if err := s.UserService.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	s.Logger.Infof("created user %q (id = %d)", user.Name, user.ID)
+	return marshalUser(user), nil
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users, err := s.UserService.FindUsers(ctx, todo.UserFilter{Offset: int(req.Offset), Limit: int(req.Limit)})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.ListUsersResponse{Users: make([]*pb.User, len(users))}
+	for i, user := range users {
+		resp.Users[i] = marshalUser(user)
+	}
+	return resp, nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	if err := s.UserService.DeleteUser(ctx, int(req.Id)); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func marshalUser(user *todo.User) *pb.User {
+	msg := &pb.User{
+		Id:        int64(user.ID),
+		Name:      user.Name,
+		Email:     user.Email,
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+	if user.DeleteAt != nil {
+		msg.DeleteAt = timestamppb.New(*user.DeleteAt)
+	}
+	return msg
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// defaultAccountDeletionGracePeriod is how long an account can still be restored after its deletion was requested.
+const defaultAccountDeletionGracePeriod = 7 * 24 * time.Hour
+
+type accountDeleteRequest struct {
+	Password string `json:"password"`
+}
+
+func (s *Server) registerAccountRoutes(r chi.Router) {
+	r.With(s.requireAuth).Delete("/user", s.handleAccountDelete)
+	r.With(s.requireAuth).Delete("/user/deletion", s.handleAccountDeleteCancel)
+
+	r.Route("/user/export", func(r chi.Router) {
+		r.Use(s.requireAuth)
+		r.Post("/", s.handleAccountExportCreate)
+		r.With(s.requireIntParam("id")).Get("/{id}", s.handleAccountExport)
+	})
+}
+
+// registerAccountDownloadRoutes registers the download of account exports, which is streamed like the other exports.
+func (s *Server) registerAccountDownloadRoutes(r chi.Router) {
+	r.With(s.requireAuth, s.requireIntParam("id")).Get("/user/export/{id}/download", s.handleAccountExportDownload)
+}
+
+func (s *Server) handleAccountExportCreate(w http.ResponseWriter, r *http.Request) {
+	export, err := s.AccountExportService.CreateAccountExport(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/user/export/%d", export.ID))
+	s.json(w, r, http.StatusAccepted, export)
+}
+
+func (s *Server) handleAccountExport(w http.ResponseWriter, r *http.Request) {
+	export, err := s.AccountExportService.FindAccountExportByID(r.Context(), r.Context().Value("id").(int))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, export)
+}
+
+func (s *Server) handleAccountExportDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(int)
+	archive, err := s.AccountExportService.DownloadAccountExport(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	// large archives take longer than the server's WriteTimeout to send to slow clients
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.error(w, r, todo.Err(todo.EINTERNAL, "download unsupported: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("todo-export-%d.zip", id)))
+	w.WriteHeader(http.StatusOK)
+	// the archive is only discarded once it was sent, a failed download can be retried
+	if _, err := w.Write(archive); err != nil {
+		s.logger(r).Warnf("failed to send export %d: %v", id, err)
+		return
+	} else if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.logger(r).Warnf("failed to send export %d: %v", id, err)
+		return
+	}
+	if err := s.AccountExportService.MarkAccountExportDownloaded(r.Context(), id); err != nil {
+		s.logger(r).E(err)
+	}
+}
+
+func (s *Server) handleAccountDelete(w http.ResponseWriter, r *http.Request) {
+	var req accountDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.error(w, r, todo.Err(todo.EINVALID, "invalid json: %v", err))
+		return
+	}
+
+	ctx := r.Context()
+	at := time.Now().UTC().Add(s.AccountDeletionGracePeriod)
+	user, err := s.UserService.ScheduleUserDeletion(ctx, todo.UserFromContext(ctx).ID, req.Password, at)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	user.Password = ""
+	s.json(w, r, http.StatusAccepted, user)
+}
+
+func (s *Server) handleAccountDeleteCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := s.UserService.CancelUserDeletion(ctx, todo.UserFromContext(ctx).ID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	user.Password = ""
+	s.json(w, r, http.StatusOK, user)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// accountExportService serves a single ready export and records whether it was marked downloaded.
+type accountExportService struct {
+	todo.AccountExportService
+	export     *todo.AccountExport
+	archive    []byte
+	downloaded bool
+}
+
+func (s *accountExportService) DownloadAccountExport(ctx context.Context, id int) ([]byte, error) {
+	if id != s.export.ID {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find export with id %d", id)
+	} else if s.downloaded {
+		return nil, todo.Err(todo.ECONFLICT, "the export was already downloaded")
+	}
+	return s.archive, nil
+}
+
+func (s *accountExportService) MarkAccountExportDownloaded(ctx context.Context, id int) error {
+	s.downloaded = true
+	return nil
+}
+
+// failingWriter fails to write the body, as when the client disconnects during a download.
+type failingWriter struct {
+	header http.Header
+}
+
+func (w *failingWriter) Header() http.Header         { return w.header }
+func (w *failingWriter) WriteHeader(int)             {}
+func (w *failingWriter) Write(b []byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestAccountExportDownload(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	exports := &accountExportService{export: &todo.AccountExport{ID: 1, Status: todo.ExportReady}, archive: []byte("PK archive")}
+	s.AccountExportService = exports
+	h := s.router()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	request := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/api/user/export/"+strconv.Itoa(exports.export.ID)+"/download", nil)
+		r.Header.Set("Authorization", "Bearer "+user.APIKey)
+		return r
+	}
+
+	// a failed download keeps the archive, the response is not buffered so the failure is noticed
+	h.ServeHTTP(&failingWriter{header: make(http.Header)}, request())
+	if exports.downloaded {
+		t.Fatal("want the export kept after a failed download")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request())
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+	} else if !bytes.Equal(w.Body.Bytes(), exports.archive) {
+		t.Fatalf("want archive %q got %q", exports.archive, w.Body.Bytes())
+	} else if !exports.downloaded {
+		t.Fatal("want the export marked downloaded")
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request())
+	if w.Code != http.StatusConflict {
+		t.Fatalf("want %d got %d", http.StatusConflict, w.Code)
+	}
+}
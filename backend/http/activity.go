@@ -0,0 +1,95 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// defaultActivityLimit is the number of activity entries returned when no limit is specified.
+const defaultActivityLimit = 100
+
+func (s *Server) registerActivityRoutes(r chi.Router) {
+	r.With(s.requireAPIKey).Get("/audit", s.handleAudit)
+}
+
+func (s *Server) handleListActivity(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(int)
+	f, err := parseActivityFilter(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	activity, err := s.ActivityService.FindListActivity(r.Context(), id, f)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, activity)
+}
+
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	f, err := parseActivityFilter(r)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	if v := r.URL.Query().Get("user"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			s.error(w, r, todo.Err(todo.EINVALID, "user must be an integer"))
+			return
+		}
+		f.UserID = &userID
+	}
+
+	activity, err := s.ActivityService.FindActivity(r.Context(), f)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, activity)
+}
+
+// parseActivityFilter reads the action, since, until, limit and offset query parameters. Times are RFC 3339.
+func parseActivityFilter(r *http.Request) (todo.ActivityFilter, error) {
+	f := todo.ActivityFilter{Limit: defaultActivityLimit}
+	query := r.URL.Query()
+
+	if v := query.Get("action"); v != "" {
+		f.Action = &v
+	}
+
+	for key, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := query.Get(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, todo.Err(todo.EINVALID, "%s must be an RFC 3339 timestamp", key)
+			}
+			*dst = &t
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return f, todo.Err(todo.EINVALID, "limit must be a positive integer")
+		}
+		f.Limit = n
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, todo.Err(todo.EINVALID, "offset must be a non-negative integer")
+		}
+		f.Offset = n
+	}
+
+	return f, nil
+}
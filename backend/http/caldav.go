@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/cmokbel1/todo-app/backend/caldav"
+	"github.com/go-chi/chi"
+)
+
+// calDAVPrefix is the path the CalDAV server is mounted at.
+const calDAVPrefix = "/dav"
+
+// calDAVMethods are the WebDAV methods CalDAV clients use besides those of HTTP.
+var calDAVMethods = []string{"PROPFIND", "PROPPATCH", "REPORT", "MKCOL", "COPY", "MOVE"}
+
+func init() {
+	// chi rejects methods it does not know about
+	for _, method := range calDAVMethods {
+		chi.RegisterMethod(method)
+	}
+}
+
+// registerCalDAVRoutes serves the lists as CalDAV calendars. CalDAV clients authenticate every request with HTTP
+// Basic auth so the handler does not use sessions.
+func (s *Server) registerCalDAVRoutes(r chi.Router) {
+	// CalDAV clients authenticate with every request, so they are limited by IP address
+	r.With(s.rateLimit(RateLimitDefault)).Mount(calDAVPrefix, caldav.NewHandler(calDAVPrefix, s.ItemListService, s.CalendarObjectService, s.UserService))
+	// RFC 6764 service discovery
+	r.Handle("/.well-known/caldav", http.RedirectHandler(calDAVPrefix, http.StatusPermanentRedirect))
+}
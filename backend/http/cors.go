@@ -0,0 +1,154 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy decides which cross-origin requests browsers may make to the server.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins like "https://todo.example.com" or wildcards like "https://*.example.com",
+	// which match every subdomain but not the domain itself. "*" allows any origin, but then no credentials.
+	AllowedOrigins []string
+	AllowedMethods []string
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers which scripts of other origins may read.
+	ExposedHeaders []string
+	// AllowCredentials allows requests with the session cookie from the allowed origins. It is ignored if any origin
+	// is allowed, so that no site can act on behalf of a user.
+	AllowCredentials bool
+	// MaxAge is how long browsers cache the answer to a preflight request.
+	MaxAge time.Duration
+}
+
+// DefaultCORSPolicy returns the policy which allows the methods and headers the API uses, but no origins.
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Origin", csrfHeader, requestIDHeader},
+		ExposedHeaders:   []string{"Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", requestIDHeader},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+}
+
+// ValidateCORSOrigin returns an error if origin is neither "*", an origin nor a wildcard origin of a CORSPolicy.
+func ValidateCORSOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q, must be a scheme and host like https://todo.example.com", origin)
+	}
+	if strings.Contains(u.Host, "*") && (!strings.HasPrefix(u.Host, "*.") || strings.Count(u.Host, "*") > 1) {
+		return fmt.Errorf("invalid origin %q, a wildcard must be the first label of the host", origin)
+	}
+	return nil
+}
+
+// AllowsAnyOrigin reports whether the policy allows requests from any origin.
+func (p CORSPolicy) AllowsAnyOrigin() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsCredentials reports whether the policy allows requests with the session cookie, which it never does for any
+// origin.
+func (p CORSPolicy) allowsCredentials() bool {
+	return p.AllowCredentials && !p.AllowsAnyOrigin()
+}
+
+// AllowsOrigin reports whether the policy allows requests from origin.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) || matchWildcardOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcardOrigin reports whether origin matches a pattern like "https://*.example.com", the wildcard matches
+// one or more labels of the host.
+func matchWildcardOrigin(pattern, origin string) bool {
+	i := strings.Index(pattern, "*")
+	if i < 0 {
+		return false
+	}
+	prefix, suffix := strings.ToLower(pattern[:i]), strings.ToLower(pattern[i+1:])
+	origin = strings.ToLower(origin)
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	labels := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(labels, ":/@?#")
+}
+
+// corsPolicy returns the policy of the route of path, which is the policy of the longest prefix of CORSRoutes which
+// matches whole segments of path, or CORS.
+func (s *Server) corsPolicy(path string) CORSPolicy {
+	policy, longest := s.CORS, -1
+	for prefix, p := range s.CORSRoutes {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if (path == prefix || strings.HasPrefix(path, prefix+"/")) && len(prefix) > longest {
+			policy, longest = p, len(prefix)
+		}
+	}
+	return policy
+}
+
+// cors is middleware that applies the CORS policy of the route. Only an origin the policy allows is echoed back, so
+// browsers refuse the responses to all other origins. Preflight requests are answered here, CalDAV clients send other
+// OPTIONS requests to discover features.
+func (s *Server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		policy := s.corsPolicy(r.URL.Path)
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		headers := w.Header()
+		headers.Add("Vary", "Origin")
+		if preflight {
+			headers.Add("Vary", "Access-Control-Request-Method")
+			headers.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if policy.AllowsOrigin(origin) {
+			if policy.AllowsAnyOrigin() {
+				headers.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				headers.Set("Access-Control-Allow-Origin", origin)
+			}
+			if policy.allowsCredentials() {
+				headers.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if preflight {
+				headers.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+				headers.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+				if policy.MaxAge > 0 {
+					headers.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge/time.Second)))
+				}
+			} else if len(policy.ExposedHeaders) > 0 {
+				headers.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+			}
+		}
+
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORSPolicy_AllowsOrigin(t *testing.T) {
+	p := CORSPolicy{AllowedOrigins: []string{"https://todo.example.com", "https://*.example.org"}}
+	tt := map[string]bool{
+		"":                              false,
+		"null":                          false,
+		"https://todo.example.com":      true,
+		"HTTPS://TODO.EXAMPLE.COM":      true,
+		"http://todo.example.com":       false,
+		"https://todo.example.com:8443": false,
+		"https://app.example.org":       true,
+		"https://a.b.example.org":       true,
+		"https://example.org":           false,
+		"https://.example.org":          false,
+		"https://evil.com/.example.org": false,
+		"https://evilexample.org":       false,
+		"https://example.org.evil.com":  false,
+	}
+	for origin, want := range tt {
+		if got := p.AllowsOrigin(origin); got != want {
+			t.Errorf("origin %q: want %v got %v", origin, want, got)
+		}
+	}
+}
+
+func TestValidateCORSOrigin(t *testing.T) {
+	for _, origin := range []string{"*", "https://todo.example.com", "http://localhost:3000", "https://*.example.com"} {
+		if err := ValidateCORSOrigin(origin); err != nil {
+			t.Errorf("origin %q: %v", origin, err)
+		}
+	}
+	for _, origin := range []string{"localhost:3000", "todo.example.com", "https://todo.example.com/", "https://a.*.example.com", "https://*.*.example.com"} {
+		if err := ValidateCORSOrigin(origin); err == nil {
+			t.Errorf("origin %q: want error", origin)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.CORS.AllowedOrigins = []string{"https://todo.example.com", "https://*.todo.example.com"}
+	s.CORSRoutes = map[string]CORSPolicy{
+		"/api/openapi.json": {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+		"/api/build":        {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+	}
+	h := s.router()
+
+	do := func(method, path, origin string) http.Header {
+		t.Helper()
+		r := httptest.NewRequest(method, path, nil)
+		r.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			r.Header.Set("Access-Control-Request-Headers", "content-type, x-csrf-token")
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if method == http.MethodOptions && w.Code != http.StatusNoContent {
+			t.Fatalf("want %d got %d", http.StatusNoContent, w.Code)
+		}
+		return w.Header()
+	}
+
+	// only the matching origin is echoed back
+	headers := do(http.MethodOptions, "/api/todos", "https://app.todo.example.com")
+	if got := headers.Get("Access-Control-Allow-Origin"); got != "https://app.todo.example.com" {
+		t.Fatalf("want origin echoed got %q", got)
+	} else if got := headers.Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("want credentials got %q", got)
+	} else if got, want := headers.Get("Access-Control-Allow-Headers"), "Accept, Authorization, Content-Type, Origin, X-CSRF-Token, X-Request-Id"; got != want {
+		t.Fatalf("want headers %q got %q", want, got)
+	} else if got, want := headers.Get("Access-Control-Max-Age"), "600"; got != want {
+		t.Fatalf("want max age %q got %q", want, got)
+	}
+
+	headers = do(http.MethodGet, "/api/todos", "https://todo.example.com")
+	if got := headers.Get("Access-Control-Allow-Origin"); got != "https://todo.example.com" {
+		t.Fatalf("want origin echoed got %q", got)
+	} else if got := headers.Get("Access-Control-Expose-Headers"); got == "" {
+		t.Fatal("want exposed headers")
+	} else if got := headers.Get("Vary"); got != "Origin" {
+		t.Fatalf("want Vary Origin got %q", got)
+	}
+
+	for _, origin := range []string{"https://evil.example.com", "https://todo.example.com.evil.com"} {
+		headers = do(http.MethodOptions, "/api/todos", origin)
+		if got := headers.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Fatalf("origin %q: want no CORS headers got %q", origin, got)
+		} else if got := headers.Get("Access-Control-Allow-Methods"); got != "" {
+			t.Fatalf("origin %q: want no CORS headers got %q", origin, got)
+		}
+	}
+
+	// the route overrides the policy, any origin may read the spec but without credentials
+	headers = do(http.MethodOptions, "/api/openapi.json", "https://evil.example.com")
+	if got := headers.Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("want any origin got %q", got)
+	} else if got := headers.Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("want no credentials got %q", got)
+	} else if got := headers.Get("Access-Control-Allow-Methods"); got != "GET" {
+		t.Fatalf("want methods %q got %q", "GET", got)
+	} else if got := headers.Get("Access-Control-Max-Age"); got != "" {
+		t.Fatalf("want no max age got %q", got)
+	}
+
+	// any origin never gets credentials, even if the policy allows them
+	for _, method := range []string{http.MethodOptions, http.MethodGet} {
+		headers = do(method, "/api/build", "https://evil.example.com")
+		if got := headers.Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Fatalf("%s: want any origin got %q", method, got)
+		} else if got := headers.Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Fatalf("%s: want no credentials got %q", method, got)
+		}
+	}
+}
+
+func TestServer_corsPolicy(t *testing.T) {
+	s := NewServer()
+	s.CORSRoutes = map[string]CORSPolicy{
+		"/api/user":      {MaxAge: time.Minute},
+		"/api/user/key/": {MaxAge: time.Hour},
+	}
+	tt := map[string]time.Duration{
+		"/api/todos":       s.CORS.MaxAge,
+		"/api/users":       s.CORS.MaxAge,
+		"/api/user":        time.Minute,
+		"/api/user/login":  time.Minute,
+		"/api/user/key":    time.Hour,
+		"/api/user/keys/1": time.Minute,
+	}
+	for path, want := range tt {
+		if got := s.corsPolicy(path).MaxAge; got != want {
+			t.Errorf("path %q: want %v got %v", path, want, got)
+		}
+	}
+}
+
+func TestServer_checkSocketOrigin(t *testing.T) {
+	s := NewServer()
+	s.CORS.AllowedOrigins = []string{"https://todo.example.com"}
+	s.CORSRoutes = map[string]CORSPolicy{
+		"/api/public": {AllowedOrigins: []string{"*"}},
+		"/api/any":    {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+	}
+
+	check := func(path, origin string) bool {
+		r := httptest.NewRequest(http.MethodGet, "http://api.example.com"+path, nil)
+		r.Header.Set("Origin", origin)
+		return s.checkSocketOrigin(r)
+	}
+	if !check("/api/lists/1/socket", "http://api.example.com") {
+		t.Fatal("want same origin allowed")
+	} else if !check("/api/lists/1/socket", "https://todo.example.com") {
+		t.Fatal("want allowed origin allowed")
+	} else if check("/api/lists/1/socket", "https://evil.example.com") {
+		t.Fatal("want other origin refused")
+	} else if check("/api/public/socket", "https://evil.example.com") {
+		t.Fatal("want origin without credentials refused")
+	} else if check("/api/any/socket", "https://evil.example.com") {
+		t.Fatal("want any origin refused even if the policy allows credentials")
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/cmokbel1/todo-app/backend/crypto"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// csrfHeader is the header which carries the CSRF token of the session, see handleCSRFToken.
+const csrfHeader = "X-CSRF-Token"
+
+// csrfSessionKey is the session key of the CSRF token.
+const csrfSessionKey = "csrf_token"
+
+func (s *Server) registerCSRFRoutes(r chi.Router) {
+	r.Get("/csrf", s.handleCSRFToken)
+}
+
+// CSRFToken is the response of the CSRF token endpoint.
+type CSRFToken struct {
+	Token string `json:"token"`
+}
+
+// handleCSRFToken returns the CSRF token of the session, which is created on the first request. It is the same for
+// the lifetime of the session and survives logging in, the session is destroyed on logout.
+func (s *Server) handleCSRFToken(w http.ResponseWriter, r *http.Request) {
+	token := s.SessionManager.GetString(r.Context(), csrfSessionKey)
+	if token == "" {
+		token = crypto.RandomString()
+		s.SessionManager.Put(r.Context(), csrfSessionKey, token)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	s.json(w, r, http.StatusOK, &CSRFToken{Token: token})
+}
+
+// csrf is middleware that protects the users of cookie sessions from cross-site request forgery. Requests which
+// change state must send the CSRF token of the session in the X-CSRF-Token header, which other sites can neither
+// read nor set. Requests which are authenticated by API key and requests without an authenticated session carry no
+// ambient credentials and are not checked. A request with both the session cookie and an Authorization header is
+// authenticated by the cookie, so it is checked.
+func (s *Server) csrf(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if authenticatedByAPIKey(r.Context()) || !s.SessionManager.Exists(r.Context(), "user") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		want := s.SessionManager.GetString(r.Context(), csrfSessionKey)
+		got := r.Header.Get(csrfHeader)
+		if want == "" || subtle.ConstantTimeCompare([]byte(want), []byte(got)) != 1 {
+			s.error(w, r, todo.Err(todo.EFORBIDDEN, "invalid CSRF token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestCSRF(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	do := func(method, path string, header http.Header) *http.Response {
+		t.Helper()
+		r, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(`{"name": "groceries"}`))
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// logging in does not require a token, there is no session to protect yet
+	r, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/user/login", strings.NewReader(`{"name": "george", "password": "password"}`))
+	if resp, err := client.Do(r); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// a cross-origin form or fetch sends the cookie but cannot read or send the token
+	if resp := do(http.MethodPost, "/api/todos", http.Header{"Origin": {"https://evil.example"}}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("want %d got %d", http.StatusForbidden, resp.StatusCode)
+	} else if resp := do(http.MethodPost, "/api/todos", http.Header{csrfHeader: {"guess"}}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("want %d got %d", http.StatusForbidden, resp.StatusCode)
+	} else if resp := do(http.MethodPost, "/api/todos", http.Header{"Authorization": {"Bearer bogus"}}); resp.StatusCode != http.StatusForbidden {
+		// the request is authenticated by the cookie, the header does not exempt it
+		t.Fatalf("want %d got %d", http.StatusForbidden, resp.StatusCode)
+	} else if resp := do(http.MethodGet, "/api/todos", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := client.Get(ts.URL + "/api/csrf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var token CSRFToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if token.Token == "" {
+		t.Fatal("want token")
+	}
+	if resp := do(http.MethodPost, "/api/todos", http.Header{csrfHeader: {token.Token}}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d got %d", http.StatusCreated, resp.StatusCode)
+	}
+
+	// the token is bound to the session
+	other, _ := cookiejar.New(nil)
+	client.Jar = other
+	r, _ = http.NewRequest(http.MethodPost, ts.URL+"/api/user/login", strings.NewReader(`{"name": "george", "password": "password"}`))
+	if resp, err := client.Do(r); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp := do(http.MethodPost, "/api/todos", http.Header{csrfHeader: {token.Token}}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("want %d got %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	// API keys are not sent by browsers on their own
+	client.Jar = nil
+	if resp := do(http.MethodPost, "/api/todos", http.Header{"Authorization": {"Bearer " + user.APIKey}}); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("want %d got %d", http.StatusCreated, resp.StatusCode)
+	}
+}
@@ -0,0 +1,61 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultDebugAddr is the default address of the debug server.
+const DefaultDebugAddr = ":6060"
+
+// DebugServer serves the Prometheus metrics at /metrics and the pprof profiles at /debug/pprof/.
+type DebugServer struct {
+	ln     net.Listener
+	server *http.Server
+	errc   chan error
+
+	Addr string
+}
+
+func NewDebugServer(addr string) *DebugServer {
+	h := http.NewServeMux()
+	h.Handle("/metrics", promhttp.Handler())
+	h.HandleFunc("/debug/pprof/", pprof.Index)
+	h.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	h.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	h.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	h.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &DebugServer{
+		// profiles are collected for up to 30 seconds by default, so writes are not limited
+		server: &http.Server{Handler: h, ReadHeaderTimeout: 5 * time.Second},
+		errc:   make(chan error, 1),
+		Addr:   addr,
+	}
+}
+
+// Listen listens on Addr and serves in the background.
+func (s *DebugServer) Listen() (err error) {
+	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
+		return err
+	}
+	go serve(s.server, s.ln, s.errc)
+	return nil
+}
+
+// Err receives the error of the server if it stops serving before it is shut down.
+func (s *DebugServer) Err() <-chan error {
+	return s.errc
+}
+
+// Shutdown stops the server and waits for pending requests until ctx is done.
+func (s *DebugServer) Shutdown(ctx context.Context) error {
+	if s.ln == nil {
+		return nil
+	}
+	return shutdown(ctx, s.server)
+}
@@ -0,0 +1,32 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestDebugServer(t *testing.T) {
+	s := NewDebugServer("localhost:0")
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	url := "http://" + s.ln.Addr().String()
+
+	for _, path := range []string{"/metrics", "/debug/pprof/"} {
+		resp, err := http.Get(url + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("want %d got %d for %s", http.StatusOK, resp.StatusCode, path)
+		}
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if _, err := http.Get(url + "/metrics"); err == nil {
+		t.Fatal("want error after shutdown")
+	}
+}
@@ -24,6 +24,10 @@ func StatusCode(err error) int {
 			return http.StatusNotFound
 		case todo.EUNAUTHORIZED:
 			return http.StatusUnauthorized
+		case todo.EFORBIDDEN:
+			return http.StatusForbidden
+		case todo.ERATELIMITED:
+			return http.StatusTooManyRequests
 		}
 	}
 
@@ -41,6 +45,10 @@ func ErrCodeFromStatus(code int) string {
 		return todo.EINVALID
 	case http.StatusUnauthorized:
 		return todo.EUNAUTHORIZED
+	case http.StatusForbidden:
+		return todo.EFORBIDDEN
+	case http.StatusTooManyRequests:
+		return todo.ERATELIMITED
 	}
 
 	return todo.EINTERNAL
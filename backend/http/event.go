@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// eventKeepAliveInterval is how often a comment is written to idle event streams so that proxies do not close
+// the connection.
+const eventKeepAliveInterval = 30 * time.Second
+
+func (s *Server) registerEventRoutes(r chi.Router) {
+	r.With(s.requireAuth).Get("/events", s.handleEvents)
+}
+
+// handleEvents streams the List and Item events of the current user as Server-Sent Events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sub, err := s.EventService.Subscribe(ctx)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	// the server's WriteTimeout applies to the whole response, lift it for this long-lived stream
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.error(w, r, todo.Err(todo.EINTERNAL, "event stream unsupported: %v", err))
+		return
+	}
+
+	headers := w.Header()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("Connection", "keep-alive")
+	headers.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(eventKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-sub.C():
+			if !ok {
+				// the subscription was closed because the client could not keep up
+				return
+			}
+
+			b, err := json.Marshal(event)
+			if err != nil {
+				s.logger(r).Errorf("failed to marshal %s event: %v", event.Type, err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, b); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestEvents(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	events := inmem.NewEventService()
+	s.EventService = events
+
+	// the stream outlives the WriteTimeout of the server
+	ts := httptest.NewUnstartedServer(s.router())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/events", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, resp.StatusCode)
+	} else if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("want event stream got %q", got)
+	}
+
+	time.Sleep(3 * ts.Config.WriteTimeout)
+	events.PublishEvent(user.ID, todo.Event{Type: todo.EventItemCreated, Item: &todo.Item{ID: 1, ListID: 1, Name: "milk"}})
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	var got []string
+	for len(got) < 2 {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatalf("stream ended after %q", got)
+			}
+			got = append(got, line)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("want event got %q", got)
+		}
+	}
+	if got[0] != "event: "+todo.EventItemCreated || !strings.HasPrefix(got[1], "data: ") || !strings.Contains(got[1], `"milk"`) {
+		t.Fatalf("unexpected event %q", got)
+	}
+}
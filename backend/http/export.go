@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// maxImportSize is the maximum size of an import request body.
+const maxImportSize = 10 << 20
+
+// exportPageSize is the number of lists loaded at once while exporting.
+const exportPageSize = 100
+
+type importResponse struct {
+	// DryRun is true when the lists were only read and not created.
+	DryRun  bool          `json:"dryRun"`
+	Summary importSummary `json:"summary"`
+	Lists   []*todo.List  `json:"lists"`
+}
+
+// importSummary counts the lists and items of an import.
+type importSummary struct {
+	Lists     int `json:"lists"`
+	Items     int `json:"items"`
+	Completed int `json:"completed"`
+}
+
+func newImportSummary(lists []*todo.List) importSummary {
+	summary := importSummary{Lists: len(lists)}
+	for _, list := range lists {
+		summary.Items += len(list.Items)
+		for _, item := range list.Items {
+			if item.Completed {
+				summary.Completed++
+			}
+		}
+	}
+	return summary
+}
+
+type importErrorResponse struct {
+	Error
+	Rows format.RowErrors `json:"rows"`
+}
+
+// registerExportRoutes registers the routes of exports, which are streamed and must be served without buffering.
+func (s *Server) registerExportRoutes(r chi.Router) {
+	r.With(s.requireAuth).Get("/export", s.handleExport)
+	r.With(s.requireAuth, s.requireIntParam("id")).Get("/todos/{id}/export", s.handleTodoListExport)
+}
+
+func (s *Server) registerImportRoutes(r chi.Router) {
+	r.With(s.requireAuth).Post("/import", s.handleImport)
+}
+
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	user, err := todo.ValidUserFromContext(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	f, err := format.Lookup(queryFormat(r))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	s.export(w, r, f, "todos", s.userLists(r.Context(), user.ID))
+}
+
+// userLists returns a ListReader of all the lists of the user, which are loaded a page at a time while they are
+// exported. The pages follow the last ID so that lists changed meanwhile are neither skipped nor repeated.
+func (s *Server) userLists(ctx context.Context, userID int) format.ListReader {
+	afterID := 0
+	return func() ([]*todo.List, error) {
+		lists, err := s.ItemListService.FindLists(ctx, todo.ListFilter{UserID: &userID, AfterID: afterID, Limit: exportPageSize})
+		if len(lists) > 0 {
+			afterID = lists[len(lists)-1].ID
+		}
+		return lists, err
+	}
+}
+
+func (s *Server) handleTodoListExport(w http.ResponseWriter, r *http.Request) {
+	f, err := format.Lookup(queryFormat(r))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	list, err := s.ItemListService.FindListByID(r.Context(), r.Context().Value("id").(int))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.export(w, r, f, list.Name, format.Lists([]*todo.List{list}))
+}
+
+func (s *Server) handleImport(w http.ResponseWriter, r *http.Request) {
+	f, err := format.Lookup(queryFormat(r))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	} else if f.Decode == nil {
+		s.error(w, r, todo.Err(todo.EINVALID, "the %s format cannot be imported", f.Name))
+		return
+	}
+
+	lists, err := f.Decode(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		s.importError(w, r, err)
+		return
+	}
+
+	// a dry run reports what would be imported without creating anything
+	if r.URL.Query().Get("dryRun") == "true" {
+		s.json(w, r, http.StatusOK, importResponse{DryRun: true, Summary: newImportSummary(lists), Lists: lists})
+		return
+	}
+
+	if err := s.ItemListService.ImportLists(r.Context(), lists); err != nil {
+		s.importError(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusCreated, importResponse{Summary: newImportSummary(lists), Lists: lists})
+}
+
+// importError writes the error of an import, invalid rows are all reported together.
+func (s *Server) importError(w http.ResponseWriter, r *http.Request, err error) {
+	var rowErrs format.RowErrors
+	if !errors.As(err, &rowErrs) {
+		s.error(w, r, err)
+		return
+	}
+	s.logger(r).Info(err.Error())
+	s.json(w, r, http.StatusBadRequest, importErrorResponse{
+		Error: Error{Message: fmt.Sprintf("the import contains %d invalid rows", len(rowErrs))},
+		Rows:  rowErrs,
+	})
+}
+
+// export streams the lists read from next as an attachment in the format f.
+func (s *Server) export(w http.ResponseWriter, r *http.Request, f *format.Format, name string, next format.ListReader) {
+	if f.Encode == nil {
+		s.error(w, r, todo.Err(todo.EINVALID, "the %s format cannot be exported", f.Name))
+		return
+	}
+
+	// the first lists are read before the status is written, so that failing to load them is still reported
+	first, err := next()
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	// large exports take longer than the server's WriteTimeout, lift it as long as lists are still written.
+	// Writers which do not support deadlines have none to lift.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.error(w, r, todo.Err(todo.EINTERNAL, "export unsupported: %v", err))
+		return
+	}
+	lists := func() ([]*todo.List, error) {
+		if first != nil {
+			lists := first
+			first = nil
+			return lists, nil
+		}
+		// the lists written so far are sent before the next page is loaded
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return nil, err
+		}
+		return next()
+	}
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename(name)+f.Extension))
+	w.WriteHeader(http.StatusOK)
+	if err := f.Encode(w, lists); err != nil {
+		// the status has already been written, all we can do is log the failure
+		s.logger(r).E(err)
+	}
+}
+
+// queryFormat returns the format query parameter, which defaults to json.
+func queryFormat(r *http.Request) string {
+	if v := r.URL.Query().Get("format"); v != "" {
+		return v
+	}
+	return format.JSON.Name
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// filename returns name with all characters which are not safe in a file name replaced.
+func filename(name string) string {
+	if name = unsafeFilenameChars.ReplaceAllString(name, "-"); name == "" || name == "-" {
+		return "list"
+	}
+	return name
+}
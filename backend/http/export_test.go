@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestExport(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	// the lists span more than one page
+	ctx := todo.NewContextWithUser(context.Background(), user)
+	for i := 0; i <= exportPageSize; i++ {
+		if err := s.ItemListService.CreateList(ctx, &todo.List{Name: "list " + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/export?format=json", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	w := httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+	}
+
+	lists, err := format.JSON.Decode(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(lists) != exportPageSize+1 {
+		t.Fatalf("want %d lists got %d", exportPageSize+1, len(lists))
+	}
+	for i, list := range lists {
+		if want := "list " + strconv.Itoa(i); list.Name != want {
+			t.Fatalf("want %q got %q", want, list.Name)
+		}
+	}
+}
+
+// blockingListService blocks loading the second page of lists until release is closed.
+type blockingListService struct {
+	todo.ItemListService
+	calls   int
+	release chan struct{}
+}
+
+func (s *blockingListService) FindLists(ctx context.Context, f todo.ListFilter) ([]*todo.List, error) {
+	if s.calls++; s.calls == 2 {
+		<-s.release
+	}
+	return s.ItemListService.FindLists(ctx, f)
+}
+
+func TestExport_Stream(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	lists := &blockingListService{ItemListService: inmem.NewItemListService(), release: make(chan struct{})}
+	s.ItemListService = lists
+
+	// the export outlives the WriteTimeout of the server
+	ts := httptest.NewUnstartedServer(s.router())
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+	defer func() {
+		select {
+		case <-lists.release:
+		default:
+			close(lists.release)
+		}
+	}()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ctx := todo.NewContextWithUser(context.Background(), user)
+	for i := 0; i <= exportPageSize; i++ {
+		if err := lists.CreateList(ctx, &todo.List{Name: "list " + strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/export?format=csv", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	// a buffered export is never received as the second page is never loaded
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	// the first page is received while the second one is still loading
+	body := bufio.NewReader(resp.Body)
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for {
+			line, err := body.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- line
+		}
+	}()
+	read := func() string {
+		t.Helper()
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("export ended early")
+			}
+			return line
+		case <-time.After(5 * time.Second):
+			t.Fatal("want line")
+		}
+		return ""
+	}
+	for i := 0; i <= exportPageSize-1; i++ {
+		read()
+	}
+	if line := read(); line != fmt.Sprintf("list %d,,false\n", exportPageSize-1) {
+		t.Fatalf("unexpected line %q", line)
+	}
+
+	time.Sleep(3 * ts.Config.WriteTimeout)
+	close(lists.release)
+	if line := read(); line != fmt.Sprintf("list %d,,false\n", exportPageSize) {
+		t.Fatalf("unexpected line %q", line)
+	}
+	if line, ok := <-lines; ok {
+		t.Fatalf("unexpected line %q", line)
+	}
+}
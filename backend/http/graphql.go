@@ -0,0 +1,15 @@
+package http
+
+import (
+	"github.com/cmokbel1/todo-app/backend/graphql"
+	"github.com/go-chi/chi"
+)
+
+func (s *Server) registerGraphQLRoutes(r chi.Router) {
+	h := graphql.NewHandler(s.ItemListService, s.Logger)
+	r.Route("/graphql", func(r chi.Router) {
+		r.Use(s.requireAuth)
+		r.Get("/", h.ServeHTTP)
+		r.Post("/", h.ServeHTTP)
+	})
+}
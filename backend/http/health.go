@@ -0,0 +1,127 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi"
+)
+
+// healthCheckTimeout is how long a single check of /readyz may take before it fails.
+const healthCheckTimeout = 2 * time.Second
+
+var errShuttingDown = errors.New("shutting down")
+
+// HealthCheck is a named check of a component the server depends on.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Health is the body of the responses of the health endpoints.
+type Health struct {
+	// Status is "ok" or "fail".
+	Status string `json:"status"`
+	// Checks are the results of the checks of the components by name.
+	Checks map[string]*HealthCheckResult `json:"checks,omitempty"`
+}
+
+// HealthCheckResult is the result of a HealthCheck.
+type HealthCheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// registerHealthRoutes registers the probes of the server outside of /api:
+//   - /healthz succeeds as long as the process serves requests.
+//   - /startupz succeeds once the server is started, see SetStarted.
+//   - /readyz succeeds if the ReadinessChecks and the session store check pass, it fails once the server shuts down
+//     so that load balancers stop sending requests.
+func (s *Server) registerHealthRoutes(r chi.Router) {
+	r.Get("/healthz", s.handleHealthz)
+	r.Get("/startupz", s.handleStartupz)
+	r.Get("/readyz", s.handleReadyz)
+}
+
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	s.json(w, r, http.StatusOK, &Health{Status: healthOK})
+}
+
+func (s *Server) handleStartupz(w http.ResponseWriter, r *http.Request) {
+	if !s.started.Load() {
+		s.json(w, r, http.StatusServiceUnavailable, &Health{Status: healthFail})
+		return
+	}
+	s.json(w, r, http.StatusOK, &Health{Status: healthOK})
+}
+
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	health := &Health{Status: healthOK, Checks: make(map[string]*HealthCheckResult)}
+	checks := append([]HealthCheck{{Name: "session_store", Check: s.checkSessionStore}}, s.ReadinessChecks...)
+	if s.shuttingDown.Load() {
+		checks = append(checks, HealthCheck{Name: "server", Check: func(context.Context) error { return errShuttingDown }})
+	}
+
+	// checks run concurrently so that the response takes as long as the slowest check
+	results := make([]*HealthCheckResult, len(checks))
+	done := make(chan struct{})
+	for i := range checks {
+		go func(i int) {
+			results[i] = runHealthCheck(r.Context(), checks[i])
+			done <- struct{}{}
+		}(i)
+	}
+	for range checks {
+		<-done
+	}
+
+	for i, check := range checks {
+		health.Checks[check.Name] = results[i]
+		if results[i].Status != healthOK {
+			health.Status = healthFail
+		}
+	}
+
+	code := http.StatusOK
+	if health.Status != healthOK {
+		code = http.StatusServiceUnavailable
+	}
+	s.json(w, r, code, health)
+}
+
+func runHealthCheck(ctx context.Context, check HealthCheck) *HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := &HealthCheckResult{Status: healthOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status, result.Error = healthFail, err.Error()
+	}
+	return result
+}
+
+// checkSessionStore looks up a session which does not exist to check that the session store can be reached.
+func (s *Server) checkSessionStore(ctx context.Context) error {
+	if store, ok := s.SessionManager.Store.(scs.CtxStore); ok {
+		_, _, err := store.FindCtx(ctx, "readyz")
+		return err
+	}
+	_, _, err := s.SessionManager.Store.Find("readyz")
+	return err
+}
+
+// SetStarted marks the server as started, /startupz fails until then.
+func (s *Server) SetStarted() {
+	s.started.Store(true)
+}
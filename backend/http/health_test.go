@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthRoutes(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	var dbErr error
+	s.ReadinessChecks = []HealthCheck{{Name: "database", Check: func(context.Context) error { return dbErr }}}
+	h := s.router()
+
+	get := func(path string) (int, *Health) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var health Health
+		if err := json.NewDecoder(w.Body).Decode(&health); err != nil {
+			t.Fatal(err)
+		}
+		return w.Code, &health
+	}
+
+	if code, health := get("/healthz"); code != http.StatusOK || health.Status != healthOK {
+		t.Fatalf("want %d got %d %+v", http.StatusOK, code, health)
+	}
+	if code, _ := get("/startupz"); code != http.StatusServiceUnavailable {
+		t.Fatalf("want %d got %d", http.StatusServiceUnavailable, code)
+	}
+	s.SetStarted()
+	if code, _ := get("/startupz"); code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, code)
+	}
+
+	code, health := get("/readyz")
+	if code != http.StatusOK || health.Status != healthOK {
+		t.Fatalf("want %d got %d %+v", http.StatusOK, code, health)
+	}
+	for _, name := range []string{"database", "session_store"} {
+		if check := health.Checks[name]; check == nil || check.Status != healthOK {
+			t.Fatalf("want %s ok got %+v", name, check)
+		}
+	}
+
+	dbErr = errors.New("connection refused")
+	code, health = get("/readyz")
+	if code != http.StatusServiceUnavailable || health.Checks["database"].Error != dbErr.Error() {
+		t.Fatalf("want %d got %d %+v", http.StatusServiceUnavailable, code, health.Checks["database"])
+	}
+	if check := health.Checks["session_store"]; check.Status != healthOK {
+		t.Fatalf("want session_store ok got %+v", check)
+	}
+
+	// readiness fails as soon as the server shuts down
+	dbErr = nil
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if code, health := get("/readyz"); code != http.StatusServiceUnavailable || health.Checks["server"].Error != errShuttingDown.Error() {
+		t.Fatalf("want %d got %d %+v", http.StatusServiceUnavailable, code, health)
+	}
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, code)
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+type feedResponse struct {
+	URL string `json:"url"`
+}
+
+func (s *Server) registerFeedRoutes(r chi.Router) {
+	r.With(s.requireAuth).Get("/user/feed", s.handleFeedURL)
+	r.With(s.requireAuth).Post("/user/feed", s.handleFeedReset)
+}
+
+// registerICalRoutes registers the routes of iCalendar exports, which are streamed like the other exports.
+func (s *Server) registerICalRoutes(r chi.Router) {
+	r.With(s.requireAuth, s.requireIntParam("id")).Get("/todos/{id}.ics", s.handleTodoListICal)
+	// calendar clients cannot log in, the unguessable token in the URL authenticates the feed
+	r.Get("/feed/{token}.ics", s.handleFeed)
+}
+
+// handleTodoListICal exports a single list as an iCalendar.
+func (s *Server) handleTodoListICal(w http.ResponseWriter, r *http.Request) {
+	list, err := s.ItemListService.FindListByID(r.Context(), r.Context().Value("id").(int))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.export(w, r, format.ICal, list.Name, format.Lists([]*todo.List{list}))
+}
+
+// handleFeedURL returns the URL of the user's calendar feed, the feed token is generated on first use.
+func (s *Server) handleFeedURL(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := s.UserService.FindUserByID(ctx, todo.UserFromContext(ctx).ID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	if user.FeedToken == "" {
+		if user, err = s.UserService.ResetFeedToken(ctx, user.ID); err != nil {
+			s.error(w, r, err)
+			return
+		}
+	}
+	s.json(w, r, http.StatusOK, feedResponse{URL: s.feedURL(user)})
+}
+
+// handleFeedReset replaces the feed token of the user, invalidating the previous feed URL.
+func (s *Server) handleFeedReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user, err := s.UserService.ResetFeedToken(ctx, todo.UserFromContext(ctx).ID)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, feedResponse{URL: s.feedURL(user)})
+}
+
+func (s *Server) handleFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := s.UserService.FindUserByFeedToken(r.Context(), chi.URLParam(r, "token"))
+	if todo.ErrCode(err) == todo.ENOTFOUND {
+		s.error(w, r, todo.Err(todo.ENOTFOUND, "feed not found"))
+		return
+	} else if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	ctx := todo.NewContextWithUser(r.Context(), user)
+	s.export(w, r, format.ICal, "todos", s.userLists(ctx, user.ID))
+}
+
+func (s *Server) feedURL(user *todo.User) string {
+	return s.URL() + "/api/feed/" + user.FeedToken + ".ics"
+}
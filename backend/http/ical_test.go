@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestICal(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	h := s.router()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ctx := todo.NewContextWithUser(context.Background(), user)
+	list := &todo.List{Name: "groceries"}
+	if err := s.ItemListService.CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	} else if err := s.ItemListService.CreateItem(ctx, &todo.Item{ListID: list.ID, Name: "milk"}); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path string, auth bool) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, path, nil)
+		if auth {
+			r.Header.Set("Authorization", "Bearer "+user.APIKey)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	calendar := func(w *httptest.ResponseRecorder) {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+		} else if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/calendar") {
+			t.Fatalf("want calendar got %q", got)
+		} else if body := w.Body.String(); !strings.Contains(body, "BEGIN:VTODO\r\n") || !strings.Contains(body, "SUMMARY:milk\r\n") {
+			t.Fatalf("want the item as a VTODO got %q", body)
+		}
+	}
+	// feedPath returns the path of the feed URL returned by the feed routes
+	feedPath := func(w *httptest.ResponseRecorder) string {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+		}
+		var resp feedResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		u, err := url.Parse(resp.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Path
+	}
+
+	t.Run("List", func(t *testing.T) {
+		calendar(do(http.MethodGet, "/api/todos/"+strconv.Itoa(list.ID)+".ics", true))
+		if w := do(http.MethodGet, "/api/todos/"+strconv.Itoa(list.ID)+".ics", false); w.Code != http.StatusUnauthorized {
+			t.Fatalf("want %d got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("Feed", func(t *testing.T) {
+		path := feedPath(do(http.MethodGet, "/api/user/feed", true))
+		if again := feedPath(do(http.MethodGet, "/api/user/feed", true)); again != path {
+			t.Fatalf("want the same feed %q got %q", path, again)
+		}
+
+		// the token authenticates the feed without a cookie or API key
+		calendar(do(http.MethodGet, path, false))
+		if w := do(http.MethodGet, "/api/feed/unknown.ics", false); w.Code != http.StatusNotFound {
+			t.Fatalf("want %d got %d", http.StatusNotFound, w.Code)
+		}
+
+		// resetting the token invalidates the previous feed
+		reset := feedPath(do(http.MethodPost, "/api/user/feed", true))
+		if reset == path {
+			t.Fatal("want a new feed")
+		} else if w := do(http.MethodGet, path, false); w.Code != http.StatusNotFound {
+			t.Fatalf("want %d got %d", http.StatusNotFound, w.Code)
+		}
+		calendar(do(http.MethodGet, reset, false))
+	})
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDHeader is the header of the ID of a request. The ID of the client is used if it is valid, otherwise a
+// new one is generated, and it is returned in the response.
+const requestIDHeader = "X-Request-Id"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// requestLog collects the fields of the request which are only known once it is handled.
+type requestLog struct {
+	userID int
+}
+
+type requestLogKey struct{}
+
+// logRequests is middleware that logs every request with its ID, user, route, status and latency. The logger with
+// the ID of the request is added to the request context so that every line logged for the request can be correlated,
+// see Server.logger and todo.LoggerFromContext.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		entry := &requestLog{}
+		ctx := context.WithValue(r.Context(), requestLogKey{}, entry)
+		fields := todo.Fields{"request_id": id}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			fields["trace_id"] = sc.TraceID().String()
+		}
+		ctx = todo.NewContextWithLogger(ctx, todo.LoggerWithFields(s.Logger, fields))
+		r = r.WithContext(ctx)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		latency := time.Since(start)
+		fields = todo.Fields{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     status,
+			"latency_ms": float64(latency.Microseconds()) / 1000,
+			"bytes":      ww.BytesWritten(),
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			// the routes of sub-routers end with a slash, which is stripped from every request
+			fields["route"] = strings.TrimSuffix(rctx.RoutePattern(), "/")
+		}
+		if entry.userID != 0 {
+			fields["user_id"] = entry.userID
+		}
+		logger := todo.LoggerWithFields(s.logger(r), fields)
+		logger.Infof("%s %s %d %v", r.Method, r.URL.Path, status, latency)
+	})
+}
+
+// newContextWithRequestUser adds the ID of the authenticated user to the fields of the request logger.
+func newContextWithRequestUser(ctx context.Context, user *todo.User) context.Context {
+	if entry, ok := ctx.Value(requestLogKey{}).(*requestLog); ok {
+		entry.userID = user.ID
+	}
+	if logger := todo.LoggerFromContext(ctx, nil); logger != nil {
+		ctx = todo.NewContextWithLogger(ctx, todo.LoggerWithFields(logger, todo.Fields{"user_id": user.ID}))
+	}
+	return ctx
+}
+
+// logger returns the logger of the request, see logRequests.
+func (s *Server) logger(r *http.Request) todo.Logger {
+	return todo.LoggerFromContext(r.Context(), s.Logger)
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,83 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestServer_logRequests(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewServer()
+	s.Logger = todo.NewStructuredLogger(&buf, todo.LogFormatJSON)
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/todos/999", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	r.Header.Set(requestIDHeader, "client-id")
+	w := httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+	if got := w.Header().Get(requestIDHeader); got != "client-id" {
+		t.Fatalf("want request ID %q got %q", "client-id", got)
+	}
+
+	// the error of the handler and the request are logged with the request ID and the user
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines got %q", lines)
+	}
+	for _, l := range lines {
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &line); err != nil {
+			t.Fatal(err)
+		} else if line["request_id"] != "client-id" || line["user_id"] != float64(user.ID) {
+			t.Fatalf("unexpected line %s", l)
+		}
+	}
+	var line map[string]interface{}
+	json.Unmarshal([]byte(lines[1]), &line)
+	if line["status"] != float64(http.StatusNotFound) || line["route"] != "/api/todos/{id}" || line["latency_ms"] == nil {
+		t.Fatalf("unexpected line %s", lines[1])
+	}
+
+	// the lines logged by handlers carry the request ID
+	buf.Reset()
+	r = httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"name": "fred", "password": "password"}`))
+	r.Header.Set(requestIDHeader, "signup-id")
+	w = httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("want %d got %d", http.StatusCreated, w.Code)
+	}
+	for _, l := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]interface{}
+		if err := json.Unmarshal([]byte(l), &line); err != nil {
+			t.Fatal(err)
+		} else if line["request_id"] != "signup-id" {
+			t.Fatalf("unexpected line %s", l)
+		}
+	}
+
+	// invalid request IDs are replaced
+	r = httptest.NewRequest(http.MethodGet, "/api/build", nil)
+	r.Header.Set(requestIDHeader, "invalid id")
+	w = httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+	if got := w.Header().Get(requestIDHeader); got == "" || got == "invalid id" {
+		t.Fatalf("unexpected request ID %q", got)
+	}
+}
@@ -2,49 +2,100 @@ package http
 
 import (
 	"net/http"
-	_ "net/http/pprof"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func ListenAndServeDebug() error {
-	h := http.NewServeMux()
-	h.Handle("/metrics", promhttp.Handler())
-	return http.ListenAndServe(":6060", h)
-}
+// unmatchedRoute is the route label of requests which matched no route, so that requests for arbitrary paths do not
+// create new series.
+const unmatchedRoute = "unmatched"
+
+var routeLabels = []string{"method", "route", "status"}
 
 var metrics = struct {
-	routeRequestCount *prometheus.CounterVec
-	routeRequestTime  *prometheus.CounterVec
+	requestCount    *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	responseSize    *prometheus.HistogramVec
+	inFlight        prometheus.Gauge
+	logins          *prometheus.CounterVec
 }{
-	routeRequestCount: promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "todo_http_route_request_count",
-		Help: "Total number of requests per route",
-	}, []string{"method", "path"}),
-	routeRequestTime: promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "todo_http_route_request_time",
-		Help: "Total number of request time (seconds) per route",
-	}, []string{"method", "path"}),
+	requestCount: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "todo_http_requests_total",
+		Help: "Total number of requests per route and status class",
+	}, routeLabels),
+	requestDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "todo_http_request_duration_seconds",
+		Help:    "Latency of requests per route and status class",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, routeLabels),
+	responseSize: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "todo_http_response_size_bytes",
+		Help:    "Size of responses per route and status class",
+		Buckets: prometheus.ExponentialBuckets(128, 4, 8),
+	}, routeLabels),
+	inFlight: promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "todo_http_requests_in_flight",
+		Help: "Number of requests being served",
+	}),
+	logins: promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "todo_http_logins_total",
+		Help: "Total number of logins per result, either success or failure",
+	}, []string{"result"}),
 }
 
 func monitorMetrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		metrics.inFlight.Inc()
+		defer metrics.inFlight.Dec()
 
-		next.ServeHTTP(w, r)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
 
-		if strings.HasPrefix(r.URL.Path, "/api") {
-			// route pattern is built as request passes through the chain of handlers
-			// so we have to wait until here to determine the full route.
-			// see: https://github.com/go-chi/chi/issues/150#issuecomment-278850733
-			route := chi.RouteContext(r.Context()).RoutePattern()
-			metrics.routeRequestCount.WithLabelValues(r.Method, route).Inc()
-			metrics.routeRequestTime.WithLabelValues(r.Method, route).Add(float64(time.Since(start).Seconds()))
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		// route pattern is built as request passes through the chain of handlers
+		// so we have to wait until here to determine the full route.
+		// see: https://github.com/go-chi/chi/issues/150#issuecomment-278850733
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" && !(status == http.StatusNotFound && strings.HasSuffix(pattern, "*")) {
+				route = strings.TrimSuffix(pattern, "/")
+			}
 		}
+
+		labels := prometheus.Labels{"method": methodLabel(r.Method), "route": route, "status": statusClass(status)}
+		metrics.requestCount.With(labels).Inc()
+		metrics.requestDuration.With(labels).Observe(time.Since(start).Seconds())
+		metrics.responseSize.With(labels).Observe(float64(ww.BytesWritten()))
 	})
 }
+
+// methodLabel returns the method label of a request, methods the server does not know share the label "other" so that
+// requests with arbitrary methods do not create new series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete,
+		http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	for _, m := range calDAVMethods {
+		if method == m {
+			return method
+		}
+	}
+	return "other"
+}
+
+// statusClass returns the class of a status code, e.g. 2xx.
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
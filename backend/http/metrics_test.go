@@ -0,0 +1,57 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMonitorMetrics(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	h := s.router()
+
+	count := func(route, status string) float64 {
+		return testutil.ToFloat64(metrics.requestCount.With(prometheus.Labels{"method": http.MethodGet, "route": route, "status": status}))
+	}
+	build, unmatched := count("/api/build", "2xx"), count(unmatchedRoute, "4xx")
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/build", nil))
+	for _, path := range []string{"/api/a", "/api/b/c", "/d"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := count("/api/build", "2xx"); got != build+1 {
+		t.Fatalf("want %v requests got %v", build+1, got)
+	}
+	// requests which match no route share a label
+	if got := count(unmatchedRoute, "4xx"); got != unmatched+3 {
+		t.Fatalf("want %v unmatched requests got %v", unmatched+3, got)
+	}
+	// unknown methods share a label
+	other := testutil.ToFloat64(metrics.requestCount.With(prometheus.Labels{"method": "other", "route": unmatchedRoute, "status": "4xx"}))
+	for _, method := range []string{"FOO", "BAR"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/api/build", nil))
+	}
+	if got := testutil.ToFloat64(metrics.requestCount.With(prometheus.Labels{"method": "other", "route": unmatchedRoute, "status": "4xx"})); got != other+2 {
+		t.Fatalf("want %v requests with other methods got %v", other+2, got)
+	}
+	if got := testutil.ToFloat64(metrics.inFlight); got != 0 {
+		t.Fatalf("want no requests in flight got %v", got)
+	}
+
+	failures := testutil.ToFloat64(metrics.logins.WithLabelValues("failure"))
+	r := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(`{"name": "george", "password": "password"}`))
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got := testutil.ToFloat64(metrics.logins.WithLabelValues("failure")); got != failures+1 {
+		t.Fatalf("want %v failed logins got %v", failures+1, got)
+	}
+}
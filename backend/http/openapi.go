@@ -0,0 +1,22 @@
+package http
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// openAPI is the OpenAPI 3 document describing every route below /api. It is kept in sync with the routes by
+// TestOpenAPI.
+//
+//go:embed openapi.json
+var openAPI []byte
+
+func (s *Server) registerOpenAPIRoute(r chi.Router) {
+	r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(openAPI)
+	})
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+)
+
+type openAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIOperation struct {
+	OperationID string `json:"operationId"`
+	Parameters  []struct {
+		Name string `json:"name"`
+		In   string `json:"in"`
+	} `json:"parameters"`
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+var openAPIPathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// TestOpenAPI ensures that the OpenAPI document describes exactly the routes registered below /api.
+func TestOpenAPI(t *testing.T) {
+	var doc openAPIDocument
+	if err := json.Unmarshal(openAPI, &doc); err != nil {
+		t.Fatalf("invalid openapi.json: %v", err)
+	} else if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("want OpenAPI 3 got %q", doc.OpenAPI)
+	}
+
+	documented := make(map[string]bool)
+	operationIDs := make(map[string]string)
+	for path, operations := range doc.Paths {
+		for method, op := range operations {
+			route := strings.ToUpper(method) + " " + path
+			documented[route] = true
+
+			if op.OperationID == "" {
+				t.Errorf("%s: operationId required", route)
+			} else if other, ok := operationIDs[op.OperationID]; ok {
+				t.Errorf("%s: operationId %q is already used by %s", route, op.OperationID, other)
+			}
+			operationIDs[op.OperationID] = route
+
+			if len(op.Responses) == 0 {
+				t.Errorf("%s: responses required", route)
+			}
+
+			params := make(map[string]bool)
+			for _, p := range op.Parameters {
+				if p.In == "path" {
+					params[p.Name] = true
+				}
+			}
+			for _, m := range openAPIPathParam.FindAllStringSubmatch(path, -1) {
+				if !params[m[1]] {
+					t.Errorf("%s: path parameter %q is not documented", route, m[1])
+				}
+			}
+		}
+	}
+
+	s := NewServer()
+	s.SessionManager = NewSessionManager()
+	registered := make(map[string]bool)
+	if err := chi.Walk(s.router(), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !strings.HasPrefix(route, "/api/") {
+			return nil
+		}
+		// trailing slashes are stripped from every request
+		path := strings.TrimSuffix(strings.TrimPrefix(route, "/api"), "/")
+		registered[method+" "+path] = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range sortedKeys(registered) {
+		if !documented[route] {
+			t.Errorf("route %s is not documented", route)
+		}
+	}
+	for _, route := range sortedKeys(documented) {
+		if !registered[route] {
+			t.Errorf("documented route %s is not registered", route)
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// The route groups which are rate limited separately.
+const (
+	// RateLimitDefault limits all routes of the API and CalDAV which are not limited by another group.
+	RateLimitDefault = "default"
+	// RateLimitLogin limits logins, which guess passwords.
+	RateLimitLogin = "login"
+	// RateLimitSignup limits the creation of users.
+	RateLimitSignup = "signup"
+	// RateLimitAPIKey limits the API requests with an Authorization header by IP address before the API key is
+	// looked up, which also counts the requests with invalid keys that guess them.
+	RateLimitAPIKey = "api_key"
+)
+
+// RateLimitGroups are the route groups which can be rate limited.
+var RateLimitGroups = []string{RateLimitDefault, RateLimitLogin, RateLimitSignup, RateLimitAPIKey}
+
+// RateLimit allows a number of requests per client within a sliding window.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// DefaultRateLimits returns the rate limits of the route groups which are not configured.
+func DefaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		RateLimitDefault: {Requests: 60, Window: time.Minute},
+		RateLimitLogin:   {Requests: 10, Window: time.Minute},
+		RateLimitSignup:  {Requests: 5, Window: time.Minute},
+		RateLimitAPIKey:  {Requests: 120, Window: time.Minute},
+	}
+}
+
+// rateLimit is middleware that limits the requests of each client to the route group. Clients are the authenticated
+// user, also if authenticated by API key, and the IP address otherwise, see clientIP.
+func (s *Server) rateLimit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := group + ":ip:" + s.clientIP(r)
+			if user := todo.UserFromContext(r.Context()); user != nil {
+				key = group + ":user:" + strconv.Itoa(user.ID)
+			}
+			if s.allowRequest(w, r, group, key) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// rateLimitAPIKeys is middleware that limits the requests with an Authorization header by IP address, it runs before
+// authentication so that requests with invalid API keys are limited too.
+func (s *Server) rateLimitAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" || s.allowRequest(w, r, RateLimitAPIKey, RateLimitAPIKey+":ip:"+s.clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// allowRequest counts the request of the client key to the route group and reports whether it is within the limit of
+// the group, otherwise it writes the error response. Requests are counted by the RateLimitService, and also once they
+// are rejected, so that a client has to slow down to be served again. Requests are not limited if there is no
+// RateLimitService or no limit for the group.
+func (s *Server) allowRequest(w http.ResponseWriter, r *http.Request, group, key string) bool {
+	limit, ok := s.RateLimits[group]
+	if s.RateLimitService == nil || !ok || limit.Requests <= 0 {
+		return true
+	}
+
+	now := time.Now().UTC()
+	window := now.Truncate(limit.Window)
+	current, previous, err := s.RateLimitService.CountRequest(r.Context(), key, window, limit.Window)
+	if err != nil {
+		// an unavailable store does not take the server down with it
+		s.logger(r).Errorf("failed to count request for rate limit: %v", err)
+		return true
+	}
+
+	// the requests of the previous window count as much as the window still overlaps the sliding window
+	elapsed := now.Sub(window)
+	rate := float64(previous)*float64(limit.Window-elapsed)/float64(limit.Window) + float64(current)
+	remaining := limit.Requests - int(math.Ceil(rate))
+	if remaining < 0 {
+		remaining = 0
+	}
+	reset := int(math.Ceil((limit.Window - elapsed).Seconds()))
+
+	headers := w.Header()
+	headers.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+	headers.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+	headers.Set("RateLimit-Reset", strconv.Itoa(reset))
+	if rate > float64(limit.Requests) {
+		headers.Set("Retry-After", strconv.Itoa(reset))
+		s.error(w, r, todo.Err(todo.ERATELIMITED, "too many requests, retry in %d seconds", reset))
+		return false
+	}
+	return true
+}
+
+// clientIP returns the IP address of the client of the request. The X-Forwarded-For header is only used if the
+// request comes from one of the TrustedProxies, the client is the last address which is not a trusted proxy.
+func (s *Server) clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !s.trustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(addr) == nil {
+			// the header is forged or broken beyond this point
+			break
+		}
+		ip = addr
+		if !s.trustedProxy(addr) {
+			break
+		}
+	}
+	return ip
+}
+
+func (s *Server) trustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range s.TrustedProxies {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
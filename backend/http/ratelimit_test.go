@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestRateLimit(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	s.RateLimitService = inmem.NewRateLimitService()
+	// the window is long enough that the test never crosses into the next one
+	s.RateLimits = map[string]RateLimit{
+		RateLimitDefault: {Requests: 2, Window: 24 * time.Hour},
+		RateLimitSignup:  {Requests: 1, Window: 24 * time.Hour},
+		RateLimitAPIKey:  {Requests: 1, Window: 24 * time.Hour},
+	}
+	h := s.router()
+
+	do := func(r *http.Request, remoteAddr string) *httptest.ResponseRecorder {
+		t.Helper()
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	get := func(remoteAddr string) *httptest.ResponseRecorder {
+		t.Helper()
+		return do(httptest.NewRequest(http.MethodGet, "/api/build", nil), remoteAddr)
+	}
+
+	if w := get("192.0.2.1:1234"); w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Fatalf("unexpected response %d %v", w.Code, w.Header())
+	}
+	get("192.0.2.1:1234")
+	w := get("192.0.2.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("want %d got %d", http.StatusTooManyRequests, w.Code)
+	} else if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("unexpected headers %v", w.Header())
+	}
+	// other clients have their own budget
+	if w := get("192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+	}
+
+	// authenticated users are limited by user rather than IP address
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/build", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	if w := do(r, "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+	}
+
+	// requests with invalid API keys are limited before the key is looked up
+	for i, want := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/api/build", nil)
+		r.Header.Set("Authorization", "Bearer guess"+strconv.Itoa(i))
+		if w := do(r, "192.0.2.5:1234"); w.Code != want {
+			t.Fatalf("want %d got %d", want, w.Code)
+		}
+	}
+
+	// signups are limited per client rather than for everyone
+	signup := func(name, remoteAddr string) int {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(`{"name": "`+name+`", "password": "password"}`))
+		return do(r, remoteAddr).Code
+	}
+	if code := signup("a", "192.0.2.3:1234"); code != http.StatusCreated {
+		t.Fatalf("want %d got %d", http.StatusCreated, code)
+	} else if code := signup("b", "192.0.2.3:1234"); code != http.StatusTooManyRequests {
+		t.Fatalf("want %d got %d", http.StatusTooManyRequests, code)
+	} else if code := signup("c", "192.0.2.4:1234"); code != http.StatusCreated {
+		t.Fatalf("want %d got %d", http.StatusCreated, code)
+	}
+
+	// the health routes are never limited
+	for i := 0; i < 3; i++ {
+		if w := do(httptest.NewRequest(http.MethodGet, "/healthz", nil), "192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("want %d got %d", http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestServer_ClientIP(t *testing.T) {
+	s := NewServer()
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	s.TrustedProxies = []*net.IPNet{proxies}
+
+	tt := []struct {
+		remoteAddr, forwardedFor, want string
+	}{
+		{"192.0.2.1:1234", "", "192.0.2.1"},
+		// untrusted clients cannot spoof their address
+		{"192.0.2.1:1234", "198.51.100.1", "192.0.2.1"},
+		{"10.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		// the client is the last address which is not a trusted proxy
+		{"10.0.0.1:1234", "203.0.113.1, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"10.0.0.1:1234", "10.0.0.3, 10.0.0.2", "10.0.0.3"},
+		{"10.0.0.1:1234", "garbage, 10.0.0.2", "10.0.0.2"},
+	}
+	for _, tc := range tt {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		if tc.forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+		if got := s.clientIP(r); got != tc.want {
+			t.Errorf("%s %q: want %s got %s", tc.remoteAddr, tc.forwardedFor, tc.want, got)
+		}
+	}
+}
@@ -8,34 +8,85 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
 	"github.com/cmokbel1/todo-app/backend/todo"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/go-chi/httprate"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Server struct {
 	ln     net.Listener
 	server *http.Server
+	errc   chan error
+	// redirect redirects plaintext requests to HTTPS if RedirectAddr is set.
+	redirect *http.Server
+	certs    *certReloader
+
+	// ctx is canceled on shutdown to end long-lived streaming responses.
+	ctx    context.Context
+	cancel func()
 
 	// config values
-	Addr               string
-	Domain             string
-	TLS                bool
-	APIKey             string
-	CORSAllowedOrigins string
+	Addr   string
+	Domain string
+	// TLS is set if the server is reached over HTTPS, either because it serves CertFile or because a proxy terminates
+	// TLS in front of it.
+	TLS    bool
+	APIKey string
+	// CORS is the CORS policy of all routes which are not overridden by CORSRoutes.
+	CORS CORSPolicy
+	// CORSRoutes are the CORS policies of the routes under their path prefix, the longest prefix wins.
+	CORSRoutes map[string]CORSPolicy
+	// CertFile and KeyFile are the certificate and key the server serves TLS with, they are reloaded once they change.
+	CertFile string
+	KeyFile  string
+	// ClientCAFile restricts the admin routes to clients with a certificate signed by one of its CAs.
+	ClientCAFile string
+	// RedirectAddr is the address which redirects plaintext requests to HTTPS.
+	RedirectAddr string
+	// HSTSMaxAge is how long browsers only connect over HTTPS once they did, no HSTS header is sent if it is zero.
+	HSTSMaxAge time.Duration
 	// AssetsDirectory is the path to the frontend HTML/CSS/JavaScript
 	AssetsDirectory string
+	// RateLimits are the rate limits of the route groups, see RateLimitGroups. Requests are not limited without a
+	// RateLimitService.
+	RateLimits       map[string]RateLimit
+	RateLimitService todo.RateLimitService
+	// TrustedProxies are the networks of the proxies whose X-Forwarded-For headers are trusted.
+	TrustedProxies []*net.IPNet
+	// AccountDeletionGracePeriod is how long after a user requested the deletion of their account it is deleted.
+	AccountDeletionGracePeriod time.Duration
+	// ShutdownDelay is how long /readyz fails before the server stops accepting connections on shutdown, which gives
+	// load balancers time to drain traffic from the server.
+	ShutdownDelay time.Duration
+	// ReadinessChecks are checked by /readyz in addition to the session store.
+	ReadinessChecks []HealthCheck
+
+	// started and shuttingDown are reported by /startupz and /readyz.
+	started      atomic.Bool
+	shuttingDown atomic.Bool
 
 	Logger todo.Logger
+	// TracerProvider records a span for every request.
+	TracerProvider trace.TracerProvider
 	// LoggerMiddleware is exposed for testing purposes.
 	LoggerMiddleware func(http.Handler) http.Handler
 	SessionManager   *scs.SessionManager
+	listHub          *listHub
 	ItemListService  todo.ItemListService
-	UserService      todo.UserService
+	// CalendarObjectService keeps the names and UIDs of the items CalDAV clients create.
+	CalendarObjectService todo.CalendarObjectService
+	UserService           todo.UserService
+	SyncService           todo.SyncService
+	EventService          todo.EventService
+	WebhookService        todo.WebhookService
+	ActivityService       todo.ActivityService
+	// AccountExportService builds the data exports requested by users.
+	AccountExportService todo.AccountExportService
 }
 
 func NewServer() *Server {
@@ -45,9 +96,18 @@ func NewServer() *Server {
 			WriteTimeout: time.Second * 6,
 			IdleTimeout:  time.Second * 6,
 		},
-		Logger:           todo.NewLogger(),
-		LoggerMiddleware: middleware.Logger,
+		errc:           make(chan error, 1),
+		Logger:         todo.NewLogger(),
+		TracerProvider: trace.NewNoopTracerProvider(),
+		EventService:   todo.NopEventService(),
+		listHub:        newListHub(),
+		RateLimits:     DefaultRateLimits(),
+		CORS:           DefaultCORSPolicy(),
+
+		AccountDeletionGracePeriod: defaultAccountDeletionGracePeriod,
 	}
+	s.LoggerMiddleware = s.logRequests
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	return s
 }
 
@@ -56,24 +116,83 @@ func (s *Server) Listen() (err error) {
 		s.Logger.Warn("API key is empty")
 	}
 
-	if s.CORSAllowedOrigins == "*" {
-		s.Logger.Warn("CORS allowed origins is '*'")
+	if s.CORS.AllowsAnyOrigin() {
+		s.Logger.Warn("CORS allows any origin")
+	}
+
+	s.server.Handler = s.router()
+	if s.CertFile != "" {
+		if s.server.TLSConfig, err = s.tlsConfig(); err != nil {
+			return err
+		}
+	}
+
+	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
+		return err
+	}
+	go serve(s.server, s.ln, s.errc)
+
+	if s.certs != nil {
+		go s.certs.watch(s.ctx, certReloadInterval)
 	}
+	if s.RedirectAddr != "" {
+		if err := s.listenRedirect(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
+// Err receives the error of the server if it stops serving before it is shut down.
+func (s *Server) Err() <-chan error {
+	return s.errc
+}
+
+// router returns the handler of all routes served by the server.
+func (s *Server) router() chi.Router {
 	r := chi.NewRouter()
 	r.Use(middleware.Recoverer)
-	// hard coded rate limit of 60 requests/minute/IP
-	r.Use(httprate.LimitByIP(60, time.Minute))
-	r.Use(s.LoggerMiddleware)
+	r.Use(once(s.traceRequests))
+	r.Use(once(s.LoggerMiddleware))
 	r.Use(s.cors)
-	r.Use(s.sessionMiddleware)
-	r.Use(monitorMetrics)
+	r.Use(s.hsts)
+	r.Use(once(monitorMetrics))
 	r.Use(middleware.StripSlashes)
+	r.Use(s.requestMetadata)
 
+	s.registerHealthRoutes(r)
+	s.registerCalDAVRoutes(r)
 	r.Route("/api", func(r chi.Router) {
-		s.registerTodoRoutes(r)
-		s.registerUserRoutes(r)
-		s.registerBuildRoute(r)
+		// the responses of these routes are streamed, they must not be buffered to save the session
+		r.Group(func(r chi.Router) {
+			r.Use(s.rateLimitAPIKeys)
+			r.Use(s.streamSessionMiddleware)
+			r.Use(s.rateLimit(RateLimitDefault))
+			s.registerEventRoutes(r)
+			s.registerSocketRoutes(r)
+			s.registerExportRoutes(r)
+			s.registerICalRoutes(r)
+			s.registerAccountDownloadRoutes(r)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(s.rateLimitAPIKeys)
+			r.Use(s.sessionMiddleware)
+			r.Use(s.rateLimit(RateLimitDefault))
+			r.Use(s.csrf)
+			s.registerTodoRoutes(r)
+			s.registerUserRoutes(r)
+			s.registerSyncRoutes(r)
+			s.registerWebhookRoutes(r)
+			s.registerActivityRoutes(r)
+			s.registerImportRoutes(r)
+			s.registerFeedRoutes(r)
+			s.registerAccountRoutes(r)
+			s.registerGraphQLRoutes(r)
+			s.registerCSRFRoutes(r)
+			s.registerBuildRoute(r)
+			s.registerOpenAPIRoute(r)
+		})
 	})
 
 	if s.AssetsDirectory != "" {
@@ -82,20 +201,36 @@ func (s *Server) Listen() (err error) {
 	}
 
 	r.NotFound(s.notFound)
-	s.server.Handler = r
-
-	if s.ln, err = net.Listen("tcp", s.Addr); err != nil {
-		return err
-	}
+	return r
+}
 
-	go s.server.Serve(s.ln)
-	return nil
+// Handler returns the handler of all routes without listening, which serves the API with net/http/httptest.
+func (s *Server) Handler() http.Handler {
+	return s.router()
 }
 
-func (s *Server) Shutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
-	defer cancel()
-	return s.server.Shutdown(ctx)
+// Shutdown fails readiness and waits for ShutdownDelay, then it stops accepting connections and waits for pending
+// requests until ctx is done. Requests which are still pending then are cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+	if s.ShutdownDelay > 0 && s.ln != nil {
+		s.Logger.Infof("waiting %v for load balancers to drain traffic", s.ShutdownDelay)
+		select {
+		case <-time.After(s.ShutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+	// streams never finish on their own
+	s.cancel()
+	if s.ln == nil {
+		return nil
+	}
+	if s.redirect != nil {
+		if err := shutdown(ctx, s.redirect); err != nil {
+			return err
+		}
+	}
+	return shutdown(ctx, s.server)
 }
 
 func (s *Server) Scheme() string {
@@ -144,25 +279,26 @@ func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) error(w http.ResponseWriter, r *http.Request, err error) {
 	code, msg := StatusCode(err), todo.ErrMessage(err)
+	logger := s.logger(r)
 	if code == http.StatusInternalServerError {
-		s.Logger.E(err)
+		logger.E(err)
 	} else if code == http.StatusUnauthorized {
-		s.Logger.Warn(msg)
+		logger.Warn(msg)
 		msg = todo.Unauthorized.Message
 	} else {
-		s.Logger.Info(err.Error())
+		logger.Info(err.Error())
 	}
 	s.json(w, r, code, &Error{Message: msg})
 }
 
-func (s *Server) json(w http.ResponseWriter, _ *http.Request, code int, body interface{}) {
+func (s *Server) json(w http.ResponseWriter, r *http.Request, code int, body interface{}) {
 	w.WriteHeader(code)
 	if code == http.StatusNoContent {
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(body); err != nil {
-		s.Logger.E(err)
+		s.logger(r).E(err)
 	}
 }
 
@@ -190,7 +326,11 @@ func (s *Server) requireIntParam(key string) func(next http.Handler) http.Handle
 func (s *Server) requireAPIKey(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if apiKey := r.Header.Get("Todo-Api-Key"); apiKey != s.APIKey {
-			s.Logger.Warnf("invalid API key originating from %v to %v", r.RemoteAddr, r.URL)
+			s.logger(r).Warnf("invalid API key originating from %v to %v", r.RemoteAddr, r.URL)
+			s.error(w, r, todo.NotFound)
+			return
+		} else if !s.hasClientCertificate(r) {
+			s.logger(r).Warnf("missing client certificate originating from %v to %v", r.RemoteAddr, r.URL)
 			s.error(w, r, todo.NotFound)
 			return
 		}
@@ -204,7 +344,7 @@ func (s *Server) requireNoAuth(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		s.Logger.Debug("requireNoAuth user should not be authd to access this route")
+		s.logger(r).Debug("requireNoAuth user should not be authd to access this route")
 		s.error(w, r, todo.Unauthorized)
 		return
 	})
@@ -216,32 +356,36 @@ func (s *Server) requireAuth(next http.Handler) http.Handler {
 			next.ServeHTTP(w, r)
 			return
 		}
-		s.Logger.Debugf("requireAuth user is not authorized")
+		s.logger(r).Debugf("requireAuth user is not authorized")
 		s.error(w, r, todo.Unauthorized)
 		return
 	})
 }
 
-// cors is middleware that enables CORS for localhost domains only. This functionality works only if the configured
-// Domain is localhost.
-func (s *Server) cors(next http.Handler) http.Handler {
+type onceKey struct{ id *int }
+
+// once applies middleware only once per request. chi applies the middlewares of a router again to its NotFound
+// handler, which would count, log and trace requests which match no route twice.
+func once(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	key := onceKey{new(int)}
+	return func(next http.Handler) http.Handler {
+		h := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Context().Value(key) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key, true)))
+		})
+	}
+}
+
+// requestMetadata is middleware that adds the client's IP address and user agent to the request context so that
+// they can be recorded in the activity log.
+func (s *Server) requestMetadata(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		headers := w.Header()
-		if s.Domain == "localhost" {
-			headers.Set("Access-Control-Allow-Origin", s.CORSAllowedOrigins)
-			headers.Set("Access-Control-Allow-Headers", "Content-Type, Origin, Accept")
-			headers.Set("Access-Control-Expose-Headers", "Link")
-			headers.Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, OPTIONS, DELETE")
-			headers.Set("Access-Control-Allow-Credentials", "true")
-		}
-		headers.Set("Vary", "Origin")
-		if r.Method == "OPTIONS" {
-			headers.Add("Vary", "Access-Control-Request-Method")
-			headers.Add("Vary", "Access-Control-Request-Headers")
-			w.WriteHeader(http.StatusNoContent)
-			return
-		}
-		next.ServeHTTP(w, r)
+		ctx := todo.NewContextWithRequestMetadata(r.Context(), todo.RequestMetadata{IP: s.clientIP(r), UserAgent: r.UserAgent()})
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
@@ -268,3 +412,30 @@ func (s *Server) assetsHandler(dir string, allowed ...string) http.HandlerFunc {
 		fs.ServeHTTP(w, r)
 	}
 }
+
+// serve serves on ln, over TLS if the server has a TLS config, and sends the error to errc if it stops serving before
+// it is shut down. Only the first error is sent if errc is shared by several servers.
+func serve(server *http.Server, ln net.Listener, errc chan<- error) {
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ServeTLS(ln, "", "")
+	} else {
+		err = server.Serve(ln)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		select {
+		case errc <- err:
+		default:
+		}
+	}
+}
+
+// shutdown gracefully shuts down the server and closes the connections of requests which are still pending once ctx
+// is done.
+func shutdown(ctx context.Context, server *http.Server) error {
+	if err := server.Shutdown(ctx); err != nil {
+		server.Close()
+		return err
+	}
+	return nil
+}
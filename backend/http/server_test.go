@@ -0,0 +1,56 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer_Shutdown(t *testing.T) {
+	s := NewServer()
+	s.Addr = "localhost:0"
+	s.SessionManager = NewSessionManager()
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(s.URL() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	} else if _, err := http.Get(s.URL() + "/healthz"); err == nil {
+		t.Fatal("want error after shutdown")
+	}
+	// a server which is shut down did not fail
+	select {
+	case err := <-s.Err():
+		t.Fatalf("unexpected error %v", err)
+	default:
+	}
+}
+
+func TestServer_Err(t *testing.T) {
+	s := NewServer()
+	s.Addr = "localhost:0"
+	s.SessionManager = NewSessionManager()
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	s.ln.Close()
+	select {
+	case err := <-s.Err():
+		if err == nil {
+			t.Fatal("want error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("want error once the listener is closed")
+	}
+}
@@ -42,22 +42,60 @@ func (s *Server) DestroySession(ctx context.Context) error {
 // sessionMiddleware populates the context with a user session from either a Cookie or from the owner of the
 // api key specified in the Authorization header.
 func (s *Server) sessionMiddleware(next http.Handler) http.Handler {
-	return s.SessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return s.SessionManager.LoadAndSave(s.authenticate(next))
+}
+
+// streamSessionMiddleware is the sessionMiddleware for long-lived streaming responses. LoadAndSave buffers the
+// entire response so that it can write the session cookie afterwards, which would prevent anything from being
+// streamed. Instead the session is loaded but never saved, so handlers must not modify it.
+func (s *Server) streamSessionMiddleware(next http.Handler) http.Handler {
+	next = s.authenticate(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if cookie, err := r.Cookie(s.SessionManager.Cookie.Name); err == nil {
+			token = cookie.Value
+		}
+
+		ctx, err := s.SessionManager.Load(r.Context(), token)
+		if err != nil {
+			s.error(w, r, todo.Err(todo.EINTERNAL, "failed to load session: %v", err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiKeyAuthKey is the context key which marks requests authenticated by the API key of the Authorization header.
+type apiKeyAuthKey struct{}
+
+// authenticatedByAPIKey reports whether the user of the request was authenticated by the API key of the Authorization
+// header rather than by the session cookie.
+func authenticatedByAPIKey(ctx context.Context) bool {
+	ok, _ := ctx.Value(apiKeyAuthKey{}).(bool)
+	return ok
+}
+
+// authenticate populates the context with the user from a loaded session or from the Authorization header.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 		if user, ok := s.SessionManager.Get(ctx, "user").(todo.User); ok {
-			s.Logger.Debugf("sessionMiddleware found user %q", user.Name)
+			s.logger(r).Debugf("sessionMiddleware found user %q", user.Name)
 			ctx = todo.NewContextWithUser(ctx, &user)
+			ctx = newContextWithRequestUser(ctx, &user)
 		} else if h := r.Header.Get("Authorization"); h != "" {
 			if token := strings.TrimPrefix(h, "Bearer "); token != "" {
-				s.Logger.Debug("beginning token auth")
+				s.logger(r).Debug("beginning token auth")
 				if user, err := s.UserService.FindUserByAPIKey(ctx, token); err != nil {
 					s.error(w, r, todo.Err(todo.EUNAUTHORIZED, "invalid credentials"))
 					return
 				} else {
 					ctx = todo.NewContextWithUser(ctx, user)
+					ctx = newContextWithRequestUser(ctx, user)
+					ctx = context.WithValue(ctx, apiKeyAuthKey{}, true)
 				}
 			}
 		}
 		next.ServeHTTP(w, r.WithContext(ctx))
-	}))
+	})
 }
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// maxMutations is the maximum number of mutations accepted in a single push.
+const maxMutations = 500
+
+type syncPushRequest struct {
+	Mutations []*todo.Mutation `json:"mutations"`
+}
+
+type syncPushResponse struct {
+	Results []*todo.MutationResult `json:"results"`
+}
+
+func (s *Server) registerSyncRoutes(r chi.Router) {
+	r.Route("/sync", func(r chi.Router) {
+		r.Use(s.requireAuth)
+		r.Get("/", s.handleSyncPull)
+		r.Post("/", s.handleSyncPush)
+	})
+}
+
+func (s *Server) handleSyncPull(w http.ResponseWriter, r *http.Request) {
+	changes, err := s.SyncService.FindChanges(r.Context(), r.URL.Query().Get("since"))
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, changes)
+}
+
+func (s *Server) handleSyncPush(w http.ResponseWriter, r *http.Request) {
+	var req syncPushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.error(w, r, todo.Err(todo.EINVALID, "invalid request body: %v", err))
+		return
+	}
+
+	if len(req.Mutations) > maxMutations {
+		s.error(w, r, todo.Err(todo.EINVALID, "at most %d mutations can be pushed at once", maxMutations))
+		return
+	}
+
+	results, err := s.SyncService.PushMutations(r.Context(), req.Mutations)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, syncPushResponse{Results: results})
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// certReloadInterval is how often the certificate files are checked for changes.
+const certReloadInterval = 10 * time.Second
+
+// certReloader serves the certificate of a key pair and reloads it once the files change, so that renewed
+// certificates are picked up without a restart.
+type certReloader struct {
+	certFile, keyFile string
+	logger            todo.Logger
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func newCertReloader(certFile, keyFile string, logger todo.Logger) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// reload loads the key pair, the current certificate is kept if it cannot be loaded.
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert, c.modTime = &cert, modTime
+	return nil
+}
+
+// latestModTime returns the latest modification time of the certificate and key files.
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// watch reloads the key pair whenever its files change until ctx is done.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, err := c.latestModTime()
+		if err != nil {
+			c.logger.Errorf("failed to check certificate: %v", err)
+			continue
+		}
+		c.mu.RLock()
+		changed := modTime.After(c.modTime)
+		c.mu.RUnlock()
+		if !changed {
+			continue
+		}
+		if err := c.reload(); err != nil {
+			c.logger.Errorf("failed to reload certificate: %v", err)
+			continue
+		}
+		c.logger.Infof("reloaded certificate %q", c.certFile)
+	}
+}
+
+// ReloadCertificate loads the certificate and key files again. It is a no-op if the server does not serve TLS.
+func (s *Server) ReloadCertificate() error {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.reload()
+}
+
+// tlsConfig returns the TLS config of the server which serves the certificate of CertFile and KeyFile over HTTP/2
+// and HTTP/1.1. Client certificates signed by ClientCAFile are verified if they are sent, see requireAPIKey.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	var err error
+	if s.certs, err = newCertReloader(s.CertFile, s.KeyFile, s.Logger); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		GetCertificate: s.certs.getCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if s.ClientCAFile != "" {
+		b, err := ioutil.ReadFile(s.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(b) {
+			return nil, errors.New("no certificates found in client CA file")
+		}
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return config, nil
+}
+
+// hasClientCertificate reports whether the client of the request sent a certificate which is signed by ClientCAFile.
+// It is always true if no client CA is configured.
+func (s *Server) hasClientCertificate(r *http.Request) bool {
+	if s.ClientCAFile == "" {
+		return true
+	}
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
+
+// hsts is middleware that tells browsers to only connect over HTTPS for HSTSMaxAge once they connected over HTTPS.
+func (s *Server) hsts(next http.Handler) http.Handler {
+	value := "max-age=" + strconv.Itoa(int(s.HSTSMaxAge/time.Second)) + "; includeSubDomains"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.TLS && s.HSTSMaxAge > 0 {
+			w.Header().Set("Strict-Transport-Security", value)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listenRedirect listens on RedirectAddr and redirects all plaintext requests to the HTTPS URL of the server.
+func (s *Server) listenRedirect() error {
+	ln, err := net.Listen("tcp", s.RedirectAddr)
+	if err != nil {
+		return err
+	}
+	s.redirect = &http.Server{
+		Addr:         ln.Addr().String(),
+		Handler:      http.HandlerFunc(s.redirectToHTTPS),
+		ReadTimeout:  s.server.ReadTimeout,
+		WriteTimeout: s.server.WriteTimeout,
+		IdleTimeout:  s.server.IdleTimeout,
+	}
+	go serve(s.redirect, ln, s.errc)
+	return nil
+}
+
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if port := s.Port(); port != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	// 308 keeps the method and body of the request, unlike 301
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
@@ -0,0 +1,265 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+)
+
+// testCert is a certificate generated for a test and the files of its key pair.
+type testCert struct {
+	cert              *x509.Certificate
+	key               *ecdsa.PrivateKey
+	certFile, keyFile string
+}
+
+// newTestCert generates a certificate for localhost signed by parent, or a self-signed CA if parent is nil, and writes
+// it to dir.
+func newTestCert(t *testing.T, dir, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA, template.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCert{cert: cert, key: key, certFile: filepath.Join(dir, name+".crt"), keyFile: filepath.Join(dir, name+".key")}
+	if err := ioutil.WriteFile(c.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(c.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func newTLSServer(t *testing.T, dir string, ca *testCert) *Server {
+	t.Helper()
+	cert := newTestCert(t, dir, "server", ca)
+	s := NewServer()
+	s.Addr = "localhost:0"
+	s.TLS = true
+	s.CertFile, s.KeyFile = cert.certFile, cert.keyFile
+	s.HSTSMaxAge = time.Hour
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.APIKey = "key"
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return s
+}
+
+func newTLSClient(ca *testCert, certs ...tls.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, Certificates: certs},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	s := newTLSServer(t, dir, ca)
+	s.RedirectAddr = "localhost:0"
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := newTLSClient(ca).Get(s.URL() + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("want HTTP/2 got %s", resp.Proto)
+	} else if got, want := resp.Header.Get("Strict-Transport-Security"), "max-age=3600; includeSubDomains"; got != want {
+		t.Fatalf("want HSTS %q got %q", want, got)
+	}
+
+	// plaintext requests are redirected to HTTPS
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err = client.Post("http://"+s.redirect.Addr+"/api/lists?a=b", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPermanentRedirect {
+		t.Fatalf("want %d got %d", http.StatusPermanentRedirect, resp.StatusCode)
+	}
+	host, _, _ := net.SplitHostPort(s.redirect.Addr)
+	if got, want := resp.Header.Get("Location"), "https://"+net.JoinHostPort(host, strconv.Itoa(s.Port()))+"/api/lists?a=b"; got != want {
+		t.Fatalf("want %q got %q", want, got)
+	}
+}
+
+func TestServer_ReloadCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	s := newTLSServer(t, dir, ca)
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	serial := func() *big.Int {
+		t.Helper()
+		// a new client does not resume the TLS session of a previous one
+		resp, err := newTLSClient(ca).Get(s.URL() + "/healthz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].SerialNumber
+	}
+
+	before := serial()
+	renewed := newTestCert(t, dir, "server", ca)
+	if got := serial(); got.Cmp(before) != 0 {
+		t.Fatal("want certificate to be reloaded only on demand")
+	}
+	if err := s.ReloadCertificate(); err != nil {
+		t.Fatal(err)
+	} else if got := serial(); got.Cmp(renewed.cert.SerialNumber) != 0 {
+		t.Fatalf("want serial %v got %v", renewed.cert.SerialNumber, got)
+	}
+
+	// a broken key pair keeps the current certificate
+	if err := ioutil.WriteFile(renewed.keyFile, []byte("broken"), 0600); err != nil {
+		t.Fatal(err)
+	} else if err := s.ReloadCertificate(); err == nil {
+		t.Fatal("want error reloading a broken key pair")
+	} else if got := serial(); got.Cmp(renewed.cert.SerialNumber) != 0 {
+		t.Fatalf("want serial %v got %v", renewed.cert.SerialNumber, got)
+	}
+}
+
+func TestCertReloader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	cert := newTestCert(t, dir, "server", ca)
+	c, err := newCertReloader(cert.certFile, cert.keyFile, NewServer().Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.watch(ctx, 10*time.Millisecond)
+
+	renewed := newTestCert(t, dir, "server", ca)
+	// the modification time of the files may not change within the resolution of the file system
+	later := time.Now().Add(time.Minute)
+	for _, name := range []string{renewed.certFile, renewed.keyFile} {
+		if err := os.Chtimes(name, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		got, _ := c.getCertificate(nil)
+		if leaf, err := x509.ParseCertificate(got.Certificate[0]); err != nil {
+			t.Fatal(err)
+		} else if leaf.SerialNumber.Cmp(renewed.cert.SerialNumber) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("want certificate to be reloaded once its files change")
+}
+
+func TestServer_ClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil)
+	s := newTLSServer(t, dir, ca)
+	s.ClientCAFile = ca.certFile
+	if err := s.Listen(); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(client *http.Client, path string) int {
+		t.Helper()
+		r, _ := http.NewRequest(http.MethodGet, s.URL()+path, nil)
+		r.Header.Set("Todo-Api-Key", s.APIKey)
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// only the admin routes require a client certificate
+	anonymous := newTLSClient(ca)
+	if code := get(anonymous, "/api/build"); code != http.StatusOK {
+		t.Fatalf("want %d got %d", http.StatusOK, code)
+	} else if code := get(anonymous, "/api/users"); code != http.StatusNotFound {
+		t.Fatalf("want %d got %d", http.StatusNotFound, code)
+	}
+
+	admin := newTestCert(t, dir, "admin", ca)
+	if code := get(newTLSClient(ca, admin.tlsCertificate(t)), "/api/users"); code == http.StatusNotFound {
+		t.Fatalf("want users got %d", code)
+	}
+
+	// certificates of other CAs are not accepted
+	other := newTestCert(t, dir, "other", newTestCert(t, dir, "other-ca", nil))
+	if code := get(newTLSClient(ca, other.tlsCertificate(t)), "/api/users"); code != http.StatusNotFound {
+		t.Fatalf("want %d got %d", http.StatusNotFound, code)
+	}
+}
@@ -19,6 +19,7 @@ func (s *Server) registerTodoRoutes(r chi.Router) {
 			r.Patch("/", s.handleTodoListEdit)
 			r.Delete("/", s.handleTodoListDelete)
 			r.Post("/", s.handleTodoItemCreate)
+			r.Get("/activity", s.handleListActivity)
 			r.Route("/{itemID}", func(r chi.Router) {
 				r.Use(s.requireIntParam("itemID"))
 				r.Get("/", s.handleTodoItemGet)
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+func TestServer_traceRequests(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	s := NewServer()
+	s.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.UserService.CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+
+	// the span continues the trace of the traceparent header
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r := httptest.NewRequest(http.MethodGet, "/api/todos/999", nil)
+	r.Header.Set("Authorization", "Bearer "+user.APIKey)
+	r.Header.Set("traceparent", traceparent)
+	s.router().ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("want 1 span got %d", len(spans))
+	}
+	span := spans[0]
+	if got, want := span.Name(), "GET /api/todos/{id}"; got != want {
+		t.Fatalf("want name %q got %q", want, got)
+	} else if got, want := span.SpanContext().TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Fatalf("want trace %q got %q", want, got)
+	} else if got, want := span.Parent().SpanID().String(), "00f067aa0ba902b7"; got != want {
+		t.Fatalf("want parent %q got %q", want, got)
+	}
+
+	attrs := make(map[string]interface{})
+	for _, attr := range span.Attributes() {
+		attrs[string(attr.Key)] = attr.Value.AsInterface()
+	}
+	if attrs[string(semconv.HTTPRouteKey)] != "/api/todos/{id}" || attrs[string(semconv.HTTPStatusCodeKey)] != int64(http.StatusNotFound) {
+		t.Fatalf("unexpected attributes %v", attrs)
+	}
+
+	// requests without a traceparent header start a new trace
+	s.router().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/build", nil))
+	if spans := recorder.Ended(); len(spans) != 2 || spans[1].Parent().IsValid() || spans[1].Name() != "GET /api/build" {
+		t.Fatalf("unexpected spans %v", spans)
+	}
+}
@@ -4,22 +4,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/cmokbel1/todo-app/backend/todo"
 	"github.com/go-chi/chi"
-	"github.com/go-chi/httprate"
 )
 
 func (s *Server) registerUserRoutes(r chi.Router) {
 	r.With(s.requireAuth).Get("/user", s.handleMe)
 	r.With(s.requireAuth).Get("/user/key", s.handleApiKey)
-	r.With(s.requireNoAuth).Post("/user/login", s.handleLogin)
+	r.With(s.requireNoAuth, s.rateLimit(RateLimitLogin)).Post("/user/login", s.handleLogin)
 	r.With(s.requireAuth).Delete("/user/logout", s.handleLogout)
 
 	r.With(s.requireAPIKey).Get("/users", s.handleUsersIndex)
-	// Limit calls to user create to 5 per minute across the entire instance
-	r.With(httprate.LimitAll(5, time.Minute), s.requireNoAuth).Post("/users", s.handleUserCreate)
+	r.With(s.requireNoAuth, s.rateLimit(RateLimitSignup)).Post("/users", s.handleUserCreate)
 	r.Route("/users/{id}", func(r chi.Router) {
 		r.Use(s.requireIntParam("id"))
 		r.With(s.requireAPIKey).Delete("/", s.handleUserDelete)
@@ -40,7 +37,7 @@ func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleApiKey(w http.ResponseWriter, r *http.Request) {
-	s.Logger.Debug("in handleApiKey")
+	s.logger(r).Debug("in handleApiKey")
 	ctx := r.Context()
 	user := todo.UserFromContext(ctx)
 	latest, err := s.UserService.FindUserByID(ctx, user.ID)
@@ -54,16 +51,18 @@ func (s *Server) handleApiKey(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	var user *todo.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-		s.Logger.E(err)
+		s.logger(r).E(err)
 		s.error(w, r, err)
 		return
 	}
 
 	if err := s.UserService.LoginUser(r.Context(), user); err != nil {
-		s.Logger.E(err)
+		metrics.logins.WithLabelValues("failure").Inc()
+		s.logger(r).E(err)
 		s.error(w, r, fmt.Errorf("invalid credentials: %w", err))
 		return
 	}
+	metrics.logins.WithLabelValues("success").Inc()
 
 	if err := s.CreateSession(r.Context(), user); err != nil {
 		s.error(w, r, err)
@@ -85,7 +84,7 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.DestroySession(ctx); err != nil {
-		s.Logger.Errorf("failed to destroy user session for user %d: %v", user.ID, err)
+		s.logger(r).Errorf("failed to destroy user session for user %d: %v", user.ID, err)
 		s.error(w, r, err)
 		return
 	}
@@ -107,21 +106,21 @@ func (s *Server) handleUserCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.Logger.Infof("received request to create user %q", user.Name)
+	s.logger(r).Infof("received request to create user %q", user.Name)
 	if err := s.UserService.CreateUser(r.Context(), user); err != nil {
 		s.error(w, r, err)
 		return
 	}
-	s.Logger.Infof("created user %q (id = %d)", user.Name, user.ID)
+	s.logger(r).Infof("created user %q (id = %d)", user.Name, user.ID)
 
 	ctx := todo.NewContextWithUser(r.Context(), user)
 	list := &todo.List{Name: "My first list", Completed: false}
 	if err := s.ItemListService.CreateList(ctx, list); err != nil {
-		s.Logger.Errorf("failed to create list for new user %q: %v", user.Name, err)
+		s.logger(r).Errorf("failed to create list for new user %q: %v", user.Name, err)
 		s.error(w, r, err)
 		return
 	}
-	s.Logger.Infof("created default list for user %q (list id = %d)", user.Name, list.ID)
+	s.logger(r).Infof("created default list for user %q (list id = %d)", user.Name, list.ID)
 	user.Password = ""
 	s.json(w, r, http.StatusCreated, user)
 }
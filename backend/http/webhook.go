@@ -0,0 +1,100 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+)
+
+// defaultDeliveryLimit is the number of deliveries returned when no limit is specified.
+const defaultDeliveryLimit = 100
+
+func (s *Server) registerWebhookRoutes(r chi.Router) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(s.requireAuth)
+		r.Get("/", s.handleWebhookIndex)
+		r.Post("/", s.handleWebhookCreate)
+		r.Route("/{id}", func(r chi.Router) {
+			r.Use(s.requireIntParam("id"))
+			r.Get("/", s.handleWebhookGet)
+			r.Delete("/", s.handleWebhookDelete)
+			r.Get("/deliveries", s.handleWebhookDeliveries)
+		})
+	})
+}
+
+func (s *Server) handleWebhookIndex(w http.ResponseWriter, r *http.Request) {
+	user, err := todo.ValidUserFromContext(r.Context())
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	webhooks, err := s.WebhookService.FindWebhooks(r.Context(), todo.WebhookFilter{UserID: &user.ID})
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, webhooks)
+}
+
+func (s *Server) handleWebhookCreate(w http.ResponseWriter, r *http.Request) {
+	webhook := &todo.Webhook{}
+	if err := json.NewDecoder(r.Body).Decode(webhook); err != nil {
+		s.error(w, r, todo.Err(todo.EINVALID, "invalid request body: %v", err))
+		return
+	}
+
+	if err := s.WebhookService.CreateWebhook(r.Context(), webhook); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusCreated, webhook)
+}
+
+func (s *Server) handleWebhookGet(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(int)
+	webhook, err := s.WebhookService.FindWebhookByID(r.Context(), id)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, webhook)
+}
+
+func (s *Server) handleWebhookDelete(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(int)
+	if err := s.WebhookService.DeleteWebhook(r.Context(), id); err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusNoContent, nil)
+}
+
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := r.Context().Value("id").(int)
+	f := todo.WebhookDeliveryFilter{WebhookID: &id, Limit: defaultDeliveryLimit}
+
+	query := r.URL.Query()
+	if status := query.Get("status"); status != "" {
+		f.Status = &status
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			s.error(w, r, todo.Err(todo.EINVALID, "limit must be a positive integer"))
+			return
+		}
+		f.Limit = n
+	}
+
+	deliveries, err := s.WebhookService.FindWebhookDeliveries(r.Context(), f)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	s.json(w, r, http.StatusOK, deliveries)
+}
@@ -0,0 +1,335 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// socketWriteWait is the time allowed to write a message to the peer.
+	socketWriteWait = 10 * time.Second
+	// socketPongWait is the time allowed to read the next pong message from the peer.
+	socketPongWait = 60 * time.Second
+	// socketPingPeriod is how often pings are sent, it must be less than socketPongWait.
+	socketPingPeriod = socketPongWait * 9 / 10
+	// socketMaxMessageSize is the maximum size of a message read from the peer.
+	socketMaxMessageSize = 64 * 1024
+)
+
+// Message types sent and received over a list socket. Events use the todo.Event* types.
+const (
+	socketCreateItem = "item.create"
+	socketUpdateItem = "item.update"
+	socketDeleteItem = "item.delete"
+	socketAck        = "ack"
+	socketError      = "error"
+	socketPresence   = "presence"
+)
+
+// socketMessage is the envelope of every message sent or received over a list socket.
+type socketMessage struct {
+	Type string `json:"type"`
+	// ID correlates a mutation submitted by the client with its ack or error.
+	ID string `json:"id,omitempty"`
+
+	ItemID int              `json:"itemId,omitempty"`
+	Item   *todo.Item       `json:"item,omitempty"`
+	List   *todo.List       `json:"list,omitempty"`
+	Update *todo.ItemUpdate `json:"update,omitempty"`
+
+	// Code and Message are set on errors, Code uses the todo.Error code vocabulary.
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	Viewers []*viewer `json:"viewers,omitempty"`
+}
+
+// viewer is a user with one or more open sockets on a list.
+type viewer struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Connections int    `json:"connections"`
+}
+
+// listHub tracks which sockets are viewing each list. Presence is tracked per server, unlike the changes of a list
+// it is not shared through the EventService, so viewers connected to other replicas are not shown.
+type listHub struct {
+	mu    sync.Mutex
+	lists map[int]map[*listSocket]struct{}
+}
+
+func newListHub() *listHub {
+	return &listHub{lists: make(map[int]map[*listSocket]struct{})}
+}
+
+// listSocket is a single connection to a list.
+type listSocket struct {
+	listID int
+	user   *todo.User
+	// presence is signaled whenever the viewers of the list change.
+	presence chan struct{}
+}
+
+func (h *listHub) join(s *listSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.lists[s.listID] == nil {
+		h.lists[s.listID] = make(map[*listSocket]struct{})
+	}
+	h.lists[s.listID][s] = struct{}{}
+	h.signal(s.listID)
+}
+
+func (h *listHub) leave(s *listSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.lists[s.listID], s)
+	if len(h.lists[s.listID]) == 0 {
+		delete(h.lists, s.listID)
+	}
+	h.signal(s.listID)
+}
+
+// signal notifies every socket on the list that presence changed. The caller must hold the lock.
+func (h *listHub) signal(listID int) {
+	for s := range h.lists[listID] {
+		select {
+		case s.presence <- struct{}{}:
+		default:
+			// a presence update is already pending
+		}
+	}
+}
+
+// viewers returns the users viewing the list ordered by ID.
+func (h *listHub) viewers(listID int) []*viewer {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	byUser := make(map[int]*viewer)
+	for s := range h.lists[listID] {
+		if v, ok := byUser[s.user.ID]; ok {
+			v.Connections++
+		} else {
+			byUser[s.user.ID] = &viewer{ID: s.user.ID, Name: s.user.Name, Connections: 1}
+		}
+	}
+
+	viewers := make([]*viewer, 0, len(byUser))
+	for _, v := range byUser {
+		viewers = append(viewers, v)
+	}
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].ID < viewers[j].ID })
+	return viewers
+}
+
+func (s *Server) registerSocketRoutes(r chi.Router) {
+	r.With(s.requireAuth, s.requireIntParam("id")).Get("/todos/{id}/ws", s.handleListSocket)
+}
+
+// handleListSocket upgrades the request to a WebSocket on which the client receives the changes to the list
+// and its items as well as the presence of other viewers, and can submit item mutations.
+func (s *Server) handleListSocket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := ctx.Value("id").(int)
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	// ensure the list exists and belongs to the user before upgrading
+	if _, err := s.ItemListService.FindListByID(ctx, id); err != nil {
+		s.error(w, r, err)
+		return
+	}
+
+	sub, err := s.EventService.Subscribe(ctx)
+	if err != nil {
+		s.error(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	upgrader := websocket.Upgrader{CheckOrigin: s.checkSocketOrigin}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied to the client
+		s.logger(r).Infof("failed to upgrade list socket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sock := &listSocket{listID: id, user: user, presence: make(chan struct{}, 1)}
+	s.listHub.join(sock)
+	defer s.listHub.leave(sock)
+
+	// reads happen on their own goroutine, all writes happen below. Malformed messages are sent as nil.
+	requests, done, quit := make(chan *socketMessage), make(chan struct{}), make(chan struct{})
+	defer close(quit)
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(socketMaxMessageSize)
+		conn.SetReadDeadline(time.Now().Add(socketPongWait))
+		conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(socketPongWait)) })
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			msg := &socketMessage{}
+			if err := json.Unmarshal(data, msg); err != nil {
+				msg = nil
+			}
+
+			select {
+			case requests <- msg:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	write := func(msg *socketMessage) error {
+		conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+		return conn.WriteJSON(msg)
+	}
+
+	ticker := time.NewTicker(socketPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case <-s.ctx.Done():
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			return
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+			err = conn.WriteMessage(websocket.PingMessage, nil)
+		case <-sock.presence:
+			err = write(&socketMessage{Type: socketPresence, Viewers: s.listHub.viewers(id)})
+		case req := <-requests:
+			err = write(s.handleSocketMessage(r, id, req))
+		case event, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			if msg := listSocketEvent(id, event); msg != nil {
+				err = write(msg)
+			}
+			if event.Type == todo.EventListDeleted && event.List.ID == id {
+				conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "list deleted"))
+				return
+			}
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handleSocketMessage applies an item mutation submitted over the socket and returns the ack or error.
+func (s *Server) handleSocketMessage(r *http.Request, listID int, req *socketMessage) *socketMessage {
+	reply := func(item *todo.Item, err error) *socketMessage {
+		var id string
+		if req != nil {
+			id = req.ID
+		}
+
+		if err != nil {
+			msg := todo.ErrMessage(err)
+			if code := todo.ErrCode(err); code == todo.EINTERNAL {
+				s.logger(r).E(err)
+			} else if code == todo.EUNAUTHORIZED {
+				msg = todo.Unauthorized.Message
+			}
+			return &socketMessage{Type: socketError, ID: id, Code: todo.ErrCode(err), Message: msg}
+		}
+		return &socketMessage{Type: socketAck, ID: id, Item: item}
+	}
+
+	if req == nil {
+		return reply(nil, todo.Err(todo.EINVALID, "malformed message"))
+	}
+
+	ctx := r.Context()
+
+	// items referenced by ID must belong to this list
+	findItem := func() (*todo.Item, error) {
+		item, err := s.ItemListService.FindItemByID(ctx, req.ItemID)
+		if err != nil {
+			return nil, err
+		} else if item.ListID != listID {
+			return nil, todo.Err(todo.ENOTFOUND, "could not find item with id %d in list %d", req.ItemID, listID)
+		}
+		return item, nil
+	}
+
+	switch req.Type {
+	case socketCreateItem:
+		if req.Item == nil {
+			return reply(nil, todo.Err(todo.EINVALID, "item required"))
+		}
+		item := &todo.Item{ListID: listID, Name: req.Item.Name, Completed: req.Item.Completed}
+		return reply(item, s.ItemListService.CreateItem(ctx, item))
+	case socketUpdateItem:
+		if req.Update == nil {
+			return reply(nil, todo.Err(todo.EINVALID, "update required"))
+		} else if _, err := findItem(); err != nil {
+			return reply(nil, err)
+		}
+		return reply(s.ItemListService.UpdateItem(ctx, req.ItemID, *req.Update))
+	case socketDeleteItem:
+		item, err := findItem()
+		if err != nil {
+			return reply(nil, err)
+		}
+		return reply(item, s.ItemListService.DeleteItem(ctx, req.ItemID))
+	}
+
+	return reply(nil, todo.Err(todo.EINVALID, "unknown message type %q", req.Type))
+}
+
+// listSocketEvent converts an event into a socket message if it concerns the list.
+func listSocketEvent(listID int, event todo.Event) *socketMessage {
+	if v := event.List; v != nil && v.ID == listID {
+		return &socketMessage{Type: event.Type, List: v}
+	} else if v := event.Item; v != nil && v.ListID == listID {
+		return &socketMessage{Type: event.Type, Item: v}
+	}
+	return nil
+}
+
+// checkSocketOrigin allows same origin requests and requests from origins the CORS policy of the route allows with
+// credentials. Browsers send the session cookie with every socket, so an origin which may not use it cannot connect.
+func (s *Server) checkSocketOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if u.Host == r.Host {
+		return true
+	}
+	policy := s.corsPolicy(r.URL.Path)
+	return policy.allowsCredentials() && policy.AllowsOrigin(origin)
+}
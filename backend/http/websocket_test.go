@@ -0,0 +1,180 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/gorilla/websocket"
+)
+
+func TestListSocket(t *testing.T) {
+	s := NewServer()
+	s.LoggerMiddleware = func(next http.Handler) http.Handler { return next }
+	s.SessionManager = NewSessionManager()
+	s.UserService = inmem.NewUserService()
+	s.ItemListService = inmem.NewItemListService()
+	events := inmem.NewEventService()
+	s.EventService = events
+	ts := httptest.NewServer(s.router())
+	defer ts.Close()
+
+	ctx := context.Background()
+	user, other := &todo.User{Name: "george", Password: "password"}, &todo.User{Name: "fred", Password: "password"}
+	for _, u := range []*todo.User{user, other} {
+		if err := s.UserService.CreateUser(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	list := &todo.List{Name: "groceries"}
+	if err := s.ItemListService.CreateList(todo.NewContextWithUser(ctx, user), list); err != nil {
+		t.Fatal(err)
+	}
+	path := "/api/todos/" + strconv.Itoa(list.ID) + "/ws"
+
+	dial := func(t *testing.T, path string, header http.Header) (*websocket.Conn, int) {
+		t.Helper()
+		conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+path, header)
+		if err != nil {
+			if resp == nil {
+				t.Fatal(err)
+			}
+			return nil, resp.StatusCode
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn, resp.StatusCode
+	}
+	bearer := func(u *todo.User) http.Header { return http.Header{"Authorization": {"Bearer " + u.APIKey}} }
+
+	// read returns the next message which is not a presence message unless presence is wanted
+	read := func(t *testing.T, conn *websocket.Conn, presence bool) *socketMessage {
+		t.Helper()
+		for {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var msg socketMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatal(err)
+			}
+			if (msg.Type == socketPresence) == presence {
+				return &msg
+			}
+		}
+	}
+	// connections waits for a presence message with the number of connections of the user
+	connections := func(t *testing.T, conn *websocket.Conn, n int) {
+		t.Helper()
+		for {
+			msg := read(t, conn, true)
+			if len(msg.Viewers) == 1 && msg.Viewers[0].ID == user.ID && msg.Viewers[0].Connections == n {
+				return
+			}
+		}
+	}
+
+	t.Run("Auth", func(t *testing.T) {
+		if _, code := dial(t, path, nil); code != http.StatusUnauthorized {
+			t.Fatalf("want %d got %d", http.StatusUnauthorized, code)
+		} else if _, code := dial(t, path, http.Header{"Authorization": {"Bearer invalid"}}); code != http.StatusUnauthorized {
+			t.Fatalf("want %d got %d", http.StatusUnauthorized, code)
+		} else if _, code := dial(t, path, bearer(other)); code == http.StatusSwitchingProtocols {
+			t.Fatal("want the list of another user refused")
+		} else if _, code := dial(t, "/api/todos/999/ws", bearer(user)); code != http.StatusNotFound {
+			t.Fatalf("want %d got %d", http.StatusNotFound, code)
+		}
+	})
+
+	t.Run("Origin", func(t *testing.T) {
+		header := bearer(user)
+		header.Set("Origin", "https://evil.example.com")
+		if _, code := dial(t, path, header); code != http.StatusForbidden {
+			t.Fatalf("want %d got %d", http.StatusForbidden, code)
+		}
+
+		header.Set("Origin", ts.URL)
+		if _, code := dial(t, path, header); code != http.StatusSwitchingProtocols {
+			t.Fatalf("want %d got %d", http.StatusSwitchingProtocols, code)
+		}
+	})
+
+	t.Run("Presence", func(t *testing.T) {
+		first, _ := dial(t, path, bearer(user))
+		connections(t, first, 1)
+
+		second, _ := dial(t, path, bearer(user))
+		connections(t, first, 2)
+		connections(t, second, 2)
+
+		second.Close()
+		connections(t, first, 1)
+	})
+
+	t.Run("Mutations", func(t *testing.T) {
+		conn, _ := dial(t, path, bearer(user))
+		send := func(msg *socketMessage) *socketMessage {
+			t.Helper()
+			if err := conn.WriteJSON(msg); err != nil {
+				t.Fatal(err)
+			}
+			return read(t, conn, false)
+		}
+
+		reply := send(&socketMessage{Type: socketCreateItem, ID: "1", Item: &todo.Item{Name: "milk"}})
+		if reply.Type != socketAck || reply.ID != "1" || reply.Item == nil || reply.Item.ListID != list.ID || reply.Item.Name != "milk" {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+		item := reply.Item
+
+		completed := true
+		reply = send(&socketMessage{Type: socketUpdateItem, ID: "2", ItemID: item.ID, Update: &todo.ItemUpdate{Completed: &completed}})
+		if reply.Type != socketAck || reply.ID != "2" || !reply.Item.Completed {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+
+		reply = send(&socketMessage{Type: socketDeleteItem, ID: "3", ItemID: 999})
+		if reply.Type != socketError || reply.ID != "3" || reply.Code != todo.ENOTFOUND {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+		reply = send(&socketMessage{Type: "item.move", ID: "4"})
+		if reply.Type != socketError || reply.ID != "4" || reply.Code != todo.EINVALID {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("{")); err != nil {
+			t.Fatal(err)
+		} else if reply := read(t, conn, false); reply.Type != socketError || reply.Code != todo.EINVALID {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+
+		reply = send(&socketMessage{Type: socketDeleteItem, ID: "5", ItemID: item.ID})
+		if reply.Type != socketAck || reply.ID != "5" || reply.Item.ID != item.ID {
+			t.Fatalf("unexpected reply %+v", reply)
+		}
+	})
+
+	t.Run("Events", func(t *testing.T) {
+		conn, _ := dial(t, path, bearer(user))
+		connections(t, conn, 1)
+
+		// changes made elsewhere are broadcast, changes of other lists are not
+		events.PublishEvent(user.ID, todo.Event{Type: todo.EventItemCreated, Item: &todo.Item{ID: 7, ListID: list.ID + 1, Name: "other"}})
+		events.PublishEvent(user.ID, todo.Event{Type: todo.EventItemCreated, Item: &todo.Item{ID: 8, ListID: list.ID, Name: "eggs"}})
+		if msg := read(t, conn, false); msg.Type != todo.EventItemCreated || msg.Item.ID != 8 {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+
+		// the socket is closed once the list is deleted
+		events.PublishEvent(user.ID, todo.Event{Type: todo.EventListDeleted, List: list})
+		if msg := read(t, conn, false); msg.Type != todo.EventListDeleted {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			t.Fatalf("want normal closure got %v", err)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// EventBufferSize is the buffer size of the channel of each subscription. Subscribers which fall behind by
+// more than this many events are unsubscribed.
+const EventBufferSize = 32
+
+var _ todo.EventService = (*EventService)(nil)
+
+// EventService is an in-process event bus.
+type EventService struct {
+	mu   sync.Mutex
+	subs map[int]map[*Subscription]struct{}
+}
+
+func NewEventService() *EventService {
+	return &EventService{subs: make(map[int]map[*Subscription]struct{})}
+}
+
+func (s *EventService) PublishEvent(userID int, event todo.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subs[userID] {
+		select {
+		case sub.c <- event:
+		default:
+			// never block publishers on a slow subscriber
+			s.unsubscribe(sub)
+		}
+	}
+}
+
+func (s *EventService) Subscribe(ctx context.Context) (todo.Subscription, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &Subscription{
+		service: s,
+		userID:  user.ID,
+		c:       make(chan todo.Event, EventBufferSize),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subs[user.ID] == nil {
+		s.subs[user.ID] = make(map[*Subscription]struct{})
+	}
+	s.subs[user.ID][sub] = struct{}{}
+
+	return sub, nil
+}
+
+// unsubscribe removes the subscription and closes its channel. The caller must hold the lock.
+func (s *EventService) unsubscribe(sub *Subscription) {
+	subs, ok := s.subs[sub.userID]
+	if !ok {
+		return
+	} else if _, ok := subs[sub]; !ok {
+		return
+	}
+
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(s.subs, sub.userID)
+	}
+	close(sub.c)
+}
+
+var _ todo.Subscription = (*Subscription)(nil)
+
+// Subscription is a subscription to the events of a single user.
+type Subscription struct {
+	service *EventService
+	userID  int
+	c       chan todo.Event
+}
+
+func (s *Subscription) C() <-chan todo.Event { return s.c }
+
+func (s *Subscription) Close() error {
+	s.service.mu.Lock()
+	defer s.service.mu.Unlock()
+	s.service.unsubscribe(s)
+	return nil
+}
@@ -0,0 +1,79 @@
+package inmem_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestEventService(t *testing.T) {
+	newContext := func(id int) context.Context {
+		return todo.NewContextWithUser(context.Background(), &todo.User{ID: id, Name: "user"})
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		s := inmem.NewEventService()
+		sub, err := s.Subscribe(newContext(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		other, err := s.Subscribe(newContext(2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer other.Close()
+
+		s.PublishEvent(1, todo.Event{Type: todo.EventListCreated, List: &todo.List{ID: 5}})
+
+		select {
+		case got := <-sub.C():
+			if got.Type != todo.EventListCreated || got.List.ID != 5 {
+				t.Fatalf("unexpected event %#v", got)
+			}
+		default:
+			t.Fatal("want event got none")
+		}
+
+		select {
+		case got := <-other.C():
+			t.Fatalf("want no event for other user got %#v", got)
+		default:
+		}
+	})
+
+	t.Run("SlowSubscriberClosed", func(t *testing.T) {
+		s := inmem.NewEventService()
+		sub, err := s.Subscribe(newContext(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i <= inmem.EventBufferSize; i++ {
+			s.PublishEvent(1, todo.Event{Type: todo.EventItemUpdated})
+		}
+
+		n := 0
+		for range sub.C() {
+			n++
+		}
+		if n != inmem.EventBufferSize {
+			t.Fatalf("want %d buffered events got %d", inmem.EventBufferSize, n)
+		}
+
+		// closing an already removed subscription is a no-op
+		if err := sub.Close(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("ErrUnauthorized", func(t *testing.T) {
+		if _, got := inmem.NewEventService().Subscribe(context.Background()); !errors.Is(got, todo.Unauthorized) {
+			t.Fatalf("want error %v got %v", todo.Unauthorized, got)
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package inmem
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.RateLimitService = (*RateLimitService)(nil)
+
+// RateLimitService counts requests in memory, so every server enforces its own budget.
+type RateLimitService struct {
+	mu        sync.Mutex
+	windows   map[rateLimitWindow]*rateLimitCount
+	lastEvict time.Time
+}
+
+type rateLimitWindow struct {
+	key   string
+	start time.Time
+}
+
+type rateLimitCount struct {
+	n int
+	// expires is when the window no longer counts as the previous window of a request.
+	expires time.Time
+}
+
+func NewRateLimitService() *RateLimitService {
+	return &RateLimitService{windows: make(map[rateLimitWindow]*rateLimitCount)}
+}
+
+func (s *RateLimitService) CountRequest(ctx context.Context, key string, window time.Time, length time.Duration) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.evict(window)
+
+	current := s.windows[rateLimitWindow{key, window}]
+	if current == nil {
+		current = &rateLimitCount{expires: window.Add(2 * length)}
+		s.windows[rateLimitWindow{key, window}] = current
+	}
+	current.n++
+
+	var previous int
+	if count := s.windows[rateLimitWindow{key, window.Add(-length)}]; count != nil {
+		previous = count.n
+	}
+	return current.n, previous, nil
+}
+
+// evict removes the expired windows at most once a minute.
+func (s *RateLimitService) evict(now time.Time) {
+	if now.Sub(s.lastEvict) < time.Minute {
+		return
+	}
+	s.lastEvict = now
+	for window, count := range s.windows {
+		if !count.expires.After(now) {
+			delete(s.windows, window)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package inmem_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+)
+
+func TestRateLimitService_CountRequest(t *testing.T) {
+	svc := inmem.NewRateLimitService()
+	ctx := context.Background()
+	window := time.Now().UTC().Truncate(time.Minute)
+
+	for i := 1; i <= 3; i++ {
+		if current, previous, err := svc.CountRequest(ctx, "ip:127.0.0.1", window.Add(-time.Minute), time.Minute); err != nil {
+			t.Fatal(err)
+		} else if current != i || previous != 0 {
+			t.Fatalf("want %d/0 requests got %d/%d", i, current, previous)
+		}
+	}
+
+	if current, previous, err := svc.CountRequest(ctx, "ip:127.0.0.1", window, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if current != 1 || previous != 3 {
+		t.Fatalf("want 1/3 requests got %d/%d", current, previous)
+	}
+	if current, previous, err := svc.CountRequest(ctx, "user:1", window, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if current != 1 || previous != 0 {
+		t.Fatalf("want 1/0 requests got %d/%d", current, previous)
+	}
+
+	// windows are forgotten once they no longer count as the previous window
+	if _, previous, err := svc.CountRequest(ctx, "ip:127.0.0.1", window.Add(2*time.Minute), time.Minute); err != nil {
+		t.Fatal(err)
+	} else if previous != 0 {
+		t.Fatalf("want 0 previous requests got %d", previous)
+	}
+}
@@ -0,0 +1,349 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/format"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.ItemListService = (*ItemListService)(nil)
+
+// ItemListService keeps Lists and Items in memory. It is intended for tests and tools and neither records changes
+// nor activity nor publishes events.
+type ItemListService struct {
+	mu     sync.Mutex
+	lists  map[int]*todo.List
+	items  map[int]*todo.Item
+	nextID int
+
+	// Now returns the current time, it is overridden in tests.
+	Now func() time.Time
+}
+
+func NewItemListService() *ItemListService {
+	return &ItemListService{
+		lists: make(map[int]*todo.List),
+		items: make(map[int]*todo.Item),
+		Now:   func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) },
+	}
+}
+
+func (s *ItemListService) FindListByID(ctx context.Context, id int) (*todo.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findListByID(ctx, id)
+}
+
+func (s *ItemListService) findListByID(ctx context.Context, id int) (*todo.List, error) {
+	lists, err := s.findLists(ctx, todo.ListFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(lists) == 0 {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find list with id %d", id)
+	}
+	return lists[0], nil
+}
+
+func (s *ItemListService) FindLists(ctx context.Context, f todo.ListFilter) ([]*todo.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findLists(ctx, f)
+}
+
+func (s *ItemListService) findLists(ctx context.Context, f todo.ListFilter) ([]*todo.List, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := make([]*todo.List, 0)
+	for _, l := range s.lists {
+		if (f.ID != nil && l.ID != *f.ID) ||
+			(f.IDs != nil && !containsInt(f.IDs, l.ID)) ||
+			(f.UserID != nil && l.UserID != *f.UserID) ||
+			(f.Name != nil && l.Name != *f.Name) ||
+			(f.Completed != nil && l.Completed != *f.Completed) ||
+			l.ID <= f.AfterID {
+			continue
+		} else if l.UserID != user.ID {
+			return nil, todo.Unauthorized
+		}
+
+		list := *l
+		list.Items = make([]*todo.Item, 0)
+		if !f.ExcludeItems {
+			list.Items, _ = s.findItems(ctx, todo.ItemFilter{ListID: &l.ID})
+		}
+		lists = append(lists, &list)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+	lo, hi := bounds(len(lists), f.Offset, f.Limit)
+	return lists[lo:hi], nil
+}
+
+func (s *ItemListService) CreateList(ctx context.Context, list *todo.List) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createList(ctx, list)
+}
+
+func (s *ItemListService) createList(ctx context.Context, list *todo.List) error {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	list.CreatedAt = s.Now()
+	list.UpdatedAt = list.CreatedAt
+	list.UserID = user.ID
+	list.Items = make([]*todo.Item, 0)
+	if err := list.Validate(); err != nil {
+		return err
+	}
+
+	s.nextID++
+	list.ID = s.nextID
+	other := *list
+	other.Items = nil
+	s.lists[list.ID] = &other
+	return nil
+}
+
+func (s *ItemListService) UpdateList(ctx context.Context, id int, upd todo.ListUpdate) (*todo.List, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list, err := s.findListByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	list.UpdatedAt = s.Now()
+	if v := upd.Name; v != nil {
+		list.Name = *v
+	}
+	if v := upd.Completed; v != nil {
+		list.Completed = *v
+	}
+	if err := list.Validate(); err != nil {
+		return nil, err
+	}
+
+	other := *list
+	other.Items = nil
+	s.lists[id] = &other
+	return list, nil
+}
+
+func (s *ItemListService) DeleteList(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return err
+	} else if id <= 0 {
+		return todo.Err(todo.EINVALID, "invalid id")
+	}
+
+	if l, ok := s.lists[id]; !ok || l.UserID != user.ID {
+		return todo.Err(todo.ENOTFOUND, "could not delete list with id %v", id)
+	}
+	delete(s.lists, id)
+	for itemID, item := range s.items {
+		if item.ListID == id {
+			delete(s.items, itemID)
+		}
+	}
+	return nil
+}
+
+func (s *ItemListService) ImportLists(ctx context.Context, lists []*todo.List) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := todo.ValidUserFromContext(ctx); err != nil {
+		return err
+	}
+
+	// validate everything first so that either all lists are created or none of them
+	if err := format.ValidateLists(lists); err != nil {
+		return err
+	}
+
+	for _, list := range lists {
+		items := list.Items
+		createdAt, updatedAt := list.CreatedAt, list.UpdatedAt
+		if err := s.createList(ctx, list); err != nil {
+			return err
+		}
+		restoreTimestamps(createdAt, updatedAt, &s.lists[list.ID].CreatedAt, &s.lists[list.ID].UpdatedAt)
+		list.CreatedAt, list.UpdatedAt = s.lists[list.ID].CreatedAt, s.lists[list.ID].UpdatedAt
+
+		for _, item := range items {
+			item.ListID = list.ID
+			createdAt, updatedAt := item.CreatedAt, item.UpdatedAt
+			if err := s.createItem(ctx, item); err != nil {
+				return err
+			}
+			restoreTimestamps(createdAt, updatedAt, &s.items[item.ID].CreatedAt, &s.items[item.ID].UpdatedAt)
+			item.CreatedAt, item.UpdatedAt = s.items[item.ID].CreatedAt, s.items[item.ID].UpdatedAt
+			list.Items = append(list.Items, item)
+		}
+	}
+	return nil
+}
+
+// restoreTimestamps overwrites the timestamps of a newly imported entity with the non-zero timestamps of the import.
+func restoreTimestamps(createdAt, updatedAt time.Time, dstCreatedAt, dstUpdatedAt *time.Time) {
+	if !createdAt.IsZero() {
+		*dstCreatedAt = createdAt
+	}
+	if !updatedAt.IsZero() {
+		*dstUpdatedAt = updatedAt
+	}
+}
+
+func (s *ItemListService) FindItemByID(ctx context.Context, id int) (*todo.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findItemByID(ctx, id)
+}
+
+func (s *ItemListService) findItemByID(ctx context.Context, id int) (*todo.Item, error) {
+	items, err := s.findItems(ctx, todo.ItemFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(items) == 0 {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find item with id %d", id)
+	}
+	return items[0], nil
+}
+
+func (s *ItemListService) FindItems(ctx context.Context, f todo.ItemFilter) ([]*todo.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findItems(ctx, f)
+}
+
+func (s *ItemListService) findItems(ctx context.Context, f todo.ItemFilter) ([]*todo.Item, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*todo.Item, 0)
+	for _, i := range s.items {
+		if (f.ID != nil && i.ID != *f.ID) ||
+			(f.IDs != nil && !containsInt(f.IDs, i.ID)) ||
+			(f.UserID != nil && i.UserID != *f.UserID) ||
+			(f.ListID != nil && i.ListID != *f.ListID) ||
+			(f.ListIDs != nil && !containsInt(f.ListIDs, i.ListID)) ||
+			(f.Name != nil && i.Name != *f.Name) ||
+			(f.Completed != nil && i.Completed != *f.Completed) {
+			continue
+		} else if i.UserID != user.ID {
+			return nil, todo.Err(todo.EUNAUTHORIZED, "user %d cannot read item %d", user.ID, i.ID)
+		}
+
+		item := *i
+		items = append(items, &item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	lo, hi := bounds(len(items), f.Offset, f.Limit)
+	return items[lo:hi], nil
+}
+
+func (s *ItemListService) CreateItem(ctx context.Context, item *todo.Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createItem(ctx, item)
+}
+
+func (s *ItemListService) createItem(ctx context.Context, item *todo.Item) error {
+	list, err := s.findListByID(ctx, item.ListID)
+	if err != nil {
+		return err
+	}
+
+	item.UserID = list.UserID
+	item.CreatedAt = s.Now()
+	item.UpdatedAt = item.CreatedAt
+	if err := item.Validate(); err != nil {
+		return err
+	}
+
+	s.nextID++
+	item.ID = s.nextID
+	other := *item
+	s.items[item.ID] = &other
+	return nil
+}
+
+func (s *ItemListService) UpdateItem(ctx context.Context, id int, upd todo.ItemUpdate) (*todo.Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.findItemByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	item.UpdatedAt = s.Now()
+	if v := upd.Name; v != nil {
+		item.Name = *v
+	}
+	if v := upd.Completed; v != nil {
+		item.Completed = *v
+	}
+	if err := item.Validate(); err != nil {
+		return nil, err
+	}
+
+	other := *item
+	s.items[id] = &other
+	return item, nil
+}
+
+func (s *ItemListService) DeleteItem(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return err
+	} else if id <= 0 {
+		return todo.Err(todo.EINVALID, "invalid id")
+	}
+
+	if i, ok := s.items[id]; !ok || i.UserID != user.ID {
+		return todo.Err(todo.ENOTFOUND, "could not delete item with id %v", id)
+	}
+	delete(s.items, id)
+	return nil
+}
+
+// bounds returns the range of a sorted result of n entries to which the offset and limit of a filter restrict it.
+func bounds(n, offset, limit int) (lo, hi int) {
+	if offset > n {
+		offset = n
+	}
+	lo, hi = offset, n
+	if limit > 0 && lo+limit < hi {
+		hi = lo + limit
+	}
+	return lo, hi
+}
+
+func containsInt(values []int, v int) bool {
+	for _, other := range values {
+		if other == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package inmem_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestItemListService(t *testing.T) {
+	newContext := func(id int) context.Context {
+		return todo.NewContextWithUser(context.Background(), &todo.User{ID: id, Name: "user"})
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		s := inmem.NewItemListService()
+		ctx := newContext(1)
+
+		list := &todo.List{Name: "groceries"}
+		if err := s.CreateList(ctx, list); err != nil {
+			t.Fatal(err)
+		} else if list.ID == 0 || list.UserID != 1 {
+			t.Fatalf("unexpected list %+v", list)
+		}
+
+		item := &todo.Item{ListID: list.ID, Name: "milk"}
+		if err := s.CreateItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+
+		completed := true
+		if item, err := s.UpdateItem(ctx, item.ID, todo.ItemUpdate{Completed: &completed}); err != nil {
+			t.Fatal(err)
+		} else if !item.Completed || item.Name != "milk" {
+			t.Fatalf("unexpected item %+v", item)
+		}
+
+		if got, err := s.FindListByID(ctx, list.ID); err != nil {
+			t.Fatal(err)
+		} else if len(got.Items) != 1 || !got.Items[0].Completed {
+			t.Fatalf("unexpected items %v", got.Items)
+		}
+
+		// the returned items are copies
+		item.Name = "changed"
+		if got, err := s.FindItemByID(ctx, item.ID); err != nil {
+			t.Fatal(err)
+		} else if got.Name != "milk" {
+			t.Fatalf("want milk got %q", got.Name)
+		}
+
+		if err := s.DeleteList(ctx, list.ID); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.FindItemByID(ctx, item.ID); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+
+	t.Run("OtherUser", func(t *testing.T) {
+		s := inmem.NewItemListService()
+		list := &todo.List{Name: "groceries"}
+		if err := s.CreateList(newContext(1), list); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := newContext(2)
+		if _, err := s.FindListByID(ctx, list.ID); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		} else if err := s.CreateItem(ctx, &todo.Item{ListID: list.ID, Name: "milk"}); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		} else if err := s.DeleteList(ctx, list.ID); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+
+		userID := 2
+		if lists, err := s.FindLists(ctx, todo.ListFilter{UserID: &userID}); err != nil {
+			t.Fatal(err)
+		} else if len(lists) != 0 {
+			t.Fatalf("want no lists got %d", len(lists))
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package inmem
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/crypto"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.UserService = (*UserService)(nil)
+
+// UserService keeps Users in memory. It is intended for tests and tools, it does not record activity and deleting
+// a User does not delete their Lists.
+type UserService struct {
+	mu     sync.Mutex
+	users  map[int]*todo.User
+	nextID int
+
+	// Now returns the current time, it is overridden in tests.
+	Now func() time.Time
+}
+
+func NewUserService() *UserService {
+	return &UserService{
+		users: make(map[int]*todo.User),
+		Now:   func() time.Time { return time.Now().UTC().Truncate(time.Microsecond) },
+	}
+}
+
+func (s *UserService) LoginUser(ctx context.Context, user *todo.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.APIKey != "" {
+		other, err := s.findUser(todo.UserFilter{APIKey: &user.APIKey})
+		if err != nil {
+			return todo.Err(todo.EUNAUTHORIZED, "invalid api key")
+		}
+		*user = *other
+		return nil
+	}
+
+	if user.Name == "" || user.Password == "" {
+		return todo.Err(todo.EINVALID, "name and password required")
+	}
+
+	other, err := s.findUser(todo.UserFilter{Name: &user.Name})
+	if err != nil {
+		return err
+	}
+
+	if matches, err := crypto.ComparePasswordAndHash(user.Password, other.Password); err != nil {
+		return todo.Err(todo.EUNAUTHORIZED, "%s", err)
+	} else if !matches {
+		return todo.Err(todo.EUNAUTHORIZED, "password mismatch for user %q", user.Name)
+	}
+
+	*user = *other
+	return nil
+}
+
+func (s *UserService) CreateUser(ctx context.Context, user *todo.User) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.CreatedAt = s.Now()
+	user.UpdatedAt = user.CreatedAt
+	if user.Name == "" {
+		return todo.Err(todo.EINVALID, "name is required")
+	}
+	if user.Password == "" {
+		return todo.Err(todo.EINVALID, "password is required")
+	}
+	for _, other := range s.users {
+		if other.Name == user.Name {
+			return todo.Err(todo.ECONFLICT, "name is already taken")
+		} else if user.Email != nil && other.Email != nil && *other.Email == *user.Email {
+			return todo.Err(todo.ECONFLICT, "email is already taken")
+		}
+	}
+
+	if user.Password, err = crypto.CreateHash(user.Password); err != nil {
+		return err
+	}
+	user.APIKey = crypto.RandomString()
+
+	s.nextID++
+	user.ID = s.nextID
+	other := *user
+	s.users[user.ID] = &other
+	return nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return todo.Err(todo.ENOTFOUND, "could not find user with id %d", id)
+	}
+	delete(s.users, id)
+	return nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, id int, upd todo.UserUpdate) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := upd.Validate(); err != nil {
+		return nil, err
+	}
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range s.users {
+		if other.ID == id {
+			continue
+		} else if upd.Name != nil && other.Name == *upd.Name {
+			return nil, todo.Err(todo.ECONFLICT, "name is already taken")
+		} else if upd.Email != nil && other.Email != nil && *other.Email == *upd.Email {
+			return nil, todo.Err(todo.ECONFLICT, "email is already taken")
+		}
+	}
+
+	if v := upd.Name; v != nil {
+		user.Name = *v
+	}
+	if v := upd.Email; v != nil {
+		user.Email = v
+	}
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) FindUserByID(ctx context.Context, id int) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(todo.UserFilter{ID: &id})
+}
+
+func (s *UserService) FindUserByName(ctx context.Context, name string) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(todo.UserFilter{Name: &name})
+}
+
+func (s *UserService) FindUserByAPIKey(ctx context.Context, apiKey string) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(todo.UserFilter{APIKey: &apiKey})
+}
+
+func (s *UserService) FindUserByFeedToken(ctx context.Context, token string) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUser(todo.UserFilter{FeedToken: &token})
+}
+
+func (s *UserService) ResetFeedToken(ctx context.Context, id int) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	user.FeedToken = crypto.RandomToken()
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) ResetPassword(ctx context.Context, id int, password string) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if password == "" {
+		return nil, todo.Err(todo.EINVALID, "password is required")
+	}
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	if user.Password, err = crypto.CreateHash(password); err != nil {
+		return nil, err
+	}
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) RotateAPIKey(ctx context.Context, id int) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	user.APIKey = crypto.RandomString()
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) FindUsers(ctx context.Context, f todo.UserFilter) ([]*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findUsers(f), nil
+}
+
+func (s *UserService) ScheduleUserDeletion(ctx context.Context, id int, password string, at time.Time) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+	if matches, err := crypto.ComparePasswordAndHash(password, user.Password); err != nil {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "%s", err)
+	} else if !matches {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "password mismatch for user %q", user.Name)
+	}
+
+	user.DeleteAt = &at
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) CancelUserDeletion(ctx context.Context, id int) (*todo.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, err := s.findUser(todo.UserFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if user.DeleteAt == nil {
+		return nil, todo.Err(todo.ECONFLICT, "the deletion of user %d is not scheduled", id)
+	}
+
+	user.DeleteAt = nil
+	user.UpdatedAt = s.Now()
+	return s.save(user), nil
+}
+
+func (s *UserService) DeleteScheduledUsers(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, now := 0, s.Now()
+	for id, user := range s.users {
+		if user.DeleteAt != nil && !user.DeleteAt.After(now) {
+			delete(s.users, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// save stores a copy of the user and returns it. The caller must hold the lock.
+func (s *UserService) save(user *todo.User) *todo.User {
+	other := *user
+	s.users[user.ID] = &other
+	return user
+}
+
+// findUser returns a copy of the single user matching the filter. The caller must hold the lock.
+func (s *UserService) findUser(f todo.UserFilter) (*todo.User, error) {
+	if users := s.findUsers(f); len(users) > 0 {
+		return users[0], nil
+	}
+	return nil, todo.Err(todo.ENOTFOUND, "user not found")
+}
+
+// findUsers returns copies of the users matching the filter. The caller must hold the lock.
+func (s *UserService) findUsers(f todo.UserFilter) []*todo.User {
+	users := make([]*todo.User, 0)
+	for _, u := range s.users {
+		if (f.ID != nil && u.ID != *f.ID) ||
+			(f.Name != nil && u.Name != *f.Name) ||
+			(f.Email != nil && (u.Email == nil || *u.Email != *f.Email)) ||
+			(f.APIKey != nil && u.APIKey != *f.APIKey) ||
+			(f.FeedToken != nil && u.FeedToken != *f.FeedToken) {
+			continue
+		}
+		user := *u
+		users = append(users, &user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	lo, hi := bounds(len(users), f.Offset, f.Limit)
+	return users[lo:hi]
+}
@@ -0,0 +1,38 @@
+package inmem_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestUserService(t *testing.T) {
+	s := inmem.NewUserService()
+	ctx := context.Background()
+
+	user := &todo.User{Name: "george", Password: "password"}
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	} else if err := s.CreateUser(ctx, &todo.User{Name: "george", Password: "other"}); todo.ErrCode(err) != todo.ECONFLICT {
+		t.Fatalf("want conflict got %v", err)
+	}
+
+	login := &todo.User{Name: "george", Password: "password"}
+	if err := s.LoginUser(ctx, login); err != nil {
+		t.Fatal(err)
+	} else if login.ID != user.ID || login.APIKey == "" {
+		t.Fatalf("unexpected user %+v", login)
+	}
+
+	if err := s.LoginUser(ctx, &todo.User{Name: "george", Password: "wrong"}); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+		t.Fatalf("want unauthorized got %v", err)
+	}
+
+	if got, err := s.FindUserByAPIKey(ctx, login.APIKey); err != nil {
+		t.Fatal(err)
+	} else if got.ID != user.ID {
+		t.Fatalf("want user %d got %d", user.ID, got.ID)
+	}
+}
@@ -0,0 +1,312 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.AccountExportService = (*AccountExportService)(nil)
+
+func NewAccountExportService(db *DB) *AccountExportService {
+	return &AccountExportService{db: db}
+}
+
+type AccountExportService struct {
+	db *DB
+}
+
+func (svc *AccountExportService) CreateAccountExport(ctx context.Context) (*todo.AccountExport, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export, err := createAccountExport(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	return export, tx.Commit()
+}
+
+func (svc *AccountExportService) FindAccountExportByID(ctx context.Context, id int) (*todo.AccountExport, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	export, err := findAccountExportByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return export, tx.Commit()
+}
+
+func (svc *AccountExportService) DownloadAccountExport(ctx context.Context, id int) ([]byte, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	archive, err := downloadAccountExport(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return archive, tx.Commit()
+}
+
+func (svc *AccountExportService) MarkAccountExportDownloaded(ctx context.Context, id int) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := markAccountExportDownloaded(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (svc *AccountExportService) ClaimAccountExports(ctx context.Context, n int, lease time.Duration) ([]*todo.AccountExport, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	exports, err := claimAccountExports(ctx, tx, n, lease)
+	if err != nil {
+		return nil, err
+	}
+	return exports, tx.Commit()
+}
+
+func (svc *AccountExportService) UpdateAccountExport(ctx context.Context, id int, upd todo.AccountExportUpdate) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateAccountExport(ctx, tx, id, upd); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func createAccountExport(ctx context.Context, tx *Tx) (*todo.AccountExport, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := todo.ExportPending
+	if pending, err := findAccountExports(ctx, tx, accountExportFilter{UserID: &user.ID, Status: &status}); err != nil {
+		return nil, err
+	} else if len(pending) > 0 {
+		return nil, todo.Err(todo.ECONFLICT, "an export is already pending")
+	}
+
+	export := &todo.AccountExport{UserID: user.ID, Status: todo.ExportPending, CreatedAt: tx.now, UpdatedAt: tx.now}
+	var id int64
+	if err := tx.QueryRowContext(ctx, `
+INSERT INTO account_exports (user_id, status, available_at, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id`,
+		export.UserID,
+		export.Status,
+		(*Time)(&export.CreatedAt),
+		(*Time)(&export.CreatedAt),
+		(*Time)(&export.UpdatedAt)).Scan(&id); err != nil {
+		return nil, err
+	}
+	export.ID = int(id)
+
+	return export, nil
+}
+
+func findAccountExportByID(ctx context.Context, tx *Tx, id int) (*todo.AccountExport, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	exports, err := findAccountExports(ctx, tx, accountExportFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(exports) == 0 {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find export with id %d", id)
+	} else if exports[0].UserID != user.ID {
+		return nil, todo.Unauthorized
+	}
+	return exports[0], nil
+}
+
+func downloadAccountExport(ctx context.Context, tx *Tx, id int) ([]byte, error) {
+	export, err := findAccountExportByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if export.Status != todo.ExportReady {
+		return nil, todo.Err(todo.ECONFLICT, "the export is %s", export.Status)
+	}
+
+	var archive []byte
+	if err := tx.QueryRowContext(ctx, `
+	SELECT archive FROM account_exports WHERE id = $1 AND status = $2`, id, todo.ExportReady).Scan(&archive); errors.Is(err, sql.ErrNoRows) {
+		return nil, todo.Err(todo.ECONFLICT, "the export was already downloaded")
+	} else if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+func markAccountExportDownloaded(ctx context.Context, tx *Tx, id int) error {
+	if _, err := findAccountExportByID(ctx, tx, id); err != nil {
+		return err
+	}
+
+	// only one of concurrent downloads marks the export
+	res, err := tx.ExecContext(ctx, `
+	UPDATE account_exports
+	SET status = $1,
+		archive = NULL,
+		updated_at = $2
+	WHERE id = $3 AND status = $4`,
+		todo.ExportDownloaded, (*Time)(&tx.now), id, todo.ExportReady)
+	if err != nil {
+		return err
+	} else if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return todo.Err(todo.ECONFLICT, "the export was already downloaded")
+	}
+	return nil
+}
+
+func claimAccountExports(ctx context.Context, tx *Tx, n int, lease time.Duration) ([]*todo.AccountExport, error) {
+	leasedUntil := tx.now.Add(lease)
+	rows, err := tx.QueryContext(ctx, `
+	UPDATE account_exports
+	SET available_at = $2
+	WHERE id IN (
+		SELECT id
+		FROM account_exports
+		WHERE status = 'pending' AND available_at <= $1
+		ORDER BY available_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING
+		id,
+		user_id,
+		status,
+		COALESCE(error, ''),
+		created_at,
+		updated_at`,
+		(*Time)(&tx.now), (*Time)(&leasedUntil), n)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccountExports(rows)
+}
+
+func updateAccountExport(ctx context.Context, tx *Tx, id int, upd todo.AccountExportUpdate) error {
+	var archive, lastError interface{}
+	switch upd.Status {
+	case todo.ExportReady:
+		archive = upd.Archive
+	case todo.ExportFailed:
+		lastError = upd.Error
+	default:
+		return todo.Err(todo.EINVALID, "invalid export status %q", upd.Status)
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE account_exports
+	SET status = $1,
+		archive = $2,
+		error = $3,
+		updated_at = $4
+	WHERE id = $5 AND status = 'pending'`,
+		upd.Status, archive, lastError, (*Time)(&tx.now), id)
+	if err != nil {
+		return err
+	} else if n, _ := result.RowsAffected(); n == 0 {
+		return todo.Err(todo.ENOTFOUND, "could not find pending export with id %d", id)
+	}
+	return nil
+}
+
+// accountExportFilter is only used internally, exports are looked up by ID through the service.
+type accountExportFilter struct {
+	ID     *int
+	UserID *int
+	Status *string
+}
+
+func findAccountExports(ctx context.Context, tx *Tx, f accountExportFilter) ([]*todo.AccountExport, error) {
+	var args []interface{}
+	where := []string{"1 = 1"}
+
+	if v := f.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.Status; v != nil {
+		where, args = append(where, fmt.Sprintf("status = $%d", len(where))), append(args, *v)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT
+		id,
+		user_id,
+		status,
+		COALESCE(error, ''),
+		created_at,
+		updated_at
+	FROM account_exports
+	WHERE `+strings.Join(where, " AND ")+`
+	ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccountExports(rows)
+}
+
+func scanAccountExports(rows *sql.Rows) ([]*todo.AccountExport, error) {
+	defer rows.Close()
+
+	exports := make([]*todo.AccountExport, 0)
+	for rows.Next() {
+		var export todo.AccountExport
+		if err := rows.Scan(
+			&export.ID,
+			&export.UserID,
+			&export.Status,
+			&export.Error,
+			(*Time)(&export.CreatedAt),
+			(*Time)(&export.UpdatedAt),
+		); err != nil {
+			return nil, err
+		}
+		exports = append(exports, &export)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
@@ -0,0 +1,189 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestAccountExportService(t *testing.T) {
+	t.Parallel()
+
+	createUser := func(t *testing.T, db *postgres.DB) context.Context {
+		t.Helper()
+		user := newUser()
+		ctx := context.Background()
+		if err := postgres.NewUserService(db).CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return todo.NewContextWithUser(ctx, user)
+	}
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := createUser(t, db)
+		s := postgres.NewAccountExportService(db)
+
+		export, err := s.CreateAccountExport(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if export.Status != todo.ExportPending {
+			t.Fatalf("want pending got %q", export.Status)
+		}
+
+		// only one export can be pending at a time
+		if _, err := s.CreateAccountExport(ctx); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("want conflict got %v", err)
+		}
+
+		// a pending export cannot be downloaded
+		if _, err := s.DownloadAccountExport(ctx, export.ID); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("want conflict got %v", err)
+		}
+
+		claimed, err := s.ClaimAccountExports(context.Background(), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(claimed) != 1 || claimed[0].ID != export.ID {
+			t.Fatalf("want export %d claimed got %v", export.ID, claimed)
+		}
+
+		// a claimed export is leased
+		if again, err := s.ClaimAccountExports(context.Background(), 10, time.Minute); err != nil {
+			t.Fatal(err)
+		} else if len(again) != 0 {
+			t.Fatalf("want no exports claimed got %d", len(again))
+		}
+
+		archive := []byte("PK archive")
+		if err := s.UpdateAccountExport(context.Background(), export.ID, todo.AccountExportUpdate{Status: todo.ExportReady, Archive: archive}); err != nil {
+			t.Fatal(err)
+		}
+
+		// the archive is kept until the download is marked, so that failed downloads can be retried
+		for i := 0; i < 2; i++ {
+			if got, err := s.DownloadAccountExport(ctx, export.ID); err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(got, archive) {
+				t.Fatalf("want archive %q got %q", archive, got)
+			}
+		}
+		if err := s.MarkAccountExportDownloaded(ctx, export.ID); err != nil {
+			t.Fatal(err)
+		} else if err := s.MarkAccountExportDownloaded(ctx, export.ID); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("want conflict got %v", err)
+		}
+
+		// the archive can only be downloaded once
+		if _, err := s.DownloadAccountExport(ctx, export.ID); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("want conflict got %v", err)
+		} else if got, err := s.FindAccountExportByID(ctx, export.ID); err != nil {
+			t.Fatal(err)
+		} else if got.Status != todo.ExportDownloaded {
+			t.Fatalf("want downloaded got %q", got.Status)
+		}
+	})
+
+	t.Run("OtherUser", func(t *testing.T) {
+		db := OpenDB(t)
+		s := postgres.NewAccountExportService(db)
+
+		export, err := s.CreateAccountExport(createUser(t, db))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		other := createUser(t, db)
+		if _, err := s.FindAccountExportByID(other, export.ID); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		} else if _, err := s.DownloadAccountExport(other, export.ID); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		} else if err := s.MarkAccountExportDownloaded(other, export.ID); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		}
+	})
+}
+
+func TestUserService_ScheduleUserDeletion(t *testing.T) {
+	t.Parallel()
+
+	db := OpenDB(t)
+	s := postgres.NewUserService(db)
+	ctx := context.Background()
+
+	user := newUser()
+	password := user.Password
+	if err := s.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	ctx = todo.NewContextWithUser(ctx, user)
+
+	list := &todo.List{Name: "Groceries"}
+	if err := postgres.NewItemListService(db).CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("WrongPassword", func(t *testing.T) {
+		if _, err := s.ScheduleUserDeletion(ctx, user.ID, "wrong", time.Now()); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		at := time.Now().Add(time.Hour).UTC().Round(time.Microsecond)
+		if got, err := s.ScheduleUserDeletion(ctx, user.ID, password, at); err != nil {
+			t.Fatal(err)
+		} else if got.DeleteAt == nil || !got.DeleteAt.Equal(at) {
+			t.Fatalf("want deletion at %v got %v", at, got.DeleteAt)
+		}
+
+		// the deletion is not due yet
+		if n, err := s.DeleteScheduledUsers(ctx); err != nil {
+			t.Fatal(err)
+		} else if n != 0 {
+			t.Fatalf("want no users deleted got %d", n)
+		}
+
+		if got, err := s.CancelUserDeletion(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		} else if got.DeleteAt != nil {
+			t.Fatalf("want deletion canceled got %v", got.DeleteAt)
+		}
+
+		if _, err := s.CancelUserDeletion(ctx, user.ID); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("want conflict got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if _, err := s.ScheduleUserDeletion(ctx, user.ID, password, time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		if n, err := s.DeleteScheduledUsers(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("want 1 user deleted got %d", n)
+		}
+
+		if _, err := s.FindUserByID(ctx, user.ID); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+		if lists, err := postgres.NewItemListService(db).FindLists(ctx, todo.ListFilter{}); err != nil {
+			t.Fatal(err)
+		} else if len(lists) != 0 {
+			t.Fatalf("want lists deleted got %d", len(lists))
+		}
+		if activity, err := postgres.NewActivityService(db).FindActivity(ctx, todo.ActivityFilter{UserID: &user.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(activity) != 0 {
+			t.Fatalf("want activity purged got %d entries", len(activity))
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.ActivityService = (*ActivityService)(nil)
+
+func NewActivityService(db *DB) *ActivityService {
+	return &ActivityService{db: db}
+}
+
+type ActivityService struct {
+	db *DB
+}
+
+func (svc *ActivityService) FindListActivity(ctx context.Context, listID int, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// ensures the list belongs to the current user
+	list, err := findTodoListByID(ctx, tx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	f.UserID, f.ListID = &list.UserID, &list.ID
+	activity, err := findActivity(ctx, tx, f)
+	if err != nil {
+		return nil, err
+	}
+	return activity, tx.Commit()
+}
+
+func (svc *ActivityService) FindActivity(ctx context.Context, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	activity, err := findActivity(ctx, tx, f)
+	if err != nil {
+		return nil, err
+	}
+	return activity, tx.Commit()
+}
+
+// activityIgnoredFields are left out of the recorded diffs. Timestamps change with every mutation and the
+// password hash must never be copied into the log.
+var activityIgnoredFields = []string{"items", "password", "updatedAt"}
+
+// recordActivity appends an entry to the activity log. The actor and request metadata are taken from the
+// context. before and after are the states of the entity before and after the mutation, either may be nil,
+// and only the fields which differ between them are recorded.
+func recordActivity(ctx context.Context, tx *Tx, a *todo.Activity, before, after interface{}) error {
+	if user := todo.UserFromContext(ctx); user != nil && user.ID > 0 {
+		a.ActorID = &user.ID
+	}
+	md := todo.RequestMetadataFromContext(ctx)
+	a.IP, a.UserAgent = md.IP, md.UserAgent
+	a.CreatedAt = tx.now
+
+	var err error
+	if a.Before, a.After, err = activityDiff(before, after); err != nil {
+		return err
+	}
+
+	var beforeArg, afterArg interface{}
+	if a.Before != nil {
+		beforeArg = string(a.Before)
+	}
+	if a.After != nil {
+		afterArg = string(a.After)
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO activity (user_id, actor_id, action, entity, entity_id, list_id, before, after, ip, user_agent, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING id`,
+		a.UserID,
+		a.ActorID,
+		a.Action,
+		a.Entity,
+		a.EntityID,
+		a.ListID,
+		beforeArg,
+		afterArg,
+		a.IP,
+		a.UserAgent,
+		(*Time)(&a.CreatedAt)).Scan(&id)
+	if err != nil {
+		return err
+	}
+	a.ID = int(id)
+
+	return nil
+}
+
+// activityDiff returns the JSON fields of before and after which differ.
+func activityDiff(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	fields := func(v interface{}) (map[string]json.RawMessage, error) {
+		m := make(map[string]json.RawMessage)
+		if v == nil {
+			return m, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(b, &m); err != nil {
+			return nil, err
+		}
+		for _, field := range activityIgnoredFields {
+			delete(m, field)
+		}
+		return m, nil
+	}
+
+	from, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for k, v := range from {
+		if other, ok := to[k]; ok && bytes.Equal(v, other) {
+			delete(from, k)
+			delete(to, k)
+		}
+	}
+
+	marshal := func(m map[string]json.RawMessage) (json.RawMessage, error) {
+		if len(m) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(m)
+	}
+
+	var a, b json.RawMessage
+	if a, err = marshal(from); err != nil {
+		return nil, nil, err
+	} else if b, err = marshal(to); err != nil {
+		return nil, nil, err
+	}
+	return a, b, nil
+}
+
+func findActivity(ctx context.Context, tx *Tx, f todo.ActivityFilter) ([]*todo.Activity, error) {
+	var args []interface{}
+	where := []string{"1 = 1"}
+	if v := f.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.ListID; v != nil {
+		where, args = append(where, fmt.Sprintf("list_id = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.Action; v != nil {
+		where, args = append(where, fmt.Sprintf("action = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.Since; v != nil {
+		where, args = append(where, fmt.Sprintf("created_at >= $%d", len(where))), append(args, (*Time)(v))
+	}
+
+	if v := f.Until; v != nil {
+		where, args = append(where, fmt.Sprintf("created_at < $%d", len(where))), append(args, (*Time)(v))
+	}
+
+	query := `
+	SELECT
+		id,
+		user_id,
+		actor_id,
+		action,
+		entity,
+		entity_id,
+		list_id,
+		before,
+		after,
+		ip,
+		user_agent,
+		created_at
+	FROM activity
+	WHERE ` + strings.Join(where, " AND ") + `
+	ORDER BY id DESC ` + FormatLimitOffset(f.Limit, f.Offset)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := make([]*todo.Activity, 0)
+	for rows.Next() {
+		var a todo.Activity
+		var before, after []byte
+		if err := rows.Scan(
+			&a.ID,
+			&a.UserID,
+			&a.ActorID,
+			&a.Action,
+			&a.Entity,
+			&a.EntityID,
+			&a.ListID,
+			&before,
+			&after,
+			&a.IP,
+			&a.UserAgent,
+			(*Time)(&a.CreatedAt),
+		); err != nil {
+			return nil, err
+		}
+		if before != nil {
+			a.Before = before
+		}
+		if after != nil {
+			a.After = after
+		}
+		activity = append(activity, &a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return activity, nil
+}
@@ -0,0 +1,124 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestActivityService(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ListActivity", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := todo.NewContextWithRequestMetadata(context.Background(), todo.RequestMetadata{IP: "127.0.0.1", UserAgent: "test"})
+		user := &todo.User{Name: *randstr(10), Password: *randstr(10)}
+		if err := postgres.NewUserService(db).CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		ctx = todo.NewContextWithUser(ctx, user)
+
+		lists := postgres.NewItemListService(db)
+		list := &todo.List{Name: "groceries"}
+		if err := lists.CreateList(ctx, list); err != nil {
+			t.Fatal(err)
+		}
+		item := &todo.Item{ListID: list.ID, Name: "milk"}
+		if err := lists.CreateItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		completed := true
+		if _, err := lists.UpdateItem(ctx, item.ID, todo.ItemUpdate{Completed: &completed}); err != nil {
+			t.Fatal(err)
+		}
+
+		s := postgres.NewActivityService(db)
+		activity, err := s.FindListActivity(ctx, list.ID, todo.ActivityFilter{})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(activity), 3; got != want {
+			t.Fatalf("want %d entries got %d", want, got)
+		}
+
+		update := activity[0]
+		if update.Action != todo.ActionItemUpdated || update.EntityID != item.ID {
+			t.Fatalf("unexpected entry %+v", update)
+		} else if update.ActorID == nil || *update.ActorID != user.ID {
+			t.Fatalf("want actor %d got %v", user.ID, update.ActorID)
+		} else if update.IP != "127.0.0.1" || update.UserAgent != "test" {
+			t.Fatalf("want request metadata got %q %q", update.IP, update.UserAgent)
+		}
+
+		var before, after map[string]interface{}
+		if err := json.Unmarshal(update.Before, &before); err != nil {
+			t.Fatal(err)
+		} else if err := json.Unmarshal(update.After, &after); err != nil {
+			t.Fatal(err)
+		} else if len(before) != 1 || before["completed"] != false || len(after) != 1 || after["completed"] != true {
+			t.Fatalf("want completed diff got %s %s", update.Before, update.After)
+		}
+
+		action := todo.ActionListCreated
+		if activity, err := s.FindListActivity(ctx, list.ID, todo.ActivityFilter{Action: &action}); err != nil {
+			t.Fatal(err)
+		} else if len(activity) != 1 || activity[0].Before != nil {
+			t.Fatalf("unexpected list activity %+v", activity)
+		}
+
+		// other users cannot read the activity
+		other := &todo.User{ID: user.ID + 1000, Name: *randstr(10)}
+		if _, err := s.FindListActivity(todo.NewContextWithUser(ctx, other), list.ID, todo.ActivityFilter{}); err == nil {
+			t.Fatal("want error reading activity of another user's list")
+		}
+	})
+
+	t.Run("Audit", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := context.Background()
+		users := postgres.NewUserService(db)
+		user := &todo.User{Name: *randstr(10), Password: *randstr(10)}
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		name := *randstr(10)
+		if _, err := users.UpdateUser(ctx, user.ID, todo.UserUpdate{Name: &name}); err != nil {
+			t.Fatal(err)
+		}
+		if err := users.DeleteUser(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		s := postgres.NewActivityService(db)
+		activity, err := s.FindActivity(ctx, todo.ActivityFilter{UserID: &user.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(activity), 3; got != want {
+			t.Fatalf("want %d entries got %d", want, got)
+		} else if activity[0].Action != todo.ActionUserDeleted || activity[2].Action != todo.ActionUserCreated {
+			t.Fatalf("unexpected actions %q %q", activity[0].Action, activity[2].Action)
+		} else if activity[2].ActorID == nil || *activity[2].ActorID != user.ID {
+			t.Fatalf("want new user as actor got %v", activity[2].ActorID)
+		}
+
+		// passwords never end up in the log
+		var created map[string]interface{}
+		if err := json.Unmarshal(activity[2].After, &created); err != nil {
+			t.Fatal(err)
+		} else if _, ok := created["password"]; ok {
+			t.Fatal("password recorded in activity log")
+		}
+
+		future := time.Now().Add(time.Hour)
+		if activity, err := s.FindActivity(ctx, todo.ActivityFilter{UserID: &user.ID, Since: &future}); err != nil {
+			t.Fatal(err)
+		} else if len(activity) != 0 {
+			t.Fatalf("want no entries got %d", len(activity))
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/jackc/pgconn"
+)
+
+var _ todo.CalendarObjectService = (*CalendarObjectService)(nil)
+
+func NewCalendarObjectService(db *DB) *CalendarObjectService {
+	return &CalendarObjectService{db: db}
+}
+
+type CalendarObjectService struct {
+	db *DB
+}
+
+func (svc *CalendarObjectService) FindCalendarObjects(ctx context.Context, listID int) ([]*todo.CalendarObject, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	objects, err := findCalendarObjects(ctx, tx, listID)
+	if err != nil {
+		return nil, err
+	}
+	return objects, tx.Commit()
+}
+
+func (svc *CalendarObjectService) CreateCalendarObject(ctx context.Context, item *todo.Item, obj *todo.CalendarObject) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createTodoItem(ctx, tx, item); err != nil {
+		return err
+	} else if err := createCalendarObject(ctx, tx, item, obj); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// findCalendarObjects returns the objects of the items which are still in the list they were created in.
+func findCalendarObjects(ctx context.Context, tx *Tx, listID int) ([]*todo.CalendarObject, error) {
+	// the list must belong to the user
+	if _, err := findTodoListByID(ctx, tx, listID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT o.item_id, o.list_id, o.name, o.uid
+	FROM caldav_objects o
+	JOIN items i ON i.id = o.item_id AND i.list_id = o.list_id
+	WHERE o.list_id = $1
+	ORDER BY o.item_id ASC`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := make([]*todo.CalendarObject, 0)
+	for rows.Next() {
+		var obj todo.CalendarObject
+		if err := rows.Scan(&obj.ItemID, &obj.ListID, &obj.Name, &obj.UID); err != nil {
+			return nil, err
+		}
+		objects = append(objects, &obj)
+	}
+	return objects, rows.Err()
+}
+
+func createCalendarObject(ctx context.Context, tx *Tx, item *todo.Item, obj *todo.CalendarObject) error {
+	obj.ItemID, obj.ListID = item.ID, item.ListID
+	if err := obj.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+	INSERT INTO caldav_objects (item_id, list_id, name, uid) VALUES ($1, $2, $3, $4)`,
+		obj.ItemID, obj.ListID, obj.Name, obj.UID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique constraint violation
+			return todo.Err(todo.ECONFLICT, "the list already has an object named %q or with uid %q", obj.Name, obj.UID)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestCalendarObjectService(t *testing.T) {
+	t.Parallel()
+
+	db := OpenDB(t)
+	s := postgres.NewCalendarObjectService(db)
+	items := postgres.NewItemListService(db)
+
+	user := newUser()
+	if err := postgres.NewUserService(db).CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ctx := todo.NewContextWithUser(context.Background(), user)
+	list := &todo.List{Name: "Groceries"}
+	if err := items.CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+
+	item := &todo.Item{ListID: list.ID, Name: "bread"}
+	obj := &todo.CalendarObject{Name: "6B29FC40-CA47.ics", UID: "6B29FC40-CA47@client"}
+	if err := s.CreateCalendarObject(ctx, item, obj); err != nil {
+		t.Fatal(err)
+	} else if item.ID == 0 || obj.ItemID != item.ID || obj.ListID != list.ID {
+		t.Fatalf("want object of item %d got %+v", item.ID, obj)
+	}
+
+	if objects, err := s.FindCalendarObjects(ctx, list.ID); err != nil {
+		t.Fatal(err)
+	} else if len(objects) != 1 || *objects[0] != *obj {
+		t.Fatalf("want %+v got %v", obj, objects)
+	}
+
+	// the item is not created if its name or UID is taken
+	for _, other := range []*todo.CalendarObject{{Name: obj.Name, UID: "other"}, {Name: "other.ics", UID: obj.UID}} {
+		if err := s.CreateCalendarObject(ctx, &todo.Item{ListID: list.ID, Name: "other"}, other); todo.ErrCode(err) != todo.ECONFLICT {
+			t.Fatalf("%+v: want conflict got %v", other, err)
+		}
+	}
+	if l, err := items.FindListByID(ctx, list.ID); err != nil {
+		t.Fatal(err)
+	} else if len(l.Items) != 1 {
+		t.Fatalf("want 1 item got %d", len(l.Items))
+	}
+
+	// objects are deleted with their item
+	if err := items.DeleteItem(ctx, item.ID); err != nil {
+		t.Fatal(err)
+	} else if objects, err := s.FindCalendarObjects(ctx, list.ID); err != nil {
+		t.Fatal(err)
+	} else if len(objects) != 0 {
+		t.Fatalf("want no objects got %d", len(objects))
+	}
+
+	// the objects of other users' lists are not found
+	other := newUser()
+	if err := postgres.NewUserService(db).CreateUser(context.Background(), other); err != nil {
+		t.Fatal(err)
+	} else if _, err := s.FindCalendarObjects(todo.NewContextWithUser(context.Background(), other), list.ID); err == nil {
+		t.Fatal("want error for the list of another user")
+	}
+}
@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// eventChannel is the NOTIFY channel events are published on.
+const eventChannel = "todo_events"
+
+// maxNotifyPayload keeps notifications below the 8000 byte payload limit of NOTIFY.
+const maxNotifyPayload = 7900
+
+var _ todo.EventService = (*EventService)(nil)
+
+// EventService fans events out across every server sharing the database. Published events are sent with
+// NOTIFY and each server LISTENs on the same channel, forwarding the notifications it receives to its local
+// in-process EventService which serves the subscriptions. The events of a transaction are sent with it and are
+// delivered once it commits.
+type EventService struct {
+	db     *DB
+	local  todo.EventService
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+}
+
+func NewEventService(db *DB, local todo.EventService) *EventService {
+	s := &EventService{db: db, local: local}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Open connects the listener and starts forwarding notifications in the background.
+func (s *EventService) Open(ctx context.Context) error {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	s.done = make(chan struct{})
+	go s.listen(conn)
+	return nil
+}
+
+// Close stops the listener.
+func (s *EventService) Close() error {
+	s.cancel()
+	if s.done != nil {
+		<-s.done
+	}
+	return nil
+}
+
+// PublishEvent sends the event to the listeners of every server, including this one.
+func (s *EventService) PublishEvent(userID int, event todo.Event) {
+	b, err := marshalNotification(userID, event)
+	if err != nil {
+		s.db.Logger.Errorf("failed to marshal %s event: %v", event.Type, err)
+		return
+	}
+
+	if _, err := s.db.db.ExecContext(s.ctx, `SELECT pg_notify($1, $2)`, eventChannel, string(b)); err != nil {
+		s.db.Logger.Errorf("failed to publish %s event: %v", event.Type, err)
+	}
+}
+
+// notify sends the notifications of the events with a single statement of tx, they are delivered once tx commits.
+func (s *EventService) notify(ctx context.Context, tx *Tx, events []txEvent) error {
+	payloads := make([]string, 0, len(events))
+	for _, e := range events {
+		b, err := marshalNotification(e.userID, e.event)
+		if err != nil {
+			s.db.logger(ctx).Errorf("failed to marshal %s event: %v", e.event.Type, err)
+			continue
+		}
+		payloads = append(payloads, string(b))
+	}
+
+	var arr pgtype.TextArray
+	if err := arr.Set(payloads); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, payload) FROM unnest($2::text[]) AS payload`, eventChannel, arr); err != nil {
+		return fmt.Errorf("failed to publish events: %w", err)
+	}
+	return nil
+}
+
+func (s *EventService) Subscribe(ctx context.Context) (todo.Subscription, error) {
+	return s.local.Subscribe(ctx)
+}
+
+func (s *EventService) connect(ctx context.Context) (*pgx.Conn, error) {
+	cfg, err := pgx.ParseConfig(s.db.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Exec(ctx, `LISTEN `+eventChannel); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// listen forwards notifications to the local EventService until the EventService is closed, reconnecting
+// whenever the connection is lost. Events published while reconnecting are not delivered.
+func (s *EventService) listen(conn *pgx.Conn) {
+	defer close(s.done)
+
+	for {
+		err := s.forward(conn)
+		conn.Close(context.Background())
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.db.Logger.Warnf("event listener disconnected: %v", err)
+
+		for conn = nil; conn == nil; {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(time.Second * 3):
+			}
+
+			if conn, err = s.connect(s.ctx); err != nil {
+				s.db.Logger.Warnf("failed to reconnect event listener: %v", err)
+			}
+		}
+	}
+}
+
+func (s *EventService) forward(conn *pgx.Conn) error {
+	for {
+		n, err := conn.WaitForNotification(s.ctx)
+		if err != nil {
+			return err
+		}
+
+		var msg notification
+		if err := json.Unmarshal([]byte(n.Payload), &msg); err != nil {
+			s.db.Logger.Errorf("failed to unmarshal event notification: %v", err)
+			continue
+		}
+		s.local.PublishEvent(msg.UserID, msg.Event)
+	}
+}
+
+type notification struct {
+	UserID int        `json:"userId"`
+	Event  todo.Event `json:"event"`
+}
+
+// marshalNotification encodes an event as a NOTIFY payload. Events which would exceed the payload limit are
+// reduced to the identifiers of the List or Item.
+func marshalNotification(userID int, event todo.Event) ([]byte, error) {
+	b, err := json.Marshal(notification{UserID: userID, Event: event})
+	if err != nil || len(b) <= maxNotifyPayload {
+		return b, err
+	}
+
+	if v := event.List; v != nil {
+		event.List = &todo.List{ID: v.ID, UserID: v.UserID}
+	}
+	if v := event.Item; v != nil {
+		event.Item = &todo.Item{ID: v.ID, UserID: v.UserID, ListID: v.ListID}
+	}
+	return json.Marshal(notification{UserID: userID, Event: event})
+}
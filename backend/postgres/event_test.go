@@ -0,0 +1,95 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/inmem"
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestEventService(t *testing.T) {
+	db := OpenDB(t)
+	s := postgres.NewEventService(db, inmem.NewEventService())
+	if err := s.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	db.EventService = s
+
+	user := newUser()
+	if err := postgres.NewUserService(db).CreateUser(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	ctx := todo.NewContextWithUser(context.Background(), user)
+	sub, err := s.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	// the events of committed transactions are sent with NOTIFY and received by the listener
+	list := &todo.List{Name: *randstr(10)}
+	if err := postgres.NewItemListService(db).CreateList(ctx, list); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-sub.C():
+		if event.Type != todo.EventListCreated || event.List == nil || event.List.ID != list.ID {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("want event")
+	}
+
+	// the events of a transaction are all sent, in order
+	lists := []*todo.List{{Name: *randstr(10)}, {Name: *randstr(10)}, {Name: *randstr(10)}}
+	if err := postgres.NewItemListService(db).ImportLists(ctx, lists); err != nil {
+		t.Fatal(err)
+	}
+	for _, list := range lists {
+		select {
+		case event := <-sub.C():
+			if event.Type != todo.EventListCreated || event.List == nil || event.List.ID != list.ID {
+				t.Fatalf("unexpected event %+v", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("want event")
+		}
+	}
+
+	// the listener reconnects once its connection is lost
+	tx, err := db.BeginTx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(context.Background(), `
+		SELECT pg_terminate_backend(pid)
+		FROM pg_stat_activity
+		WHERE pid <> pg_backend_pid() AND datname = current_database() AND query = 'LISTEN todo_events'`); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// events published while reconnecting are lost, so they are published until one is received
+	deadline := time.After(15 * time.Second)
+	for {
+		s.PublishEvent(user.ID, todo.Event{Type: todo.EventListUpdated, List: list})
+		select {
+		case event := <-sub.C():
+			if event.Type != todo.EventListUpdated {
+				t.Fatalf("unexpected event %+v", event)
+			}
+			return
+		case <-time.After(500 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("want event after reconnecting")
+		}
+	}
+}
@@ -3,20 +3,36 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/cmokbel1/todo-app/backend/todo"
 	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var _ pgx.Logger = (*Logger)(nil)
 
-// Logger implements a pgx.Logger which logs SQL queries
+// Logger implements a pgx.Logger which logs SQL queries with the logger of the request context, if any, records a
+// span for every query and observes their durations.
 type Logger struct {
 	todo.Logger
+	// LogQueries toggles the logging of queries.
+	LogQueries bool
+	// Tracer records the spans of queries, nothing is recorded if it is nil.
+	Tracer trace.Tracer
 }
 
 func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, message string, data map[string]interface{}) {
-	if message != "Query" {
+	if message == "Query" || message == "Exec" {
+		if d, ok := data["time"].(time.Duration); ok {
+			observeQuery(fmt.Sprint(data["sql"]), d)
+		}
+		l.trace(ctx, level, message, data)
+	}
+	if message != "Query" || !l.LogQueries {
 		return
 	}
 
@@ -27,16 +43,50 @@ func (l *Logger) Log(ctx context.Context, level pgx.LogLevel, message string, da
 		msg = fmt.Sprintf("postgres query %v", sql)
 	}
 
+	logger := todo.LoggerFromContext(ctx, l.Logger)
 	switch level {
 	case pgx.LogLevelDebug:
-		l.Debug(msg)
+		logger.Debug(msg)
 	case pgx.LogLevelInfo:
-		l.Info(msg)
+		logger.Info(msg)
 	case pgx.LogLevelWarn:
-		l.Warn(msg)
+		logger.Warn(msg)
 	case pgx.LogLevelError:
-		l.Error(msg)
+		logger.Error(msg)
+	}
+}
+
+// trace records the span of a query once it ran, pgx only reports queries after they ran with their duration.
+func (l *Logger) trace(ctx context.Context, level pgx.LogLevel, message string, data map[string]interface{}) {
+	if l.Tracer == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return
+	}
+	sql, _ := data["sql"].(string)
+	if logBlackListQueries[sql] {
+		return
+	}
+
+	end := time.Now()
+	start := end
+	if d, ok := data["time"].(time.Duration); ok {
+		start = end.Add(-d)
+	}
+	_, span := l.Tracer.Start(ctx, "postgres "+message,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBStatement(sql)),
+	)
+	if n, ok := data["rowCount"].(int); ok {
+		span.SetAttributes(attribute.Int("db.rows", n))
+	}
+	if level == pgx.LogLevelError {
+		err, _ := data["err"].(error)
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.SetStatus(codes.Error, fmt.Sprint(data["err"]))
 	}
+	span.End(trace.WithTimestamp(end))
 }
 
 // logBlackListQueries are queries that are not logged when query logging is enabled.
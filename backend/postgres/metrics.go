@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -14,14 +15,90 @@ var metrics = struct {
 	sessionCountGauge  prometheus.Gauge
 	todoItemCountGauge prometheus.Gauge
 	todoListCountGauge prometheus.Gauge
+	queryDuration      *prometheus.HistogramVec
 }{
 	userCountGauge:     promauto.NewGauge(prometheus.GaugeOpts{Name: "todo_db_users", Help: "Total number of users"}),
 	sessionCountGauge:  promauto.NewGauge(prometheus.GaugeOpts{Name: "todo_db_sessions", Help: "Total number of active sessions"}),
 	todoItemCountGauge: promauto.NewGauge(prometheus.GaugeOpts{Name: "todo_db_todo_items", Help: "Total number of todo items"}),
 	todoListCountGauge: promauto.NewGauge(prometheus.GaugeOpts{Name: "todo_db_todo_lists", Help: "Total number of todo lists"}),
+	queryDuration: promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "todo_db_query_duration_seconds",
+		Help:    "Duration of SQL statements per operation, e.g. SELECT",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"}),
+}
+
+// queryOperations are the operations of the query duration metric, other statements are counted as "other".
+var queryOperations = map[string]bool{
+	"SELECT": true,
+	"INSERT": true,
+	"UPDATE": true,
+	"DELETE": true,
+	"BEGIN":  true,
+	"COMMIT": true,
+}
+
+// observeQuery records the duration of a statement.
+func observeQuery(sql string, d time.Duration) {
+	op := "other"
+	if fields := strings.Fields(sql); len(fields) > 0 {
+		if v := strings.ToUpper(fields[0]); queryOperations[v] {
+			op = v
+		}
+	}
+	metrics.queryDuration.WithLabelValues(op).Observe(d.Seconds())
+}
+
+// poolStatsCollector exports the connection pool statistics of a DB.
+type poolStatsCollector struct {
+	db *DB
+
+	openConnections *prometheus.Desc
+	inUse           *prometheus.Desc
+	idle            *prometheus.Desc
+	waitCount       *prometheus.Desc
+	waitDuration    *prometheus.Desc
+	maxIdleClosed   *prometheus.Desc
+	maxLifetime     *prometheus.Desc
+}
+
+func newPoolStatsCollector(db *DB) *poolStatsCollector {
+	return &poolStatsCollector{
+		db:              db,
+		openConnections: prometheus.NewDesc("todo_db_pool_open_connections", "Number of established connections", nil, nil),
+		inUse:           prometheus.NewDesc("todo_db_pool_in_use_connections", "Number of connections in use", nil, nil),
+		idle:            prometheus.NewDesc("todo_db_pool_idle_connections", "Number of idle connections", nil, nil),
+		waitCount:       prometheus.NewDesc("todo_db_pool_wait_count_total", "Total number of connections waited for", nil, nil),
+		waitDuration:    prometheus.NewDesc("todo_db_pool_wait_duration_seconds_total", "Total time waited for connections", nil, nil),
+		maxIdleClosed:   prometheus.NewDesc("todo_db_pool_max_idle_closed_total", "Total number of connections closed due to the maximum of idle connections", nil, nil),
+		maxLifetime:     prometheus.NewDesc("todo_db_pool_max_lifetime_closed_total", "Total number of connections closed due to their maximum lifetime", nil, nil),
+	}
+}
+
+func (c *poolStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.openConnections
+	ch <- c.inUse
+	ch <- c.idle
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.maxIdleClosed
+	ch <- c.maxLifetime
+}
+
+func (c *poolStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.db.Stats()
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(c.maxIdleClosed, prometheus.CounterValue, float64(stats.MaxIdleClosed))
+	ch <- prometheus.MustNewConstMetric(c.maxLifetime, prometheus.CounterValue, float64(stats.MaxLifetimeClosed))
 }
 
 func (db *DB) monitorMetrics() {
+	defer close(db.monitorDone)
+
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
 
@@ -32,7 +109,7 @@ func (db *DB) monitorMetrics() {
 		case <-ticker.C:
 		}
 
-		if err := db.updateStats(db.ctx); err != nil {
+		if err := db.updateStats(db.ctx); err != nil && db.ctx.Err() == nil {
 			db.Logger.Errorf("db monitor failed to update stats: %v", err)
 		}
 	}
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS changes
+(
+    -- id doubles as the sync token handed out to clients
+    id         BIGSERIAL PRIMARY KEY NOT NULL,
+    user_id    BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    -- entity is either 'list' or 'item'
+    entity     TEXT                  NOT NULL,
+    entity_id  BIGINT                NOT NULL,
+    -- op is one of 'create', 'update' or 'delete'
+    op         TEXT                  NOT NULL,
+    created_at TIMESTAMPTZ           NOT NULL
+);
+
+CREATE INDEX changes_user_id_id_idx ON changes (user_id, id);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS changes;
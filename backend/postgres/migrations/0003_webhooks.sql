@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS webhooks
+(
+    id          BIGSERIAL PRIMARY KEY NOT NULL,
+    user_id     BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    url         TEXT                  NOT NULL,
+    -- an empty array subscribes to every event type
+    event_types TEXT[]                NOT NULL,
+    secret      TEXT                  NOT NULL,
+    created_at  TIMESTAMPTZ           NOT NULL,
+    updated_at  TIMESTAMPTZ           NOT NULL
+);
+
+CREATE INDEX webhooks_user_id_idx ON webhooks (user_id);
+
+-- webhook_deliveries is the outbox of webhook events, rows are written in the same transaction as the change
+CREATE TABLE IF NOT EXISTS webhook_deliveries
+(
+    id              BIGSERIAL PRIMARY KEY NOT NULL,
+    webhook_id      BIGINT REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type      TEXT                  NOT NULL,
+    payload         JSONB                 NOT NULL,
+    -- status is one of 'pending', 'delivered' or 'dead'
+    status          TEXT                  NOT NULL,
+    attempts        INT                   NOT NULL,
+    response_status INT,
+    last_error      TEXT,
+    next_attempt_at TIMESTAMPTZ           NOT NULL,
+    created_at      TIMESTAMPTZ           NOT NULL,
+    updated_at      TIMESTAMPTZ           NOT NULL
+);
+
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- +goose Down
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
@@ -0,0 +1,41 @@
+-- +goose Up
+-- activity is an append only audit log, it has no foreign keys so entries outlive the users and lists they describe
+CREATE TABLE IF NOT EXISTS activity
+(
+    id         BIGSERIAL PRIMARY KEY NOT NULL,
+    user_id    BIGINT                NOT NULL,
+    actor_id   BIGINT,
+    action     TEXT                  NOT NULL,
+    entity     TEXT                  NOT NULL,
+    entity_id  BIGINT                NOT NULL,
+    list_id    BIGINT,
+    before     JSONB,
+    after      JSONB,
+    ip         TEXT                  NOT NULL,
+    user_agent TEXT                  NOT NULL,
+    created_at TIMESTAMPTZ           NOT NULL
+);
+
+CREATE INDEX activity_user_id_idx ON activity (user_id, id);
+CREATE INDEX activity_list_id_idx ON activity (list_id, id) WHERE list_id IS NOT NULL;
+CREATE INDEX activity_created_at_idx ON activity (created_at);
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION activity_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'activity entries cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER activity_immutable
+    BEFORE UPDATE OR DELETE
+    ON activity
+    FOR EACH ROW
+EXECUTE FUNCTION activity_immutable();
+
+-- +goose Down
+
+DROP TABLE IF EXISTS activity;
+DROP FUNCTION IF EXISTS activity_immutable();
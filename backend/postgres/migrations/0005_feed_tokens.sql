@@ -0,0 +1,10 @@
+-- +goose Up
+-- feed_token authenticates the user's calendar feed, it is generated on first use
+ALTER TABLE users ADD COLUMN feed_token TEXT;
+
+CREATE UNIQUE INDEX users_feed_token_key ON users (feed_token);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS users_feed_token_key;
+ALTER TABLE users DROP COLUMN IF EXISTS feed_token;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- delete_at is set when a user requests the deletion of their account, the account is deleted once it has passed
+ALTER TABLE users ADD COLUMN delete_at TIMESTAMPTZ;
+
+CREATE INDEX users_delete_at_idx ON users (delete_at) WHERE delete_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS account_exports
+(
+    id           BIGSERIAL PRIMARY KEY NOT NULL,
+    user_id      BIGINT REFERENCES users (id) ON DELETE CASCADE,
+    -- status is one of 'pending', 'ready', 'downloaded' or 'failed'
+    status       TEXT                  NOT NULL,
+    -- archive is the ZIP archive of a ready export, it is discarded once downloaded
+    archive      BYTEA,
+    error        TEXT,
+    -- pending exports are claimed by pushing available_at into the future
+    available_at TIMESTAMPTZ           NOT NULL,
+    created_at   TIMESTAMPTZ           NOT NULL,
+    updated_at   TIMESTAMPTZ           NOT NULL
+);
+
+CREATE INDEX account_exports_user_id_idx ON account_exports (user_id, id);
+CREATE INDEX account_exports_pending_idx ON account_exports (available_at) WHERE status = 'pending';
+
+-- +goose Down
+
+DROP TABLE IF EXISTS account_exports;
+DROP INDEX IF EXISTS users_delete_at_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS delete_at;
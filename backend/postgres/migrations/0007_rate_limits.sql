@@ -0,0 +1,17 @@
+-- +goose Up
+-- rate_limits counts the requests of rate limited clients per window, so that all servers enforce one budget
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limits
+(
+    key          TEXT        NOT NULL,
+    window_start TIMESTAMPTZ NOT NULL,
+    count        INTEGER     NOT NULL,
+    -- expires_at is when the window no longer counts as the previous window of a request
+    expires_at   TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, window_start)
+);
+
+CREATE INDEX rate_limits_expires_at_idx ON rate_limits (expires_at);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS rate_limits;
@@ -0,0 +1,28 @@
+-- +goose Up
+-- the activity of deleted accounts is purged, which the transaction deleting them allows by setting
+-- todo.purge_activity, see deleteScheduledUsers
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION activity_immutable() RETURNS trigger AS
+$$
+BEGIN
+    IF current_setting('todo.purge_activity', true) = 'on' THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'activity entries cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION activity_immutable() RETURNS trigger AS
+$$
+BEGIN
+    RAISE EXCEPTION 'activity entries cannot be modified';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
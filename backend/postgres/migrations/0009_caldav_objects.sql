@@ -0,0 +1,15 @@
+-- +goose Up
+-- caldav_objects are the names and UIDs under which CalDAV clients created items
+CREATE TABLE IF NOT EXISTS caldav_objects
+(
+    item_id BIGINT PRIMARY KEY NOT NULL REFERENCES items (id) ON DELETE CASCADE,
+    list_id BIGINT             NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    name    TEXT               NOT NULL,
+    uid     TEXT               NOT NULL,
+    UNIQUE (list_id, name),
+    UNIQUE (list_id, uid)
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS caldav_objects;
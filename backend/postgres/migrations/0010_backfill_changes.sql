@@ -0,0 +1,17 @@
+-- +goose Up
+-- lists and items which existed before the change log have no changes, so a client syncing from scratch would not
+-- see them, they are logged as created in the order they were
+INSERT INTO changes (user_id, entity, entity_id, op, created_at)
+SELECT l.user_id, 'list', l.id, 'create', l.created_at
+FROM lists l
+WHERE NOT EXISTS(SELECT 1 FROM changes c WHERE c.entity = 'list' AND c.entity_id = l.id)
+ORDER BY l.id;
+
+INSERT INTO changes (user_id, entity, entity_id, op, created_at)
+SELECT i.user_id, 'item', i.id, 'create', i.created_at
+FROM items i
+WHERE NOT EXISTS(SELECT 1 FROM changes c WHERE c.entity = 'item' AND c.entity_id = i.id)
+ORDER BY i.id;
+
+-- +goose Down
+-- the backfilled changes cannot be told apart from the logged ones, and are harmless to keep
@@ -7,6 +7,8 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
+	"path"
 	"time"
 
 	"github.com/alexedwards/scs/postgresstore"
@@ -15,8 +17,15 @@ import (
 	"github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/pressly/goose/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the name of the tracer of the spans of transactions and queries.
+const tracerName = "github.com/cmokbel1/todo-app/backend/postgres"
+
 //go:embed migrations/*.sql
 var migrationsFS embed.FS
 
@@ -25,6 +34,8 @@ type DB struct {
 	db     *sqlx.DB
 	ctx    context.Context
 	cancel func()
+	// monitorDone is closed once the metrics monitor stopped.
+	monitorDone chan struct{}
 
 	// Connection string
 	DSN string
@@ -32,6 +43,11 @@ type DB struct {
 	Logger todo.Logger
 	// EnableQueryLogging toggles INFO logging of underlying SQL queries.
 	EnableQueryLogging bool
+	// EventService receives the events of committed transactions.
+	EventService todo.EventService
+	// TracerProvider records a span for every transaction and query of a traced context.
+	TracerProvider trace.TracerProvider
+	tracer         trace.Tracer
 
 	// Now returns current time in UTC rounded to the nearest microsecond
 	Now func() time.Time
@@ -42,6 +58,9 @@ func New(dsn string) *DB {
 		DSN:    dsn,
 		Now:    func() time.Time { return time.Now().UTC().Round(time.Microsecond) },
 		Logger: todo.NewLogger(),
+
+		EventService:   todo.NopEventService(),
+		TracerProvider: trace.NewNoopTracerProvider(),
 	}
 	db.ctx, db.cancel = context.WithCancel(context.Background())
 	return db
@@ -56,9 +75,8 @@ func (db *DB) Open(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	if db.EnableQueryLogging {
-		cfg.Logger = &Logger{db.Logger}
-	}
+	db.tracer = db.TracerProvider.Tracer(tracerName)
+	cfg.Logger = &Logger{Logger: db.Logger, LogQueries: db.EnableQueryLogging, Tracer: db.tracer}
 	sqlDB := stdlib.OpenDB(*cfg)
 	sqlDB.SetMaxOpenConns(10)
 	sqlDB.SetMaxIdleConns(10)
@@ -78,24 +96,168 @@ func (db *DB) Open(ctx context.Context) error {
 	return nil
 }
 
+// Migrate applies all pending migrations.
 func (db *DB) Migrate() error {
+	if err := db.setupMigrations(); err != nil {
+		return err
+	}
+	return goose.Up(db.db.DB, "migrations")
+}
+
+// MigrateDown rolls back the latest applied migration.
+func (db *DB) MigrateDown() error {
+	if err := db.setupMigrations(); err != nil {
+		return err
+	}
+	return goose.Down(db.db.DB, "migrations")
+}
+
+// MigrateRedo rolls back the latest applied migration and applies it again.
+func (db *DB) MigrateRedo() error {
+	if err := db.setupMigrations(); err != nil {
+		return err
+	}
+	return goose.Redo(db.db.DB, "migrations")
+}
+
+// Migration is the state of a single migration.
+type Migration struct {
+	Version int64
+	// Name is the file name of the migration.
+	Name string
+	// AppliedAt is zero if the migration is pending.
+	AppliedAt time.Time
+}
+
+// Migrations returns the state of all migrations ordered by version.
+func (db *DB) Migrations(ctx context.Context) ([]*Migration, error) {
+	if err := db.setupMigrations(); err != nil {
+		return nil, err
+	}
+	if _, err := goose.EnsureDBVersion(db.db.DB); err != nil {
+		return nil, err
+	}
+	collected, err := goose.CollectMigrations("migrations", 0, goose.MaxVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []*Migration
+	for _, m := range collected {
+		migration := &Migration{Version: m.Version, Name: path.Base(m.Source)}
+
+		var appliedAt time.Time
+		var applied bool
+		err := db.db.QueryRowContext(ctx, `
+		SELECT tstamp, is_applied FROM `+goose.TableName()+`
+		WHERE version_id = $1
+		ORDER BY tstamp DESC
+		LIMIT 1`, m.Version).Scan(&appliedAt, &applied)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		} else if applied {
+			migration.AppliedAt = appliedAt.UTC()
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// Ping checks that the database can be reached.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.db.PingContext(ctx)
+}
+
+// CheckMigrations returns an error if the database is not migrated to the latest migration.
+func (db *DB) CheckMigrations(ctx context.Context) error {
+	latest, err := latestMigration()
+	if err != nil {
+		return err
+	}
+
+	current, err := db.migrationVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if current < latest {
+		return fmt.Errorf("database version %d is behind the latest migration %d", current, latest)
+	}
+	return nil
+}
+
+// migrationVersion returns the version of the latest applied migration. Unlike goose.GetDBVersion it takes a context
+// and does not create the version table.
+func (db *DB) migrationVersion(ctx context.Context) (int64, error) {
+	rows, err := db.db.QueryContext(ctx, `SELECT version_id, is_applied FROM `+goose.TableName()+` ORDER BY id DESC`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	// the latest row of a version tells whether it is applied or rolled back
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		var applied bool
+		if err := rows.Scan(&version, &applied); err != nil {
+			return 0, err
+		}
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if applied {
+			return version, rows.Err()
+		}
+	}
+	return 0, rows.Err()
+}
+
+// latestMigration returns the version of the latest embedded migration.
+func latestMigration() (int64, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return 0, err
+	}
+	var latest int64
+	for _, e := range entries {
+		v, err := goose.NumericComponent(e.Name())
+		if err != nil {
+			continue
+		}
+		if v > latest {
+			latest = v
+		}
+	}
+	return latest, nil
+}
+
+func (db *DB) setupMigrations() error {
 	if err := goose.SetDialect("postgres"); err != nil {
 		return err
 	}
 
 	goose.SetBaseFS(migrationsFS)
 	goose.SetLogger(db.Logger)
+	return nil
+}
 
-	if err := goose.Up(db.db.DB, "migrations"); err != nil {
-		return err
+// MonitorMetrics periodically updates the database gauges until the DB is closed and exports the statistics of the
+// connection pool. It must only be called for one DB.
+func (db *DB) MonitorMetrics() {
+	if err := prometheus.Register(newPoolStatsCollector(db)); err != nil {
+		db.Logger.Warnf("failed to register connection pool metrics: %v", err)
 	}
-
+	db.monitorDone = make(chan struct{})
 	go db.monitorMetrics()
-	return nil
 }
 
+// Close stops the metrics monitor and closes the connections to the database.
 func (db *DB) Close() error {
 	db.cancel()
+	if db.monitorDone != nil {
+		<-db.monitorDone
+	}
 
 	if db.db != nil {
 		return db.db.Close()
@@ -104,19 +266,38 @@ func (db *DB) Close() error {
 	return nil
 }
 
+// BeginTx begins a transaction. Transactions of a traced context have a span which ends once they are committed or
+// rolled back.
 func (db *DB) BeginTx(ctx context.Context) (*Tx, error) {
+	var span trace.Span
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		ctx, span = db.tracer.Start(ctx, "postgres.Tx", trace.WithSpanKind(trace.SpanKindClient))
+	}
 	tx, err := db.db.BeginTx(ctx, nil)
 	if err != nil {
+		if span != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+		}
 		return nil, err
 	}
 
 	return &Tx{
-		Tx:  tx,
-		db:  db,
-		now: db.Now(),
+		Tx:   tx,
+		db:   db,
+		ctx:  ctx,
+		now:  db.Now(),
+		span: span,
 	}, nil
 }
 
+// logger returns the logger of the request context, so that lines logged for a request can be correlated, or the
+// Logger of the DB.
+func (db *DB) logger(ctx context.Context) todo.Logger {
+	return todo.LoggerFromContext(ctx, db.Logger)
+}
+
 func (db *DB) ping(ctx context.Context) error {
 	pctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	defer cancel()
@@ -148,7 +329,72 @@ func (db *DB) ping(ctx context.Context) error {
 type Tx struct {
 	*sql.Tx
 	db  *DB
+	ctx context.Context
 	now time.Time
+
+	// events are published to the EventService once the transaction commits, or sent with NOTIFY as part of
+	// the transaction when the EventService is the postgres one.
+	events []txEvent
+	// span is ended once the transaction is committed or rolled back.
+	span trace.Span
+}
+
+type txEvent struct {
+	userID int
+	event  todo.Event
+}
+
+// Commit commits the transaction and publishes its events.
+func (tx *Tx) Commit() error {
+	// notifications are delivered once the transaction commits, so they are neither sent for transactions which
+	// fail to commit nor lost after the commit
+	events, notify := tx.db.EventService.(*EventService)
+	if notify && len(tx.events) > 0 {
+		if err := events.notify(tx.ctx, tx, tx.events); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Tx.Commit(); err != nil {
+		tx.end("commit", err)
+		return err
+	}
+	tx.end("commit", nil)
+
+	if !notify {
+		for _, e := range tx.events {
+			tx.db.EventService.PublishEvent(e.userID, e.event)
+		}
+	}
+	return nil
+}
+
+// Rollback aborts the transaction, it is a no-op which returns sql.ErrTxDone once the transaction is committed.
+func (tx *Tx) Rollback() error {
+	err := tx.Tx.Rollback()
+	if !errors.Is(err, sql.ErrTxDone) {
+		tx.end("rollback", err)
+	}
+	return err
+}
+
+// end ends the span of the transaction once.
+func (tx *Tx) end(outcome string, err error) {
+	if tx.span == nil {
+		return
+	}
+	tx.span.SetAttributes(attribute.String("db.transaction.outcome", outcome))
+	if err != nil {
+		tx.span.RecordError(err)
+		tx.span.SetStatus(codes.Error, err.Error())
+	}
+	tx.span.End()
+	tx.span = nil
+}
+
+// publishEvent queues an event to be published once the transaction commits.
+func (tx *Tx) publishEvent(userID int, event todo.Event) {
+	tx.events = append(tx.events, txEvent{userID: userID, event: event})
 }
 
 // Time is a helper type used on time.Time to ensure that records read/written to postgres are
@@ -134,3 +134,40 @@ func env(key string, def string) string {
 	}
 	return def
 }
+
+func TestDB_Migrations(t *testing.T) {
+	db := OpenDB(t)
+
+	migrations, err := db.Migrations(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(migrations) == 0 {
+		t.Fatal("want migrations")
+	}
+	for _, m := range migrations {
+		if m.AppliedAt.IsZero() {
+			t.Fatalf("want migration %s applied", m.Name)
+		}
+	}
+
+	last := migrations[len(migrations)-1]
+	if err := db.MigrateDown(); err != nil {
+		t.Fatal(err)
+	} else if migrations, err = db.Migrations(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if got := migrations[len(migrations)-1]; !got.AppliedAt.IsZero() {
+		t.Fatalf("want migration %s pending", last.Name)
+	} else if err := db.CheckMigrations(context.Background()); err == nil {
+		t.Fatal("want error checking pending migrations")
+	}
+
+	if err := db.Migrate(); err != nil {
+		t.Fatal(err)
+	} else if err := db.MigrateRedo(); err != nil {
+		t.Fatal(err)
+	} else if err := db.CheckMigrations(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := db.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.RateLimitService = (*RateLimitService)(nil)
+
+// rateLimitCleanupInterval is how often expired windows are deleted.
+const rateLimitCleanupInterval = time.Minute
+
+// RateLimitService counts requests in the database, so that all servers enforce one budget per client.
+type RateLimitService struct {
+	db *DB
+
+	mu          sync.Mutex
+	lastCleanup time.Time
+}
+
+func NewRateLimitService(db *DB) *RateLimitService {
+	return &RateLimitService{db: db}
+}
+
+func (svc *RateLimitService) CountRequest(ctx context.Context, key string, window time.Time, length time.Duration) (int, int, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	current, previous, err := countRateLimitedRequest(ctx, tx, key, window, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	if svc.cleanupDue(tx.now) {
+		if err := deleteExpiredRateLimits(ctx, tx); err != nil {
+			return 0, 0, err
+		}
+	}
+	return current, previous, tx.Commit()
+}
+
+// cleanupDue reports whether the expired windows should be deleted, which is done by one request per interval.
+func (svc *RateLimitService) cleanupDue(now time.Time) bool {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	if now.Sub(svc.lastCleanup) < rateLimitCleanupInterval {
+		return false
+	}
+	svc.lastCleanup = now
+	return true
+}
+
+func countRateLimitedRequest(ctx context.Context, tx *Tx, key string, window time.Time, length time.Duration) (current, previous int, err error) {
+	expiresAt, previousWindow := window.Add(2*length), window.Add(-length)
+	err = tx.QueryRowContext(ctx, `
+		WITH counted AS (
+			INSERT INTO rate_limits (key, window_start, count, expires_at)
+			VALUES ($1, $2, 1, $3)
+			ON CONFLICT (key, window_start) DO UPDATE SET count = rate_limits.count + 1
+			RETURNING count
+		)
+		SELECT (SELECT count FROM counted),
+		       COALESCE((SELECT count FROM rate_limits WHERE key = $1 AND window_start = $4), 0)
+	`, key, (*Time)(&window), (*Time)(&expiresAt), (*Time)(&previousWindow)).Scan(&current, &previous)
+	return current, previous, err
+}
+
+func deleteExpiredRateLimits(ctx context.Context, tx *Tx) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM rate_limits WHERE expires_at <= $1`, (*Time)(&tx.now))
+	return err
+}
@@ -0,0 +1,38 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+)
+
+func TestRateLimitService_CountRequest(t *testing.T) {
+	db := OpenDB(t)
+	// servers which share the database share the budget
+	a, b := postgres.NewRateLimitService(db), postgres.NewRateLimitService(db)
+	ctx := context.Background()
+	window := time.Now().UTC().Truncate(time.Minute)
+
+	for i, svc := range []*postgres.RateLimitService{a, b, a} {
+		if current, previous, err := svc.CountRequest(ctx, "ip:127.0.0.1", window.Add(-time.Minute), time.Minute); err != nil {
+			t.Fatal(err)
+		} else if current != i+1 || previous != 0 {
+			t.Fatalf("want %d/0 requests got %d/%d", i+1, current, previous)
+		}
+	}
+
+	if current, previous, err := b.CountRequest(ctx, "ip:127.0.0.1", window, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if current != 1 || previous != 3 {
+		t.Fatalf("want 1/3 requests got %d/%d", current, previous)
+	}
+	if current, previous, err := b.CountRequest(ctx, "user:1", window, time.Minute); err != nil {
+		t.Fatal(err)
+	} else if current != 1 || previous != 0 {
+		t.Fatalf("want 1/0 requests got %d/%d", current, previous)
+	}
+}
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+var _ todo.SyncService = (*SyncService)(nil)
+
+func NewSyncService(db *DB) *SyncService {
+	return &SyncService{db: db}
+}
+
+type SyncService struct {
+	db *DB
+}
+
+func (svc *SyncService) FindChanges(ctx context.Context, since string) (*todo.ChangeSet, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	changes, err := findChanges(ctx, tx, since)
+	if err != nil {
+		return nil, err
+	}
+	return changes, tx.Commit()
+}
+
+func (svc *SyncService) PushMutations(ctx context.Context, mutations []*todo.Mutation) ([]*todo.MutationResult, error) {
+	if _, err := todo.ValidUserFromContext(ctx); err != nil {
+		return nil, err
+	}
+
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	results := make([]*todo.MutationResult, 0, len(mutations))
+	for _, m := range mutations {
+		result, err := pushMutation(ctx, tx, m)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, tx.Commit()
+}
+
+// recordChange appends an entry to the change log for the user. It must be called within the same transaction
+// as the change itself so that the log never diverges from the data.
+func recordChange(ctx context.Context, tx *Tx, userID int, entity string, entityID int, op string) error {
+	// Serialize change log writes per user for the remainder of the transaction. Change IDs are allocated
+	// from a sequence, without the lock a transaction could commit a lower ID after a client has already
+	// synced past it.
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, userID); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+INSERT INTO changes (user_id, entity, entity_id, op, created_at)
+VALUES ($1, $2, $3, $4, $5)`,
+		userID,
+		entity,
+		entityID,
+		op,
+		(*Time)(&tx.now))
+	return err
+}
+
+func findChanges(ctx context.Context, tx *Tx, since string) (*todo.ChangeSet, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := parseSyncToken(since)
+	if err != nil {
+		return nil, err
+	}
+
+	// only the latest change per entity matters to the client
+	rows, err := tx.QueryContext(ctx, `
+	SELECT DISTINCT ON (entity, entity_id)
+		id,
+		entity,
+		entity_id,
+		op,
+		created_at
+	FROM changes
+	WHERE user_id = $1 AND id > $2
+	ORDER BY entity, entity_id, id DESC`, user.ID, after)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	set := &todo.ChangeSet{
+		Lists:      make([]*todo.List, 0),
+		Items:      make([]*todo.Item, 0),
+		Tombstones: make([]*todo.Tombstone, 0),
+	}
+	token := after
+	upserts := map[string]map[int]bool{todo.EntityList: {}, todo.EntityItem: {}}
+	for rows.Next() {
+		var id int64
+		var op string
+		var tombstone todo.Tombstone
+		if err := rows.Scan(
+			&id,
+			&tombstone.Entity,
+			&tombstone.ID,
+			&op,
+			(*Time)(&tombstone.DeletedAt),
+		); err != nil {
+			return nil, err
+		}
+
+		if id > token {
+			token = id
+		}
+
+		if op == todo.OpDelete {
+			set.Tombstones = append(set.Tombstones, &tombstone)
+		} else if ids, ok := upserts[tombstone.Entity]; ok {
+			ids[tombstone.ID] = true
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	set.Token = strconv.FormatInt(token, 10)
+
+	if ids := upserts[todo.EntityList]; len(ids) > 0 {
+		lists, err := findTodoLists(ctx, tx, todo.ListFilter{IDs: changedIDs(ids), UserID: &user.ID})
+		if err != nil {
+			return nil, err
+		}
+		set.Lists = lists
+	}
+
+	if ids := upserts[todo.EntityItem]; len(ids) > 0 {
+		items, err := findTodoItems(ctx, tx, todo.ItemFilter{IDs: changedIDs(ids), UserID: &user.ID})
+		if err != nil {
+			return nil, err
+		}
+		set.Items = items
+	}
+
+	return set, nil
+}
+
+// changedIDs returns the IDs of a set of changed entities.
+func changedIDs(set map[int]bool) []int {
+	ids := make([]int, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func parseSyncToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || v < 0 {
+		return 0, todo.Err(todo.EINVALID, "invalid sync token %q", token)
+	}
+	return v, nil
+}
+
+// pushMutation applies a single mutation inside a savepoint so that a failing mutation does not abort the
+// remainder of the batch. An error is only returned if the transaction itself can no longer be used.
+func pushMutation(ctx context.Context, tx *Tx, m *todo.Mutation) (*todo.MutationResult, error) {
+	if _, err := tx.ExecContext(ctx, `SAVEPOINT mutation`); err != nil {
+		return nil, err
+	}
+	events := len(tx.events)
+
+	result := &todo.MutationResult{ClientID: m.ClientID}
+	if err := applyMutation(ctx, tx, m, result); err != nil {
+		if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT mutation`); err != nil {
+			return nil, err
+		}
+		tx.events = tx.events[:events]
+		if todo.ErrCode(err) == todo.EINTERNAL {
+			tx.db.logger(ctx).Errorf("failed to apply mutation %q: %v", m.ClientID, err)
+		}
+		return &todo.MutationResult{
+			ClientID: m.ClientID,
+			Status:   todo.MutationError,
+			Error:    todo.ErrMessage(err),
+		}, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT mutation`); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func applyMutation(ctx context.Context, tx *Tx, m *todo.Mutation, result *todo.MutationResult) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	if m.Entity == todo.EntityList {
+		return applyListMutation(ctx, tx, m, result)
+	}
+	return applyItemMutation(ctx, tx, m, result)
+}
+
+func applyListMutation(ctx context.Context, tx *Tx, m *todo.Mutation, result *todo.MutationResult) error {
+	if m.Op == todo.OpCreate {
+		list := &todo.List{}
+		if v := m.Name; v != nil {
+			list.Name = *v
+		}
+		if v := m.Completed; v != nil {
+			list.Completed = *v
+		}
+		if err := createTodoList(ctx, tx, list); err != nil {
+			return err
+		}
+		result.Status, result.List = todo.MutationApplied, list
+		return nil
+	}
+
+	list, err := findTodoListByID(ctx, tx, m.ID)
+	if err != nil {
+		return err
+	} else if list.UpdatedAt.After(m.UpdatedAt) {
+		result.Status, result.List = todo.MutationConflict, list
+		return nil
+	}
+
+	if m.Op == todo.OpDelete {
+		if err := deleteTodoList(ctx, tx, m.ID); err != nil {
+			return err
+		}
+		result.Status = todo.MutationApplied
+		return nil
+	}
+
+	if list, err = updateTodoList(ctx, tx, m.ID, todo.ListUpdate{Name: m.Name, Completed: m.Completed}); err != nil {
+		return err
+	}
+	result.Status, result.List = todo.MutationApplied, list
+	return nil
+}
+
+func applyItemMutation(ctx context.Context, tx *Tx, m *todo.Mutation, result *todo.MutationResult) error {
+	if m.Op == todo.OpCreate {
+		item := &todo.Item{ListID: m.ListID}
+		if v := m.Name; v != nil {
+			item.Name = *v
+		}
+		if v := m.Completed; v != nil {
+			item.Completed = *v
+		}
+		if err := createTodoItem(ctx, tx, item); err != nil {
+			return err
+		}
+		result.Status, result.Item = todo.MutationApplied, item
+		return nil
+	}
+
+	item, err := findTodoItem(ctx, tx, m.ID)
+	if err != nil {
+		return err
+	} else if item.UpdatedAt.After(m.UpdatedAt) {
+		result.Status, result.Item = todo.MutationConflict, item
+		return nil
+	}
+
+	if m.Op == todo.OpDelete {
+		if err := deleteTodoItem(ctx, tx, m.ID); err != nil {
+			return err
+		}
+		result.Status = todo.MutationApplied
+		return nil
+	}
+
+	if item, err = updateTodoItem(ctx, tx, m.ID, todo.ItemUpdate{Name: m.Name, Completed: m.Completed}); err != nil {
+		return err
+	}
+	result.Status, result.Item = todo.MutationApplied, item
+	return nil
+}
@@ -0,0 +1,160 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestSyncService(t *testing.T) {
+	t.Parallel()
+
+	createUserAndList := func(t *testing.T, db *postgres.DB) (context.Context, *todo.List) {
+		t.Helper()
+		user := &todo.User{Name: *randstr(10), Password: *randstr(10)}
+		ctx := context.Background()
+		if err := postgres.NewUserService(db).CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		ctx = todo.NewContextWithUser(ctx, user)
+
+		list := &todo.List{Name: *randstr(10)}
+		if err := postgres.NewItemListService(db).CreateList(ctx, list); err != nil {
+			t.Fatal(err)
+		}
+		return ctx, list
+	}
+
+	t.Run("FindChanges", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx, list := createUserAndList(t, db)
+		items := postgres.NewItemListService(db)
+		s := postgres.NewSyncService(db)
+
+		initial, err := s.FindChanges(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(initial.Lists), 1; got != want {
+			t.Fatalf("want %d lists got %d", want, got)
+		}
+
+		item := &todo.Item{ListID: list.ID, Name: *randstr(10)}
+		if err := items.CreateItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := s.FindChanges(ctx, initial.Token)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(changes.Lists), 0; got != want {
+			t.Fatalf("want %d lists got %d", want, got)
+		} else if got, want := len(changes.Items), 1; got != want {
+			t.Fatalf("want %d items got %d", want, got)
+		} else if got, want := changes.Items[0].ID, item.ID; got != want {
+			t.Fatalf("want item id %d got %d", want, got)
+		}
+
+		if err := items.DeleteList(ctx, list.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err = s.FindChanges(ctx, changes.Token)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(changes.Tombstones), 2; got != want {
+			t.Fatalf("want %d tombstones got %d", want, got)
+		}
+
+		changes, err = s.FindChanges(ctx, changes.Token)
+		if err != nil {
+			t.Fatal(err)
+		} else if got := len(changes.Lists) + len(changes.Items) + len(changes.Tombstones); got != 0 {
+			t.Fatalf("want no changes got %d", got)
+		}
+	})
+
+	t.Run("FindChangesOnlyChanged", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx, list := createUserAndList(t, db)
+		items := postgres.NewItemListService(db)
+		s := postgres.NewSyncService(db)
+
+		first, second := &todo.Item{ListID: list.ID, Name: *randstr(10)}, &todo.Item{ListID: list.ID, Name: *randstr(10)}
+		for _, item := range []*todo.Item{first, second} {
+			if err := items.CreateItem(ctx, item); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		initial, err := s.FindChanges(ctx, "")
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(initial.Items), 2; got != want {
+			t.Fatalf("want %d items got %d", want, got)
+		}
+
+		completed := true
+		if _, err := items.UpdateItem(ctx, second.ID, todo.ItemUpdate{Completed: &completed}); err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := s.FindChanges(ctx, initial.Token)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(changes.Lists), 0; got != want {
+			t.Fatalf("want %d lists got %d", want, got)
+		} else if got, want := len(changes.Items), 1; got != want {
+			t.Fatalf("want %d items got %d", want, got)
+		} else if got, want := changes.Items[0].ID, second.ID; got != want {
+			t.Fatalf("want item id %d got %d", want, got)
+		} else if !changes.Items[0].Completed {
+			t.Fatal("want item completed")
+		}
+	})
+
+	t.Run("ErrInvalidToken", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx, _ := createUserAndList(t, db)
+		if _, got := postgres.NewSyncService(db).FindChanges(ctx, "abc"); !errors.Is(got, todo.Invalid) {
+			t.Fatalf("want error %v got %v", todo.Invalid, got)
+		}
+	})
+
+	t.Run("PushMutations", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx, list := createUserAndList(t, db)
+		s := postgres.NewSyncService(db)
+
+		name, renamed := *randstr(10), *randstr(10)
+		results, err := s.PushMutations(ctx, []*todo.Mutation{
+			{ClientID: "1", Entity: todo.EntityItem, Op: todo.OpCreate, ListID: list.ID, Name: &name},
+			{ClientID: "2", Entity: todo.EntityList, Op: todo.OpUpdate, ID: list.ID, Name: &renamed, UpdatedAt: time.Now()},
+			{ClientID: "3", Entity: todo.EntityList, Op: todo.OpUpdate, ID: list.ID, Name: &name, UpdatedAt: list.UpdatedAt.Add(-time.Hour)},
+			{ClientID: "4", Entity: todo.EntityItem, Op: todo.OpDelete, ID: 999999, UpdatedAt: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{todo.MutationApplied, todo.MutationApplied, todo.MutationConflict, todo.MutationError}
+		for i, result := range results {
+			if got := result.Status; got != want[i] {
+				t.Errorf("mutation %s: want status %q got %q", result.ClientID, want[i], got)
+			}
+		}
+
+		if got, err := postgres.NewItemListService(db).FindListByID(ctx, list.ID); err != nil {
+			t.Fatal(err)
+		} else if got.Name != renamed {
+			t.Fatalf("want list name %q got %q", renamed, got.Name)
+		} else if len(got.Items) != 1 {
+			t.Fatalf("want 1 item got %d", len(got.Items))
+		}
+	})
+}
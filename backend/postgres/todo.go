@@ -2,10 +2,15 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/cmokbel1/todo-app/backend/format"
 	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/jackc/pgtype"
 )
 
 var _ todo.ItemListService = (*ItemListService)(nil)
@@ -69,6 +74,14 @@ func findTodoLists(ctx context.Context, tx *Tx, f todo.ListFilter) ([]*todo.List
 		where, args = append(where, fmt.Sprintf("id = $%d", len(where))), append(args, *v)
 	}
 
+	if v := f.IDs; v != nil {
+		var ids pgtype.Int8Array
+		if err := ids.Set(v); err != nil {
+			return nil, err
+		}
+		where, args = append(where, fmt.Sprintf("id = ANY($%d)", len(where))), append(args, ids)
+	}
+
 	if v := f.UserID; v != nil {
 		where, args = append(where, fmt.Sprintf("user_id = $%d", len(where))), append(args, *v)
 	}
@@ -81,6 +94,10 @@ func findTodoLists(ctx context.Context, tx *Tx, f todo.ListFilter) ([]*todo.List
 		where, args = append(where, fmt.Sprintf("completed = $%d", len(where))), append(args, *v)
 	}
 
+	if v := f.AfterID; v > 0 {
+		where, args = append(where, fmt.Sprintf("id > $%d", len(where))), append(args, v)
+	}
+
 	query := `
 	SELECT 
 		id, 
@@ -122,12 +139,23 @@ func findTodoLists(ctx context.Context, tx *Tx, f todo.ListFilter) ([]*todo.List
 		return nil, err
 	}
 
-	for _, list := range lists {
-		items, err := findTodoItems(ctx, tx, todo.ItemFilter{ListID: &list.ID})
-		if err != nil {
-			return nil, err
-		}
-		list.Items = append(list.Items, items...)
+	if f.ExcludeItems || len(lists) == 0 {
+		return lists, nil
+	}
+
+	// the items of all lists are loaded with a single query
+	byID := make(map[int]*todo.List, len(lists))
+	ids := make([]int, len(lists))
+	for i, list := range lists {
+		byID[list.ID], ids[i] = list, list.ID
+	}
+	items, err := findTodoItems(ctx, tx, todo.ItemFilter{ListIDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		list := byID[item.ListID]
+		list.Items = append(list.Items, item)
 	}
 
 	return lists, nil
@@ -153,6 +181,7 @@ func updateTodoList(ctx context.Context, tx *Tx, id int, upd todo.ListUpdate) (*
 		return nil, err
 	}
 
+	prev := *list
 	list.UpdatedAt = tx.now
 	if v := upd.Name; v != nil {
 		list.Name = *v
@@ -171,6 +200,10 @@ func updateTodoList(ctx context.Context, tx *Tx, id int, upd todo.ListUpdate) (*
 		return list, err
 	}
 
+	if err := listChanged(ctx, tx, todo.OpUpdate, &prev, list); err != nil {
+		return list, err
+	}
+
 	return list, nil
 }
 
@@ -218,7 +251,7 @@ RETURNING id`,
 	}
 	list.ID = int(id)
 
-	return nil
+	return listChanged(ctx, tx, todo.OpCreate, nil, list)
 }
 
 func (svc *ItemListService) DeleteList(ctx context.Context, id int) error {
@@ -244,16 +277,114 @@ func deleteTodoList(ctx context.Context, tx *Tx, id int) error {
 		return todo.Err(todo.EINVALID, "invalid id")
 	}
 
-	result, err := tx.ExecContext(ctx, `DELETE FROM lists WHERE id = $1 AND user_id = $2`, id, user.ID)
+	// items are removed by the cascade, collect them first so their deletion is recorded in the change log
+	var itemIDs []int
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM items WHERE list_id = $1 AND user_id = $2`, id, user.ID)
 	if err != nil {
 		return err
-	} else if n, _ := result.RowsAffected(); n == 0 {
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID int
+		if err := rows.Scan(&itemID); err != nil {
+			return err
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	list := &todo.List{ID: id, UserID: user.ID}
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM lists
+	WHERE id = $1 AND user_id = $2
+	RETURNING name, completed, created_at, updated_at`, id, user.ID).Scan(
+		&list.Name,
+		&list.Completed,
+		(*Time)(&list.CreatedAt),
+		(*Time)(&list.UpdatedAt))
+	if errors.Is(err, sql.ErrNoRows) {
 		return todo.Err(todo.ENOTFOUND, "could not delete list with id %v", id)
+	} else if err != nil {
+		return err
+	}
+
+	for _, itemID := range itemIDs {
+		if err := recordChange(ctx, tx, user.ID, todo.EntityItem, itemID, todo.OpDelete); err != nil {
+			return err
+		}
+	}
+
+	return listChanged(ctx, tx, todo.OpDelete, nil, list)
+}
+
+func (svc *ItemListService) ImportLists(ctx context.Context, lists []*todo.List) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
+	if err := importTodoLists(ctx, tx, lists); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func importTodoLists(ctx context.Context, tx *Tx, lists []*todo.List) error {
+	if _, err := todo.ValidUserFromContext(ctx); err != nil {
+		return err
+	}
+	// all the invalid lists and items are reported at once rather than the first one created
+	if err := format.ValidateLists(lists); err != nil {
+		return err
+	}
+
+	for i, list := range lists {
+		items := list.Items
+		createdAt, updatedAt := list.CreatedAt, list.UpdatedAt
+		if err := createTodoList(ctx, tx, list); todo.ErrCode(err) == todo.EINVALID {
+			return todo.Err(todo.EINVALID, "list %d: %s", i+1, todo.ErrMessage(err))
+		} else if err != nil {
+			return err
+		} else if err := restoreTimestamps(ctx, tx, "lists", list.ID, createdAt, updatedAt, &list.CreatedAt, &list.UpdatedAt); err != nil {
+			return err
+		}
+
+		for j, item := range items {
+			item.ListID = list.ID
+			createdAt, updatedAt := item.CreatedAt, item.UpdatedAt
+			if err := createTodoItem(ctx, tx, item); todo.ErrCode(err) == todo.EINVALID {
+				return todo.Err(todo.EINVALID, "list %d item %d: %s", i+1, j+1, todo.ErrMessage(err))
+			} else if err != nil {
+				return err
+			} else if err := restoreTimestamps(ctx, tx, "items", item.ID, createdAt, updatedAt, &item.CreatedAt, &item.UpdatedAt); err != nil {
+				return err
+			}
+			list.Items = append(list.Items, item)
+		}
+	}
 	return nil
 }
 
+// restoreTimestamps overwrites the timestamps of a newly imported row with the non-zero timestamps of the import.
+func restoreTimestamps(ctx context.Context, tx *Tx, table string, id int, createdAt, updatedAt time.Time, dstCreatedAt, dstUpdatedAt *time.Time) error {
+	if createdAt.IsZero() && updatedAt.IsZero() {
+		return nil
+	}
+	if !createdAt.IsZero() {
+		*dstCreatedAt = createdAt
+	}
+	if !updatedAt.IsZero() {
+		*dstUpdatedAt = updatedAt
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE `+table+` SET created_at = $1, updated_at = $2 WHERE id = $3`,
+		(*Time)(dstCreatedAt), (*Time)(dstUpdatedAt), id)
+	return err
+}
+
 func (svc *ItemListService) FindItemByID(ctx context.Context, id int) (*todo.Item, error) {
 	tx, err := svc.db.BeginTx(ctx)
 	if err != nil {
@@ -307,10 +438,26 @@ func findTodoItems(ctx context.Context, tx *Tx, f todo.ItemFilter) ([]*todo.Item
 		where, args = append(where, fmt.Sprintf("id = $%d", len(where))), append(args, *v)
 	}
 
+	if v := f.IDs; v != nil {
+		var ids pgtype.Int8Array
+		if err := ids.Set(v); err != nil {
+			return nil, err
+		}
+		where, args = append(where, fmt.Sprintf("id = ANY($%d)", len(where))), append(args, ids)
+	}
+
 	if v := f.ListID; v != nil {
 		where, args = append(where, fmt.Sprintf("list_id = $%d", len(where))), append(args, *v)
 	}
 
+	if v := f.ListIDs; v != nil {
+		var ids pgtype.Int8Array
+		if err := ids.Set(v); err != nil {
+			return nil, err
+		}
+		where, args = append(where, fmt.Sprintf("list_id = ANY($%d)", len(where))), append(args, ids)
+	}
+
 	if v := f.UserID; v != nil {
 		where, args = append(where, fmt.Sprintf("user_id = $%d", len(where))), append(args, *v)
 	}
@@ -393,6 +540,7 @@ func updateTodoItem(ctx context.Context, tx *Tx, id int, upd todo.ItemUpdate) (*
 		return nil, err
 	}
 
+	prev := *item
 	item.UpdatedAt = tx.now
 	if v := upd.Name; v != nil {
 		item.Name = *v
@@ -415,6 +563,10 @@ func updateTodoItem(ctx context.Context, tx *Tx, id int, upd todo.ItemUpdate) (*
 		return item, err
 	}
 
+	if err := itemChanged(ctx, tx, todo.OpUpdate, &prev, item); err != nil {
+		return item, err
+	}
+
 	return item, nil
 }
 
@@ -461,7 +613,7 @@ RETURNING id`,
 	}
 	item.ID = int(id)
 
-	return nil
+	return itemChanged(ctx, tx, todo.OpCreate, nil, item)
 }
 
 func (svc *ItemListService) DeleteItem(ctx context.Context, id int) error {
@@ -488,12 +640,93 @@ func deleteTodoItem(ctx context.Context, tx *Tx, id int) error {
 		return todo.Err(todo.EINVALID, "invalid id")
 	}
 
-	result, err := tx.ExecContext(ctx, `DELETE FROM items WHERE id = $1 AND user_id = $2`, id, user.ID)
-	if err != nil {
-		return err
-	} else if n, _ := result.RowsAffected(); n == 0 {
+	item := &todo.Item{ID: id, UserID: user.ID}
+	err = tx.QueryRowContext(ctx, `
+	DELETE FROM items
+	WHERE id = $1 AND user_id = $2
+	RETURNING list_id, name, completed, created_at, updated_at`, id, user.ID).Scan(
+		&item.ListID,
+		&item.Name,
+		&item.Completed,
+		(*Time)(&item.CreatedAt),
+		(*Time)(&item.UpdatedAt))
+	if errors.Is(err, sql.ErrNoRows) {
 		return todo.Err(todo.ENOTFOUND, "could not delete item with id %v", id)
+	} else if err != nil {
+		return err
 	}
 
-	return nil
+	return itemChanged(ctx, tx, todo.OpDelete, nil, item)
+}
+
+var (
+	listEventTypes = map[string]string{
+		todo.OpCreate: todo.EventListCreated,
+		todo.OpUpdate: todo.EventListUpdated,
+		todo.OpDelete: todo.EventListDeleted,
+	}
+	itemEventTypes = map[string]string{
+		todo.OpCreate: todo.EventItemCreated,
+		todo.OpUpdate: todo.EventItemUpdated,
+		todo.OpDelete: todo.EventItemDeleted,
+	}
+)
+
+// listChanged records a change to a List in the change log and the activity log, queues the matching event and
+// enqueues the webhook deliveries for it. prev is the state of the List before an update.
+func listChanged(ctx context.Context, tx *Tx, op string, prev, list *todo.List) error {
+	if err := recordChange(ctx, tx, list.UserID, todo.EntityList, list.ID, op); err != nil {
+		return err
+	}
+
+	other := *list
+	other.Items = nil
+	event := todo.Event{Type: listEventTypes[op], List: &other}
+
+	activity := &todo.Activity{UserID: list.UserID, Action: event.Type, Entity: todo.EntityList, EntityID: list.ID, ListID: &list.ID}
+	if err := recordActivity(ctx, tx, activity, activityBefore(op, prev, list), activityAfter(op, list)); err != nil {
+		return err
+	}
+
+	tx.publishEvent(list.UserID, event)
+	return enqueueWebhooks(ctx, tx, list.UserID, event)
+}
+
+// itemChanged records a change to an Item in the change log and the activity log, queues the matching event and
+// enqueues the webhook deliveries for it. prev is the state of the Item before an update.
+func itemChanged(ctx context.Context, tx *Tx, op string, prev, item *todo.Item) error {
+	if err := recordChange(ctx, tx, item.UserID, todo.EntityItem, item.ID, op); err != nil {
+		return err
+	}
+
+	other := *item
+	event := todo.Event{Type: itemEventTypes[op], Item: &other}
+
+	activity := &todo.Activity{UserID: item.UserID, Action: event.Type, Entity: todo.EntityItem, EntityID: item.ID, ListID: &item.ListID}
+	if err := recordActivity(ctx, tx, activity, activityBefore(op, prev, item), activityAfter(op, item)); err != nil {
+		return err
+	}
+
+	tx.publishEvent(item.UserID, event)
+	return enqueueWebhooks(ctx, tx, item.UserID, event)
+}
+
+// activityBefore returns the state of an entity before the op: nothing for a create, prev for an update and the
+// entity itself for a delete.
+func activityBefore(op string, prev, entity interface{}) interface{} {
+	switch op {
+	case todo.OpCreate:
+		return nil
+	case todo.OpDelete:
+		return entity
+	}
+	return prev
+}
+
+// activityAfter returns the state of an entity after the op, which is nothing for a delete.
+func activityAfter(op string, entity interface{}) interface{} {
+	if op == todo.OpDelete {
+		return nil
+	}
+	return entity
 }
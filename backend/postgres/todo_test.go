@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/cmokbel1/todo-app/backend/format"
 	"github.com/cmokbel1/todo-app/backend/postgres"
 	"github.com/cmokbel1/todo-app/backend/todo"
 )
@@ -100,6 +101,46 @@ func TestItemListService(t *testing.T) {
 		}
 	})
 
+	t.Run("FindListsWithItems", func(t *testing.T) {
+		db := OpenDB(t)
+
+		ctx, user, list := createUserAndListWithItems(t, db)
+		s := postgres.NewItemListService(db)
+		other := &todo.List{UserID: user.ID, Name: *randstr(10)}
+		if err := s.CreateList(ctx, other); err != nil {
+			t.Fatal(err)
+		}
+		item := &todo.Item{ListID: other.ID, UserID: user.ID, Name: *randstr(10)}
+		if err := s.CreateItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+		other.Items = []*todo.Item{item}
+
+		if got, err := s.FindLists(ctx, todo.ListFilter{UserID: &user.ID}); err != nil {
+			t.Fatal(err)
+		} else if want := []*todo.List{list, other}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("want lists %v got %v", want, got)
+		}
+
+		if got, err := s.FindLists(ctx, todo.ListFilter{UserID: &user.ID, ExcludeItems: true}); err != nil {
+			t.Fatal(err)
+		} else if len(got) != 2 || len(got[0].Items) != 0 || len(got[1].Items) != 0 {
+			t.Fatalf("want lists without items got %v", got)
+		}
+
+		if got, err := s.FindLists(ctx, todo.ListFilter{UserID: &user.ID, AfterID: list.ID}); err != nil {
+			t.Fatal(err)
+		} else if len(got) != 1 || got[0].ID != other.ID {
+			t.Fatalf("want the lists after %d got %v", list.ID, got)
+		}
+
+		if got, err := s.FindItems(ctx, todo.ItemFilter{ListIDs: []int{list.ID, other.ID}}); err != nil {
+			t.Fatal(err)
+		} else if len(got) != 3 {
+			t.Fatalf("want 3 items got %v", got)
+		}
+	})
+
 	t.Run("DeleteList", func(t *testing.T) {
 		db := OpenDB(t)
 
@@ -320,4 +361,52 @@ func TestItemListService(t *testing.T) {
 			}
 		})
 	})
+
+	t.Run("ImportLists", func(t *testing.T) {
+		db := OpenDB(t)
+
+		t.Run("Success", func(t *testing.T) {
+			ctx, user := createUser(t, db)
+			s := postgres.NewItemListService(db)
+			lists := []*todo.List{
+				{Name: *randstr(10), Items: []*todo.Item{{Name: *randstr(10)}, {Name: *randstr(10), Completed: true}}},
+				{Name: *randstr(10)},
+			}
+			if err := s.ImportLists(ctx, lists); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.FindLists(ctx, todo.ListFilter{UserID: &user.ID})
+			if err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(got, lists) {
+				t.Fatalf("want lists %v got %v", lists, got)
+			}
+		})
+
+		t.Run("ErrInvalidRollsBack", func(t *testing.T) {
+			ctx, user := createUser(t, db)
+			s := postgres.NewItemListService(db)
+			lists := []*todo.List{
+				{Name: *randstr(10)},
+				{Name: *randstr(10), Items: []*todo.Item{{Name: ""}}},
+				{Name: "", Items: []*todo.Item{{Name: *randstr(10)}, {Name: ""}}},
+			}
+			err := s.ImportLists(ctx, lists)
+			if got, want := err, todo.Invalid; !errors.Is(got, want) {
+				t.Fatalf("want error %v got %v", want, got)
+			}
+			// every invalid list and item is reported
+			var rowErrs format.RowErrors
+			if !errors.As(err, &rowErrs) || len(rowErrs) != 3 {
+				t.Fatalf("want 3 row errors got %v", err)
+			}
+
+			if got, err := s.FindLists(ctx, todo.ListFilter{UserID: &user.ID}); err != nil {
+				t.Fatal(err)
+			} else if len(got) != 0 {
+				t.Fatalf("want no lists got %d", len(got))
+			}
+		})
+	})
 }
@@ -0,0 +1,59 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestDB_Tracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	db := postgres.New(OpenDB(t).DSN)
+	db.TracerProvider = tp
+	if err := db.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	ctx, request := tp.Tracer("test").Start(context.Background(), "request")
+	tx, err := db.BeginTx(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT 1`); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Rollback(); err == nil {
+		t.Fatal("want error rolling back a committed transaction")
+	}
+	request.End()
+
+	names := make(map[string]int)
+	for _, span := range recorder.Ended() {
+		names[span.Name()]++
+		if span.Name() != "request" && span.SpanContext().TraceID() != request.SpanContext().TraceID() {
+			t.Fatalf("span %q is not part of the trace of the request", span.Name())
+		}
+	}
+	if names["postgres.Tx"] != 1 || names["postgres Exec"] == 0 {
+		t.Fatalf("unexpected spans %v", names)
+	}
+
+	// transactions and queries without a traced context are not recorded
+	n := len(recorder.Ended())
+	if tx, err := db.BeginTx(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	} else if got := len(recorder.Ended()); got != n {
+		t.Fatalf("want %d spans got %d", n, got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cmokbel1/todo-app/backend/crypto"
 	"github.com/cmokbel1/todo-app/backend/todo"
@@ -44,7 +45,7 @@ func loginUser(ctx context.Context, tx *Tx, user *todo.User) error {
 			return todo.Err(todo.EUNAUTHORIZED, "invalid api key")
 		}
 		*user = *other
-		return nil
+		return userChanged(todo.NewContextWithUser(ctx, user), tx, todo.ActionUserLogin, nil, user)
 	}
 
 	if user.Name == "" || user.Password == "" {
@@ -63,7 +64,7 @@ func loginUser(ctx context.Context, tx *Tx, user *todo.User) error {
 	}
 
 	*user = *other
-	return nil
+	return userChanged(todo.NewContextWithUser(ctx, user), tx, todo.ActionUserLogin, nil, user)
 }
 
 func (svc *UserService) CreateUser(ctx context.Context, user *todo.User) error {
@@ -120,7 +121,11 @@ RETURNING id`,
 	}
 	user.ID = int(id)
 
-	return nil
+	// users register themselves, so the new user is the actor unless someone is already logged in
+	if todo.UserFromContext(ctx) == nil {
+		ctx = todo.NewContextWithUser(ctx, user)
+	}
+	return userChanged(ctx, tx, todo.ActionUserCreated, nil, user)
 }
 
 func (svc *UserService) DeleteUser(ctx context.Context, id int) error {
@@ -209,14 +214,122 @@ func (svc *UserService) FindUserByAPIKey(ctx context.Context, apiKey string) (*t
 	return user, tx.Commit()
 }
 
-func deleteUser(ctx context.Context, tx *Tx, id int) error {
-	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+func (svc *UserService) FindUserByFeedToken(ctx context.Context, token string) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
 	if err != nil {
-		return err
-	} else if n, _ := result.RowsAffected(); n == 0 {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := findUserByFeedToken(ctx, tx, token)
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+func (svc *UserService) ResetFeedToken(ctx context.Context, id int) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := resetFeedToken(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("postgres reset feed token: %w", err)
+	}
+
+	return user, tx.Commit()
+}
+
+func (svc *UserService) ResetPassword(ctx context.Context, id int, password string) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := resetPassword(ctx, tx, id, password)
+	if err != nil {
+		return nil, fmt.Errorf("postgres reset password: %w", err)
+	}
+
+	return user, tx.Commit()
+}
+
+func (svc *UserService) RotateAPIKey(ctx context.Context, id int) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := rotateAPIKey(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("postgres rotate api key: %w", err)
+	}
+
+	return user, tx.Commit()
+}
+
+func (svc *UserService) ScheduleUserDeletion(ctx context.Context, id int, password string, at time.Time) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := scheduleUserDeletion(ctx, tx, id, password, at)
+	if err != nil {
+		return nil, fmt.Errorf("postgres schedule user deletion: %w", err)
+	}
+
+	return user, tx.Commit()
+}
+
+func (svc *UserService) CancelUserDeletion(ctx context.Context, id int) (*todo.User, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := cancelUserDeletion(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("postgres cancel user deletion: %w", err)
+	}
+
+	return user, tx.Commit()
+}
+
+func (svc *UserService) DeleteScheduledUsers(ctx context.Context) (int, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := deleteScheduledUsers(ctx, tx)
+	if err != nil {
+		return 0, fmt.Errorf("postgres delete scheduled users: %w", err)
+	}
+
+	return n, tx.Commit()
+}
+
+func deleteUser(ctx context.Context, tx *Tx, id int) error {
+	user, err := findUserByID(ctx, tx, id)
+	if todo.ErrCode(err) == todo.ENOTFOUND {
 		return todo.Err(todo.ENOTFOUND, "could not delete user with id %v", id)
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return err
 	}
-	return nil
+	return userChanged(ctx, tx, todo.ActionUserDeleted, nil, user)
 }
 
 func updateUser(ctx context.Context, tx *Tx, id int, upd todo.UserUpdate) (*todo.User, error) {
@@ -226,6 +339,7 @@ func updateUser(ctx context.Context, tx *Tx, id int, upd todo.UserUpdate) (*todo
 		return nil, wrap(err)
 	}
 
+	prev := *user
 	user.UpdatedAt = tx.now
 	if v := upd.Email; v != nil {
 		user.Email = v
@@ -245,9 +359,168 @@ func updateUser(ctx context.Context, tx *Tx, id int, upd todo.UserUpdate) (*todo
 		return nil, err
 	}
 
+	if err := userChanged(ctx, tx, todo.ActionUserUpdated, &prev, user); err != nil {
+		return nil, err
+	}
+
 	return user, nil
 }
 
+func resetFeedToken(ctx context.Context, tx *Tx, id int) (*todo.User, error) {
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.FeedToken = crypto.RandomToken()
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET feed_token = $1 WHERE id = $2`, user.FeedToken, id); err != nil {
+		return nil, err
+	}
+
+	if err := userChanged(ctx, tx, todo.ActionUserFeedTokenReset, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func resetPassword(ctx context.Context, tx *Tx, id int, password string) (*todo.User, error) {
+	if password == "" {
+		return nil, todo.Err(todo.EINVALID, "password is required")
+	}
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Password, err = crypto.CreateHash(password); err != nil {
+		return nil, err
+	}
+	user.UpdatedAt = tx.now
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET password = $1, updated_at = $2 WHERE id = $3`,
+		user.Password, (*Time)(&user.UpdatedAt), id); err != nil {
+		return nil, err
+	}
+
+	if err := userChanged(ctx, tx, todo.ActionUserPasswordReset, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func rotateAPIKey(ctx context.Context, tx *Tx, id int) (*todo.User, error) {
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user.APIKey = crypto.RandomString()
+	user.UpdatedAt = tx.now
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET api_key = $1, updated_at = $2 WHERE id = $3`,
+		user.APIKey, (*Time)(&user.UpdatedAt), id); err != nil {
+		return nil, err
+	}
+
+	if err := userChanged(ctx, tx, todo.ActionUserAPIKeyRotated, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func scheduleUserDeletion(ctx context.Context, tx *Tx, id int, password string, at time.Time) (*todo.User, error) {
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if password == "" {
+		return nil, todo.Err(todo.EINVALID, "password required")
+	} else if matches, err := crypto.ComparePasswordAndHash(password, user.Password); err != nil {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "%s", err)
+	} else if !matches {
+		return nil, todo.Err(todo.EUNAUTHORIZED, "password mismatch for user %q", user.Name)
+	}
+
+	user.DeleteAt = &at
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET delete_at = $1 WHERE id = $2`, (*Time)(user.DeleteAt), id); err != nil {
+		return nil, err
+	}
+
+	if err := userChanged(ctx, tx, todo.ActionUserDeletionScheduled, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func cancelUserDeletion(ctx context.Context, tx *Tx, id int) (*todo.User, error) {
+	user, err := findUserByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if user.DeleteAt == nil {
+		return nil, todo.Err(todo.ECONFLICT, "the deletion of user %q is not scheduled", user.Name)
+	}
+
+	user.DeleteAt = nil
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET delete_at = NULL WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	if err := userChanged(ctx, tx, todo.ActionUserDeletionCanceled, nil, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// deleteScheduledUsers deletes all users whose deletion is due. Lists, items, webhooks and exports are deleted by
+// cascade and the activity of the users is purged, see purgeUserActivity.
+func deleteScheduledUsers(ctx context.Context, tx *Tx) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+	SELECT id FROM users WHERE delete_at <= $1 ORDER BY id ASC FOR UPDATE SKIP LOCKED`, (*Time)(&tx.now))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if err := deleteUser(ctx, tx, id); err != nil {
+			return 0, err
+		} else if err := purgeUserActivity(ctx, tx, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
+// purgeUserActivity deletes the activity log of a deleted user, which records their personal data, and removes the
+// actor, IP address and user agent from the entries they caused in the logs of other users. The activity log is
+// immutable otherwise, so purging is only allowed until the end of the statements.
+func purgeUserActivity(ctx context.Context, tx *Tx, id int) error {
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('todo.purge_activity', 'on', true)`); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM activity WHERE user_id = $1`, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+	UPDATE activity SET actor_id = NULL, ip = '', user_agent = '' WHERE actor_id = $1`, id); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('todo.purge_activity', 'off', true)`)
+	return err
+}
+
 func findUserByName(ctx context.Context, tx *Tx, name string) (*todo.User, error) {
 	name = strings.ToLower(name)
 	users, err := findUsers(ctx, tx, todo.UserFilter{Name: &name})
@@ -279,6 +552,16 @@ func findUserByAPIKey(ctx context.Context, tx *Tx, apiKey string) (*todo.User, e
 	return users[0], nil
 }
 
+func findUserByFeedToken(ctx context.Context, tx *Tx, token string) (*todo.User, error) {
+	users, err := findUsers(ctx, tx, todo.UserFilter{FeedToken: &token})
+	if err != nil {
+		return nil, err
+	} else if len(users) == 0 {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find user with feed token")
+	}
+	return users[0], nil
+}
+
 func findUsers(ctx context.Context, tx *Tx, f todo.UserFilter) ([]*todo.User, error) {
 	var args []interface{}
 	where := []string{"1 = 1"}
@@ -301,6 +584,10 @@ func findUsers(ctx context.Context, tx *Tx, f todo.UserFilter) ([]*todo.User, er
 		where, args = append(where, fmt.Sprintf("api_key = $%d", len(where))), append(args, *v)
 	}
 
+	if v := f.FeedToken; v != nil {
+		where, args = append(where, fmt.Sprintf("feed_token = $%d", len(where))), append(args, *v)
+	}
+
 	query := `
 	SELECT 
 		id,
@@ -308,6 +595,8 @@ func findUsers(ctx context.Context, tx *Tx, f todo.UserFilter) ([]*todo.User, er
 		email,
 		password,
 		api_key,
+		COALESCE(feed_token, ''),
+		delete_at,
 		created_at, 
 		updated_at
 	FROM users
@@ -322,17 +611,23 @@ func findUsers(ctx context.Context, tx *Tx, f todo.UserFilter) ([]*todo.User, er
 	var users []*todo.User
 	for rows.Next() {
 		var user todo.User
+		var deleteAt time.Time
 		if err := rows.Scan(
 			&user.ID,
 			&user.Name,
 			&user.Email,
 			&user.Password,
 			&user.APIKey,
+			&user.FeedToken,
+			(*Time)(&deleteAt),
 			(*Time)(&user.CreatedAt),
 			(*Time)(&user.UpdatedAt),
 		); err != nil {
 			return nil, err
 		}
+		if !deleteAt.IsZero() {
+			user.DeleteAt = &deleteAt
+		}
 		users = append(users, &user)
 	}
 
@@ -342,3 +637,19 @@ func findUsers(ctx context.Context, tx *Tx, f todo.UserFilter) ([]*todo.User, er
 
 	return users, nil
 }
+
+// userChanged records a change to a User in the activity log. prev is the state of the User before an update.
+func userChanged(ctx context.Context, tx *Tx, action string, prev, user *todo.User) error {
+	activity := &todo.Activity{UserID: user.ID, Action: action, Entity: todo.EntityUser, EntityID: user.ID}
+
+	var before, after interface{}
+	switch action {
+	case todo.ActionUserCreated:
+		after = user
+	case todo.ActionUserUpdated:
+		before, after = prev, user
+	case todo.ActionUserDeleted:
+		before = user
+	}
+	return recordActivity(ctx, tx, activity, before, after)
+}
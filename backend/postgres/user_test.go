@@ -260,3 +260,127 @@ func TestUserService_FindUsers(t *testing.T) {
 		}
 	})
 }
+
+func TestUserService_ResetFeedToken(t *testing.T) {
+	t.Parallel()
+
+	db := OpenDB(t)
+	s := postgres.NewUserService(db)
+
+	t.Run("Success", func(t *testing.T) {
+		ctx := context.Background()
+		user := newUser()
+		if err := s.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		} else if user.FeedToken != "" {
+			t.Fatalf("want empty feed token got %q", user.FeedToken)
+		}
+
+		first, err := s.ResetFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if first.FeedToken == "" {
+			t.Fatal("want feed token")
+		}
+
+		if other, err := s.FindUserByFeedToken(ctx, first.FeedToken); err != nil {
+			t.Fatal(err)
+		} else if other.ID != user.ID {
+			t.Fatalf("want user %d got %d", user.ID, other.ID)
+		}
+
+		second, err := s.ResetFeedToken(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if second.FeedToken == first.FeedToken {
+			t.Fatal("want a new feed token")
+		}
+
+		// the previous token no longer finds the user
+		if _, err := s.FindUserByFeedToken(ctx, first.FeedToken); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := s.ResetFeedToken(context.Background(), 0); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	t.Parallel()
+
+	db := OpenDB(t)
+	s := postgres.NewUserService(db)
+
+	t.Run("Success", func(t *testing.T) {
+		ctx := context.Background()
+		user := newUser()
+		if err := s.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.ResetPassword(ctx, user.ID, "changed"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := s.LoginUser(ctx, &todo.User{Name: user.Name, Password: "changed"}); err != nil {
+			t.Fatal(err)
+		} else if err := s.LoginUser(ctx, &todo.User{Name: user.Name, Password: user.Name}); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		user := newUser()
+		if err := s.CreateUser(context.Background(), user); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.ResetPassword(context.Background(), user.ID, ""); todo.ErrCode(err) != todo.EINVALID {
+			t.Fatalf("want invalid got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := s.ResetPassword(context.Background(), 0, "changed"); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+}
+
+func TestUserService_RotateAPIKey(t *testing.T) {
+	t.Parallel()
+
+	db := OpenDB(t)
+	s := postgres.NewUserService(db)
+
+	t.Run("Success", func(t *testing.T) {
+		ctx := context.Background()
+		user := newUser()
+		if err := s.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := s.RotateAPIKey(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if rotated.APIKey == "" || rotated.APIKey == user.APIKey {
+			t.Fatalf("want a new api key got %q", rotated.APIKey)
+		}
+
+		if other, err := s.FindUserByAPIKey(ctx, rotated.APIKey); err != nil {
+			t.Fatal(err)
+		} else if other.ID != user.ID {
+			t.Fatalf("want user %d got %d", user.ID, other.ID)
+		}
+		// the previous key no longer finds the user
+		if _, err := s.FindUserByAPIKey(ctx, user.APIKey); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		if _, err := s.RotateAPIKey(context.Background(), 0); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+}
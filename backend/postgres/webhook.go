@@ -0,0 +1,448 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/crypto"
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/jackc/pgtype"
+)
+
+var _ todo.WebhookService = (*WebhookService)(nil)
+
+func NewWebhookService(db *DB) *WebhookService {
+	return &WebhookService{db: db}
+}
+
+type WebhookService struct {
+	db *DB
+}
+
+func (svc *WebhookService) CreateWebhook(ctx context.Context, w *todo.Webhook) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createWebhook(ctx, tx, w); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (svc *WebhookService) DeleteWebhook(ctx context.Context, id int) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteWebhook(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (svc *WebhookService) FindWebhookByID(ctx context.Context, id int) (*todo.Webhook, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	w, err := findWebhookByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return w, tx.Commit()
+}
+
+func (svc *WebhookService) FindWebhooks(ctx context.Context, f todo.WebhookFilter) ([]*todo.Webhook, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	webhooks, err := findWebhooks(ctx, tx, f)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, tx.Commit()
+}
+
+func (svc *WebhookService) FindWebhookDeliveries(ctx context.Context, f todo.WebhookDeliveryFilter) ([]*todo.WebhookDelivery, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deliveries, err := findWebhookDeliveries(ctx, tx, f)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+func (svc *WebhookService) ClaimWebhookDeliveries(ctx context.Context, n int, lease time.Duration) ([]*todo.WebhookDelivery, error) {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	deliveries, err := claimWebhookDeliveries(ctx, tx, n, lease)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, tx.Commit()
+}
+
+func (svc *WebhookService) UpdateWebhookDelivery(ctx context.Context, id int, upd todo.WebhookDeliveryUpdate) error {
+	tx, err := svc.db.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := updateWebhookDelivery(ctx, tx, id, upd); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func createWebhook(ctx context.Context, tx *Tx, w *todo.Webhook) error {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	w.UserID = user.ID
+	w.CreatedAt = tx.now
+	w.UpdatedAt = w.CreatedAt
+	if w.EventTypes == nil {
+		w.EventTypes = make([]string, 0)
+	}
+	if w.Secret == "" {
+		w.Secret = crypto.RandomString()
+	}
+
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	var eventTypes pgtype.TextArray
+	if err := eventTypes.Set(w.EventTypes); err != nil {
+		return err
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+INSERT INTO webhooks (user_id, url, event_types, secret, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id`,
+		w.UserID,
+		w.URL,
+		eventTypes,
+		w.Secret,
+		(*Time)(&w.CreatedAt),
+		(*Time)(&w.UpdatedAt)).Scan(&id)
+	if err != nil {
+		return err
+	}
+	w.ID = int(id)
+
+	return nil
+}
+
+func deleteWebhook(ctx context.Context, tx *Tx, id int) error {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, user.ID)
+	if err != nil {
+		return err
+	} else if n, _ := result.RowsAffected(); n == 0 {
+		return todo.Err(todo.ENOTFOUND, "could not delete webhook with id %v", id)
+	}
+	return nil
+}
+
+func findWebhookByID(ctx context.Context, tx *Tx, id int) (*todo.Webhook, error) {
+	webhooks, err := findWebhooks(ctx, tx, todo.WebhookFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(webhooks) == 0 {
+		return nil, todo.Err(todo.ENOTFOUND, "could not find webhook with id %d", id)
+	}
+	return webhooks[0], nil
+}
+
+func findWebhooks(ctx context.Context, tx *Tx, f todo.WebhookFilter) ([]*todo.Webhook, error) {
+	user, err := todo.ValidUserFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var args []interface{}
+	where := []string{"1 = 1"}
+	if v := f.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", len(where))), append(args, *v)
+	}
+
+	if v := f.UserID; v != nil {
+		where, args = append(where, fmt.Sprintf("user_id = $%d", len(where))), append(args, *v)
+	}
+
+	query := `
+	SELECT
+		id,
+		user_id,
+		url,
+		event_types,
+		secret,
+		created_at,
+		updated_at
+	FROM webhooks
+	WHERE ` + strings.Join(where, " AND ") + `
+	ORDER BY id ASC ` + FormatLimitOffset(f.Limit, f.Offset)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*todo.Webhook, 0)
+	for rows.Next() {
+		var w todo.Webhook
+		var eventTypes pgtype.TextArray
+		if err := rows.Scan(
+			&w.ID,
+			&w.UserID,
+			&w.URL,
+			&eventTypes,
+			&w.Secret,
+			(*Time)(&w.CreatedAt),
+			(*Time)(&w.UpdatedAt),
+		); err != nil {
+			return nil, err
+		} else if err := eventTypes.AssignTo(&w.EventTypes); err != nil {
+			return nil, err
+		}
+
+		if w.UserID != user.ID {
+			return nil, todo.Unauthorized
+		}
+		webhooks = append(webhooks, &w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func findWebhookDeliveries(ctx context.Context, tx *Tx, f todo.WebhookDeliveryFilter) ([]*todo.WebhookDelivery, error) {
+	if f.WebhookID == nil {
+		return nil, todo.Err(todo.EINVALID, "webhook id required")
+	}
+
+	// ensures the webhook belongs to the current user
+	if _, err := findWebhookByID(ctx, tx, *f.WebhookID); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{*f.WebhookID}
+	where := []string{"1 = 1", "webhook_id = $1"}
+	if v := f.Status; v != nil {
+		where, args = append(where, fmt.Sprintf("status = $%d", len(where))), append(args, *v)
+	}
+
+	query := `
+	SELECT
+		id,
+		webhook_id,
+		event_type,
+		payload,
+		status,
+		attempts,
+		COALESCE(response_status, 0),
+		COALESCE(last_error, ''),
+		next_attempt_at,
+		created_at,
+		updated_at
+	FROM webhook_deliveries
+	WHERE ` + strings.Join(where, " AND ") + `
+	ORDER BY id DESC ` + FormatLimitOffset(f.Limit, f.Offset)
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*todo.WebhookDelivery, 0)
+	for rows.Next() {
+		var d todo.WebhookDelivery
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.EventType,
+			&payload,
+			&d.Status,
+			&d.Attempts,
+			&d.ResponseStatus,
+			&d.LastError,
+			(*Time)(&d.NextAttemptAt),
+			(*Time)(&d.CreatedAt),
+			(*Time)(&d.UpdatedAt),
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func claimWebhookDeliveries(ctx context.Context, tx *Tx, n int, lease time.Duration) ([]*todo.WebhookDelivery, error) {
+	leasedUntil := tx.now.Add(lease)
+	rows, err := tx.QueryContext(ctx, `
+	UPDATE webhook_deliveries d
+	SET next_attempt_at = $2
+	FROM webhooks w
+	WHERE d.webhook_id = w.id AND d.id IN (
+		SELECT id
+		FROM webhook_deliveries
+		WHERE status = 'pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at ASC
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING
+		d.id,
+		d.webhook_id,
+		d.event_type,
+		d.payload,
+		d.status,
+		d.attempts,
+		COALESCE(d.response_status, 0),
+		COALESCE(d.last_error, ''),
+		d.next_attempt_at,
+		d.created_at,
+		d.updated_at,
+		w.user_id,
+		w.url,
+		w.secret`,
+		(*Time)(&tx.now), (*Time)(&leasedUntil), n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*todo.WebhookDelivery, 0)
+	for rows.Next() {
+		d := todo.WebhookDelivery{Webhook: &todo.Webhook{}}
+		var payload []byte
+		if err := rows.Scan(
+			&d.ID,
+			&d.WebhookID,
+			&d.EventType,
+			&payload,
+			&d.Status,
+			&d.Attempts,
+			&d.ResponseStatus,
+			&d.LastError,
+			(*Time)(&d.NextAttemptAt),
+			(*Time)(&d.CreatedAt),
+			(*Time)(&d.UpdatedAt),
+			&d.Webhook.UserID,
+			&d.Webhook.URL,
+			&d.Webhook.Secret,
+		); err != nil {
+			return nil, err
+		}
+		d.Payload = payload
+		d.Webhook.ID = d.WebhookID
+		deliveries = append(deliveries, &d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func updateWebhookDelivery(ctx context.Context, tx *Tx, id int, upd todo.WebhookDeliveryUpdate) error {
+	switch upd.Status {
+	case todo.DeliveryPending, todo.DeliveryDelivered, todo.DeliveryDead:
+	default:
+		return todo.Err(todo.EINVALID, "invalid delivery status %q", upd.Status)
+	}
+
+	var responseStatus, lastError interface{}
+	if upd.ResponseStatus != 0 {
+		responseStatus = upd.ResponseStatus
+	}
+	if upd.Error != "" {
+		lastError = upd.Error
+	}
+
+	nextAttemptAt := upd.NextAttemptAt
+	if nextAttemptAt.IsZero() {
+		nextAttemptAt = tx.now
+	}
+
+	result, err := tx.ExecContext(ctx, `
+	UPDATE webhook_deliveries
+	SET status = $1,
+		attempts = attempts + 1,
+		response_status = $2,
+		last_error = $3,
+		next_attempt_at = $4,
+		updated_at = $5
+	WHERE id = $6`,
+		upd.Status, responseStatus, lastError, (*Time)(&nextAttemptAt), (*Time)(&tx.now), id)
+	if err != nil {
+		return err
+	} else if n, _ := result.RowsAffected(); n == 0 {
+		return todo.Err(todo.ENOTFOUND, "could not find webhook delivery with id %d", id)
+	}
+	return nil
+}
+
+// enqueueWebhooks adds the event to the outbox of every webhook of the user subscribed to its type.
+func enqueueWebhooks(ctx context.Context, tx *Tx, userID int, event todo.Event) error {
+	payload, err := json.Marshal(todo.WebhookPayload{
+		Type:      event.Type,
+		Timestamp: tx.now,
+		List:      event.List,
+		Item:      event.Item,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+	INSERT INTO webhook_deliveries (webhook_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at)
+	SELECT id, $2, $3, 'pending', 0, $4, $4, $4
+	FROM webhooks
+	WHERE user_id = $1 AND (cardinality(event_types) = 0 OR $2 = ANY(event_types))`,
+		userID, event.Type, string(payload), (*Time)(&tx.now))
+	return err
+}
@@ -0,0 +1,159 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/postgres"
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestWebhookService(t *testing.T) {
+	t.Parallel()
+
+	createUser := func(t *testing.T, db *postgres.DB) context.Context {
+		t.Helper()
+		user := &todo.User{Name: *randstr(10), Password: *randstr(10)}
+		ctx := context.Background()
+		if err := postgres.NewUserService(db).CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		return todo.NewContextWithUser(ctx, user)
+	}
+
+	t.Run("CreateWebhook", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := createUser(t, db)
+		s := postgres.NewWebhookService(db)
+
+		w := &todo.Webhook{URL: "https://example.com/hook"}
+		if err := s.CreateWebhook(ctx, w); err != nil {
+			t.Fatal(err)
+		} else if w.ID == 0 {
+			t.Fatal("want webhook id")
+		} else if w.Secret == "" {
+			t.Fatal("want generated secret")
+		}
+
+		got, err := s.FindWebhookByID(ctx, w.ID)
+		if err != nil {
+			t.Fatal(err)
+		} else if got.URL != w.URL || got.Secret != w.Secret || len(got.EventTypes) != 0 {
+			t.Fatalf("want %+v got %+v", w, got)
+		}
+
+		// other users cannot see the webhook
+		other := createUser(t, db)
+		if _, err := s.FindWebhookByID(other, w.ID); todo.ErrCode(err) != todo.EUNAUTHORIZED {
+			t.Fatalf("want unauthorized got %v", err)
+		} else if err := s.DeleteWebhook(other, w.ID); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+
+	t.Run("ErrInvalid", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := createUser(t, db)
+		s := postgres.NewWebhookService(db)
+
+		for _, w := range []*todo.Webhook{
+			{URL: ""},
+			{URL: "/relative"},
+			{URL: "ftp://example.com"},
+			{URL: "http://localhost:8080/api/users"},
+			{URL: "http://169.254.169.254/latest/meta-data"},
+			{URL: "http://[::ffff:10.0.0.1]/"},
+			{URL: "https://example.com", EventTypes: []string{"unknown"}},
+		} {
+			if err := s.CreateWebhook(ctx, w); todo.ErrCode(err) != todo.EINVALID {
+				t.Fatalf("%+v: want invalid got %v", w, err)
+			}
+		}
+	})
+
+	t.Run("Deliveries", func(t *testing.T) {
+		db := OpenDB(t)
+		ctx := createUser(t, db)
+		s := postgres.NewWebhookService(db)
+
+		all := &todo.Webhook{URL: "https://example.com/all"}
+		if err := s.CreateWebhook(ctx, all); err != nil {
+			t.Fatal(err)
+		}
+		items := &todo.Webhook{URL: "https://example.com/items", EventTypes: []string{todo.EventItemCreated}}
+		if err := s.CreateWebhook(ctx, items); err != nil {
+			t.Fatal(err)
+		}
+
+		list := &todo.List{Name: *randstr(10)}
+		if err := postgres.NewItemListService(db).CreateList(ctx, list); err != nil {
+			t.Fatal(err)
+		}
+		item := &todo.Item{ListID: list.ID, Name: *randstr(10)}
+		if err := postgres.NewItemListService(db).CreateItem(ctx, item); err != nil {
+			t.Fatal(err)
+		}
+
+		if deliveries, err := s.FindWebhookDeliveries(ctx, todo.WebhookDeliveryFilter{WebhookID: &all.ID}); err != nil {
+			t.Fatal(err)
+		} else if got, want := len(deliveries), 2; got != want {
+			t.Fatalf("want %d deliveries got %d", want, got)
+		}
+
+		deliveries, err := s.FindWebhookDeliveries(ctx, todo.WebhookDeliveryFilter{WebhookID: &items.ID})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(deliveries), 1; got != want {
+			t.Fatalf("want %d deliveries got %d", want, got)
+		}
+
+		var payload todo.WebhookPayload
+		if err := json.Unmarshal(deliveries[0].Payload, &payload); err != nil {
+			t.Fatal(err)
+		} else if payload.Type != todo.EventItemCreated || payload.Item == nil || payload.Item.ID != item.ID {
+			t.Fatalf("unexpected payload %s", deliveries[0].Payload)
+		}
+
+		claimed, err := s.ClaimWebhookDeliveries(context.Background(), 10, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(claimed), 3; got != want {
+			t.Fatalf("want %d claimed deliveries got %d", want, got)
+		} else if claimed[0].Webhook == nil || claimed[0].Webhook.Secret == "" {
+			t.Fatal("want claimed delivery to include its webhook")
+		}
+
+		// leased deliveries are not claimed again
+		if again, err := s.ClaimWebhookDeliveries(context.Background(), 10, time.Minute); err != nil {
+			t.Fatal(err)
+		} else if len(again) != 0 {
+			t.Fatalf("want no claimed deliveries got %d", len(again))
+		}
+
+		upd := todo.WebhookDeliveryUpdate{Status: todo.DeliveryDelivered, ResponseStatus: 200}
+		if err := s.UpdateWebhookDelivery(context.Background(), deliveries[0].ID, upd); err != nil {
+			t.Fatal(err)
+		}
+
+		status := todo.DeliveryDelivered
+		delivered, err := s.FindWebhookDeliveries(ctx, todo.WebhookDeliveryFilter{WebhookID: &items.ID, Status: &status})
+		if err != nil {
+			t.Fatal(err)
+		} else if got, want := len(delivered), 1; got != want {
+			t.Fatalf("want %d delivered got %d", want, got)
+		} else if delivered[0].Attempts != 1 || delivered[0].ResponseStatus != 200 {
+			t.Fatalf("unexpected delivery %+v", delivered[0])
+		}
+
+		// deleting the webhook deletes its deliveries
+		if err := s.DeleteWebhook(ctx, items.ID); err != nil {
+			t.Fatal(err)
+		} else if _, err := s.FindWebhookDeliveries(ctx, todo.WebhookDeliveryFilter{WebhookID: &items.ID}); todo.ErrCode(err) != todo.ENOTFOUND {
+			t.Fatalf("want not found got %v", err)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// Account export statuses.
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	// ExportDownloaded marks an export whose archive was downloaded and discarded.
+	ExportDownloaded = "downloaded"
+	ExportFailed     = "failed"
+)
+
+// AccountExport is an archive of all data belonging to a User. It is built in the background and can be downloaded
+// once.
+type AccountExport struct {
+	ID     int `json:"id"`
+	UserID int `json:"userId"`
+	// Status is one of ExportPending, ExportReady, ExportDownloaded or ExportFailed.
+	Status string `json:"status"`
+	// Error describes why a failed export could not be built.
+	Error string `json:"error,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// AccountExportUpdate records the outcome of building an export.
+type AccountExportUpdate struct {
+	Status string
+	// Archive is the ZIP archive of a ready export.
+	Archive []byte
+	Error   string
+}
+
+// AccountExportService manages the exports of account data.
+type AccountExportService interface {
+	// CreateAccountExport queues an export of the current user's data.
+	// Errors returned:
+	//	conflict: an export of the current user is already pending
+	//	unauthorized: no user was found in the context
+	CreateAccountExport(ctx context.Context) (*AccountExport, error)
+	// FindAccountExportByID returns the AccountExport with the matching ID.
+	// Errors returned:
+	//	not_found: no matching AccountExport was found
+	//	unauthorized: the AccountExport belongs to another user
+	FindAccountExportByID(ctx context.Context, id int) (*AccountExport, error)
+	// DownloadAccountExport returns the archive of a ready export. The archive is kept until it was sent and
+	// MarkAccountExportDownloaded discards it, so that a failed download can be retried.
+	// Errors returned:
+	//	conflict: the export is not ready or was already downloaded
+	//	not_found: no matching AccountExport was found
+	//	unauthorized: the AccountExport belongs to another user
+	DownloadAccountExport(ctx context.Context, id int) ([]byte, error)
+	// MarkAccountExportDownloaded discards the archive of a downloaded export, so that it can only be downloaded
+	// once.
+	// Errors returned:
+	//	conflict: the export is not ready or was already downloaded
+	//	not_found: no matching AccountExport was found
+	//	unauthorized: the AccountExport belongs to another user
+	MarkAccountExportDownloaded(ctx context.Context, id int) error
+
+	// ClaimAccountExports returns up to n pending exports and leases them for the given duration so that other
+	// workers skip them. It is not scoped to a user and is intended for the background worker.
+	ClaimAccountExports(ctx context.Context, n int, lease time.Duration) ([]*AccountExport, error)
+	// UpdateAccountExport records the outcome of building an export.
+	UpdateAccountExport(ctx context.Context, id int, upd AccountExportUpdate) error
+}
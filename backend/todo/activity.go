@@ -0,0 +1,81 @@
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Activity actions recorded by the ItemListService and UserService. List and item actions match their event
+// types.
+const (
+	ActionListCreated = EventListCreated
+	ActionListUpdated = EventListUpdated
+	ActionListDeleted = EventListDeleted
+	ActionItemCreated = EventItemCreated
+	ActionItemUpdated = EventItemUpdated
+	ActionItemDeleted = EventItemDeleted
+	ActionUserCreated = "user.created"
+	ActionUserUpdated = "user.updated"
+	ActionUserDeleted = "user.deleted"
+	ActionUserLogin   = "user.login"
+	// ActionUserFeedTokenReset is recorded when the calendar feed token of a user is replaced.
+	ActionUserFeedTokenReset = "user.feed_token_reset"
+	// ActionUserDeletionScheduled and ActionUserDeletionCanceled are recorded when a user requests or cancels the
+	// deletion of their account.
+	ActionUserDeletionScheduled = "user.deletion_scheduled"
+	ActionUserDeletionCanceled  = "user.deletion_canceled"
+	// ActionUserPasswordReset and ActionUserAPIKeyRotated are recorded when an operator replaces the password or
+	// the API key of a user.
+	ActionUserPasswordReset = "user.password_reset"
+	ActionUserAPIKeyRotated = "user.api_key_rotated"
+)
+
+// EntityUser identifies a User in the activity log.
+const EntityUser = "user"
+
+// Activity is an immutable entry in the activity log describing a single mutation.
+type Activity struct {
+	ID int `json:"id"`
+	// UserID is the account which owns the changed entity.
+	UserID int `json:"userId"`
+	// ActorID is the user which made the change. It is empty for changes made with the server API key.
+	ActorID  *int   `json:"actorId,omitempty"`
+	Action   string `json:"action"`
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entityId"`
+	// ListID is the list the entity belongs to, if any.
+	ListID *int `json:"listId,omitempty"`
+	// Before and After contain the fields which were changed by the mutation with their previous and new values.
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"userAgent,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ActivityFilter struct {
+	// Filter fields
+	UserID *int       `json:"userId"`
+	ListID *int       `json:"listId"`
+	Action *string    `json:"action"`
+	Since  *time.Time `json:"since"`
+	Until  *time.Time `json:"until"`
+
+	// Range restrictions
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// ActivityService reads the activity log. Entries are written by the services performing the mutations.
+type ActivityService interface {
+	// FindListActivity returns the activity of a List belonging to the current user, newest first.
+	// Errors returned:
+	//	not_found: no matching List was found
+	//	unauthorized: the List belongs to another user
+	FindListActivity(ctx context.Context, listID int, f ActivityFilter) ([]*Activity, error)
+	// FindActivity returns the activity of all users matching the filter, newest first. It is intended for
+	// administrators and is not scoped to the current user.
+	FindActivity(ctx context.Context, f ActivityFilter) ([]*Activity, error)
+}
@@ -0,0 +1,32 @@
+package todo
+
+import "context"
+
+// CalendarObject is the name and UID under which a CalDAV client created an item. Clients keep addressing the item by
+// them, so they are kept for as long as the item exists.
+type CalendarObject struct {
+	ItemID int `json:"itemId"`
+	ListID int `json:"listId"`
+	// Name is the last segment of the path of the resource, like "<uuid>.ics".
+	Name string `json:"name"`
+	// UID is the UID of the VTODO.
+	UID string `json:"uid"`
+}
+
+func (o *CalendarObject) Validate() error {
+	if o.Name == "" {
+		return Err(EINVALID, "name required")
+	} else if o.UID == "" {
+		return Err(EINVALID, "uid required")
+	}
+	return nil
+}
+
+// CalendarObjectService keeps the names and UIDs of the items which CalDAV clients create.
+type CalendarObjectService interface {
+	// FindCalendarObjects returns the objects of the items of a list of the current user.
+	FindCalendarObjects(ctx context.Context, listID int) ([]*CalendarObject, error)
+	// CreateCalendarObject creates the item and records the name and UID of the object the client created it as. It
+	// fails with ECONFLICT if the list of the item already has an object of the name or UID.
+	CreateCalendarObject(ctx context.Context, item *Item, obj *CalendarObject) error
+}
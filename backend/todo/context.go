@@ -7,8 +7,14 @@ import "context"
 // interfering with our context keys.
 type contextKey int
 
-// userContextKey stores the current logged-in user in the context.
-const userContextKey = contextKey(iota + 1)
+const (
+	// userContextKey stores the current logged-in user in the context.
+	userContextKey = contextKey(iota + 1)
+	// requestMetadataContextKey stores the RequestMetadata of the current request in the context.
+	requestMetadataContextKey
+	// loggerContextKey stores the Logger of the current request in the context.
+	loggerContextKey
+)
 
 // NewContextWithUser returns a new context with the given user.
 func NewContextWithUser(ctx context.Context, user *User) context.Context {
@@ -39,3 +45,34 @@ func ValidUserFromContext(ctx context.Context) (*User, error) {
 
 	return user, nil
 }
+
+// RequestMetadata describes the client of the request which triggered a change.
+type RequestMetadata struct {
+	IP        string
+	UserAgent string
+}
+
+// NewContextWithRequestMetadata returns a new context with the given request metadata.
+func NewContextWithRequestMetadata(ctx context.Context, md RequestMetadata) context.Context {
+	return context.WithValue(ctx, requestMetadataContextKey, md)
+}
+
+// RequestMetadataFromContext returns the metadata of the current request, if any.
+func RequestMetadataFromContext(ctx context.Context) RequestMetadata {
+	md, _ := ctx.Value(requestMetadataContextKey).(RequestMetadata)
+	return md
+}
+
+// NewContextWithLogger returns a new context with the given logger.
+func NewContextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey, logger)
+}
+
+// LoggerFromContext returns the logger of the current request, which adds the fields of the request to every line,
+// or fallback if there is none.
+func LoggerFromContext(ctx context.Context, fallback Logger) Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(Logger); ok {
+		return logger
+	}
+	return fallback
+}
@@ -10,8 +10,10 @@ const (
 	ENOTFOUND     = "NOT_FOUND"
 	EINVALID      = "INVALID"
 	EUNAUTHORIZED = "UNAUTHORIZED"
+	EFORBIDDEN    = "FORBIDDEN"
 	ECONFLICT     = "CONFLICT"
 	EINTERNAL     = "INTERNAL"
+	ERATELIMITED  = "RATE_LIMITED"
 )
 
 // the following errors are intended to be used as sentinel values to determine error likeness
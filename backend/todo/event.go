@@ -0,0 +1,54 @@
+package todo
+
+import "context"
+
+// Event types published when Lists and Items change.
+const (
+	EventListCreated = "list.created"
+	EventListUpdated = "list.updated"
+	EventListDeleted = "list.deleted"
+	EventItemCreated = "item.created"
+	EventItemUpdated = "item.updated"
+	EventItemDeleted = "item.deleted"
+)
+
+// Event represents a change to a List or Item that is pushed to subscribers.
+type Event struct {
+	// Type is one of the Event* constants.
+	Type string `json:"type"`
+	// List is set for list events. Items are never included.
+	List *List `json:"list,omitempty"`
+	// Item is set for item events.
+	Item *Item `json:"item,omitempty"`
+}
+
+// EventService publishes events to subscribers.
+type EventService interface {
+	// PublishEvent publishes an event to all subscriptions belonging to the user.
+	PublishEvent(userID int, event Event)
+	// Subscribe creates a subscription for the events of the current user. The caller must close the
+	// Subscription when done.
+	// Errors returned:
+	//	unauthorized: no user was found in the context
+	Subscribe(ctx context.Context) (Subscription, error)
+}
+
+// Subscription represents a stream of events for a single user.
+type Subscription interface {
+	// C returns a channel of events. The channel is closed when the Subscription is closed or when the
+	// subscriber falls too far behind.
+	C() <-chan Event
+	// Close unsubscribes and closes the event channel.
+	Close() error
+}
+
+// NopEventService returns an EventService which drops published events and never delivers any.
+func NopEventService() EventService { return &nopEventService{} }
+
+type nopEventService struct{}
+
+func (*nopEventService) PublishEvent(userID int, event Event) {}
+
+func (*nopEventService) Subscribe(ctx context.Context) (Subscription, error) {
+	return nil, NotImplemented
+}
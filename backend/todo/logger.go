@@ -1,11 +1,18 @@
 package todo
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Logger represents a leveled logger
@@ -33,7 +40,7 @@ type Logger interface {
 	SetPrefix(prefix string)
 	Writer() io.Writer
 
-	// Fatal, Fatalf, Fatalln, Panic, Panicf, and Panicln are not implemented.
+	// Fatal, Fatalf and Fatalln exit after logging, Panic, Panicf and Panicln panic after logging.
 	Fatal(v ...interface{})
 	Fatalf(format string, v ...interface{})
 	Fatalln(v ...interface{})
@@ -155,17 +162,38 @@ func (l *DefaultLogger) Println(v ...interface{}) {
 	}
 }
 
-func (l *DefaultLogger) Fatal(v ...interface{}) {}
+func (l *DefaultLogger) Fatal(v ...interface{}) {
+	l.Error(fmt.Sprint(v...))
+	os.Exit(1)
+}
 
-func (l *DefaultLogger) Fatalf(format string, v ...interface{}) {}
+func (l *DefaultLogger) Fatalf(format string, v ...interface{}) {
+	l.Error(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
 
-func (l *DefaultLogger) Fatalln(v ...interface{}) {}
+func (l *DefaultLogger) Fatalln(v ...interface{}) {
+	l.Error(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	os.Exit(1)
+}
 
-func (l *DefaultLogger) Panic(v ...interface{}) {}
+func (l *DefaultLogger) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	l.Error(msg)
+	panic(msg)
+}
 
-func (l *DefaultLogger) Panicf(format string, v ...interface{}) {}
+func (l *DefaultLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.Error(msg)
+	panic(msg)
+}
 
-func (l *DefaultLogger) Panicln(v ...interface{}) {}
+func (l *DefaultLogger) Panicln(v ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	l.Error(msg)
+	panic(msg)
+}
 
 func (l *DefaultLogger) Flags() int { return l.Logger.Flags() }
 
@@ -176,3 +204,236 @@ func (l *DefaultLogger) Prefix() string { return l.Logger.Prefix() }
 func (l *DefaultLogger) SetPrefix(prefix string) { l.Logger.SetPrefix(prefix) }
 
 func (l *DefaultLogger) Writer() io.Writer { return l.Logger.Writer() }
+
+// Fields are key-value pairs which are added to every line logged by a logger, see LoggerWithFields.
+type Fields map[string]interface{}
+
+// LoggerWithFields returns a logger which adds the fields to every line if l supports fields like StructuredLogger
+// does, otherwise l is returned as is.
+func LoggerWithFields(l Logger, fields Fields) Logger {
+	if fl, ok := l.(interface{ WithFields(Fields) Logger }); ok {
+		return fl.WithFields(fields)
+	}
+	return l
+}
+
+const (
+	// LogFormatText is the format of the DefaultLogger.
+	LogFormatText = "text"
+	// LogFormatJSON writes every line as a JSON object.
+	LogFormatJSON = "json"
+	// LogFormatLogfmt writes every line as logfmt key=value pairs.
+	LogFormatLogfmt = "logfmt"
+)
+
+// NewStructuredLogger creates a StructuredLogger at LogLevelInfo which writes lines of the format, either
+// LogFormatJSON or LogFormatLogfmt, to w.
+func NewStructuredLogger(w io.Writer, format string) *StructuredLogger {
+	return &StructuredLogger{
+		out:      &structuredOutput{w: w},
+		format:   format,
+		minLevel: LogLevelInfo,
+	}
+}
+
+// StructuredLogger is a leveled Logger which writes every line with its time, level, message and the fields of the
+// logger as JSON or logfmt.
+type StructuredLogger struct {
+	out      *structuredOutput
+	format   string
+	minLevel LogLevel
+	prefix   string
+	flags    int
+	fields   Fields
+}
+
+// structuredOutput is shared by a StructuredLogger and the loggers derived from it with WithFields.
+type structuredOutput struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WithFields returns a logger which writes to the same output with the fields of l and the given fields.
+func (l *StructuredLogger) WithFields(fields Fields) Logger {
+	other := *l
+	other.fields = make(Fields, len(l.fields)+len(fields))
+	for k, v := range l.fields {
+		other.fields[k] = v
+	}
+	for k, v := range fields {
+		other.fields[k] = v
+	}
+	return &other
+}
+
+func (l *StructuredLogger) log(level LogLevel, msg string) {
+	if level < l.minLevel {
+		return
+	}
+
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	if l.format == LogFormatJSON {
+		buf.WriteByte('{')
+		writeJSONField(&buf, "time", time.Now().UTC().Format(time.RFC3339Nano))
+		buf.WriteByte(',')
+		writeJSONField(&buf, "level", level.String())
+		buf.WriteByte(',')
+		writeJSONField(&buf, "msg", l.prefix+msg)
+		for _, k := range keys {
+			buf.WriteByte(',')
+			writeJSONField(&buf, k, l.fields[k])
+		}
+		buf.WriteString("}\n")
+	} else {
+		writeLogfmtField(&buf, "time", time.Now().UTC().Format(time.RFC3339Nano))
+		buf.WriteByte(' ')
+		writeLogfmtField(&buf, "level", level.String())
+		buf.WriteByte(' ')
+		writeLogfmtField(&buf, "msg", l.prefix+msg)
+		for _, k := range keys {
+			buf.WriteByte(' ')
+			writeLogfmtField(&buf, k, l.fields[k])
+		}
+		buf.WriteByte('\n')
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+}
+
+func writeLogfmtField(buf *bytes.Buffer, key string, value interface{}) {
+	v := fmt.Sprint(value)
+	if v == "" || strings.ContainsAny(v, " =\"\t\r\n") {
+		v = strconv.Quote(v)
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(v)
+}
+
+func (l *StructuredLogger) Debug(msg string) { l.log(LogLevelDebug, msg) }
+
+func (l *StructuredLogger) Debugf(format string, v ...interface{}) {
+	l.Debug(fmt.Sprintf(format, v...))
+}
+
+func (l *StructuredLogger) Info(msg string) { l.log(LogLevelInfo, msg) }
+
+func (l *StructuredLogger) Infof(format string, v ...interface{}) { l.Info(fmt.Sprintf(format, v...)) }
+
+func (l *StructuredLogger) Warn(msg string) { l.log(LogLevelWarn, msg) }
+
+func (l *StructuredLogger) Warnf(format string, v ...interface{}) { l.Warn(fmt.Sprintf(format, v...)) }
+
+func (l *StructuredLogger) Error(msg string) { l.log(LogLevelError, msg) }
+
+func (l *StructuredLogger) Errorf(format string, v ...interface{}) {
+	l.Error(fmt.Sprintf(format, v...))
+}
+
+func (l *StructuredLogger) E(err error) { l.Error(err.Error()) }
+
+// SetLevel sets the minimum level of the logger, it does not change the loggers already derived with WithFields.
+func (l *StructuredLogger) SetLevel(level string) {
+	switch strings.ToLower(level) {
+	case "debug":
+		l.minLevel = LogLevelDebug
+	case "warn":
+		l.minLevel = LogLevelWarn
+	case "info":
+		l.minLevel = LogLevelInfo
+	case "error":
+		l.minLevel = LogLevelError
+	}
+}
+
+func (l *StructuredLogger) Level() LogLevel { return l.minLevel }
+
+func (l *StructuredLogger) SetOutput(w io.Writer) {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w = w
+}
+
+func (l *StructuredLogger) Output(calldepth int, s string) error {
+	l.Info(strings.TrimSuffix(s, "\n"))
+	return nil
+}
+
+func (l *StructuredLogger) Printf(format string, v ...interface{}) { l.Info(fmt.Sprintf(format, v...)) }
+
+func (l *StructuredLogger) Print(v ...interface{}) { l.Info(fmt.Sprint(v...)) }
+
+func (l *StructuredLogger) Println(v ...interface{}) {
+	l.Info(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+func (l *StructuredLogger) Fatal(v ...interface{}) {
+	l.Error(fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+func (l *StructuredLogger) Fatalf(format string, v ...interface{}) {
+	l.Error(fmt.Sprintf(format, v...))
+	os.Exit(1)
+}
+
+func (l *StructuredLogger) Fatalln(v ...interface{}) {
+	l.Error(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	os.Exit(1)
+}
+
+func (l *StructuredLogger) Panic(v ...interface{}) {
+	msg := fmt.Sprint(v...)
+	l.Error(msg)
+	panic(msg)
+}
+
+func (l *StructuredLogger) Panicf(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	l.Error(msg)
+	panic(msg)
+}
+
+func (l *StructuredLogger) Panicln(v ...interface{}) {
+	msg := strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	l.Error(msg)
+	panic(msg)
+}
+
+// Flags are kept for the Logger interface but ignored, every line has its time.
+func (l *StructuredLogger) Flags() int { return l.flags }
+
+func (l *StructuredLogger) SetFlags(flag int) { l.flags = flag }
+
+func (l *StructuredLogger) Prefix() string { return l.prefix }
+
+// SetPrefix sets a prefix of the messages of the logger.
+func (l *StructuredLogger) SetPrefix(prefix string) { l.prefix = prefix }
+
+func (l *StructuredLogger) Writer() io.Writer {
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	return l.out.w
+}
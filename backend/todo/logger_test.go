@@ -0,0 +1,61 @@
+package todo_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+func TestStructuredLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := todo.NewStructuredLogger(&buf, todo.LogFormatJSON)
+	logger.SetLevel("info")
+	logger.Debug("dropped")
+	request := todo.LoggerWithFields(logger, todo.Fields{"request_id": "abc"})
+	todo.LoggerWithFields(request, todo.Fields{"user_id": 1}).Warnf("failed %q", "list")
+	logger.Info("no fields")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 lines got %q", lines)
+	}
+	var line map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatal(err)
+	} else if line["level"] != "warn" || line["msg"] != `failed "list"` || line["request_id"] != "abc" || line["user_id"] != float64(1) || line["time"] == nil {
+		t.Fatalf("unexpected line %s", lines[0])
+	}
+	if strings.Contains(lines[1], "request_id") {
+		t.Fatalf("unexpected fields in %s", lines[1])
+	}
+
+	buf.Reset()
+	logger = todo.NewStructuredLogger(&buf, todo.LogFormatLogfmt)
+	todo.LoggerWithFields(logger, todo.Fields{"route": "/api/todos/{id}", "empty": ""}).Info("GET /api/todos/1 200")
+	if got := buf.String(); !strings.Contains(got, ` level=info msg="GET /api/todos/1 200" empty="" route=/api/todos/{id}`+"\n") {
+		t.Fatalf("unexpected line %q", got)
+	}
+
+	// loggers without fields are returned as is
+	if l := todo.NewLogger(); todo.LoggerWithFields(l, todo.Fields{"a": 1}) != todo.Logger(l) {
+		t.Fatal("want the same logger")
+	}
+}
+
+func TestDefaultLogger_Panic(t *testing.T) {
+	var buf bytes.Buffer
+	logger := todo.NewLogger()
+	logger.SetOutput(&buf)
+
+	defer func() {
+		if r := recover(); r != "failed 1" {
+			t.Fatalf("want panic got %v", r)
+		} else if got := buf.String(); !strings.Contains(got, "[error] failed 1") {
+			t.Fatalf("want the message logged got %q", got)
+		}
+	}()
+	logger.Panicf("failed %d", 1)
+}
@@ -0,0 +1,15 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitService counts the requests of rate limited clients in fixed windows. Servers which share a
+// RateLimitService enforce one budget per client.
+type RateLimitService interface {
+	// CountRequest counts a request of the client identified by key in the window which starts at window and lasts
+	// length. It returns the number of requests of the client in that window, including this one, and in the window
+	// before it.
+	CountRequest(ctx context.Context, key string, window time.Time, length time.Duration) (current, previous int, err error)
+}
@@ -0,0 +1,114 @@
+package todo
+
+import (
+	"context"
+	"time"
+)
+
+// Entity types recorded in the change log.
+const (
+	EntityList = "list"
+	EntityItem = "item"
+)
+
+// Operations recorded in the change log and accepted as client mutations.
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Mutation result statuses.
+const (
+	MutationApplied  = "applied"
+	MutationConflict = "conflict"
+	MutationError    = "error"
+)
+
+// ChangeSet contains everything that changed for a user after a sync token.
+type ChangeSet struct {
+	// Lists contains the current state of every List created or updated since the token.
+	Lists []*List `json:"lists"`
+	// Items contains the current state of every Item created or updated since the token.
+	Items []*Item `json:"items"`
+	// Tombstones contains every List or Item deleted since the token.
+	Tombstones []*Tombstone `json:"tombstones"`
+	// Token is passed as the since parameter on the next sync.
+	Token string `json:"token"`
+}
+
+// Tombstone marks a List or Item as deleted.
+type Tombstone struct {
+	Entity    string    `json:"entity"`
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// Mutation is a change made by an offline client that is pushed to the server.
+type Mutation struct {
+	// ClientID is an opaque identifier chosen by the client, it is echoed back in the MutationResult.
+	ClientID string `json:"clientId"`
+	// Entity is one of EntityList or EntityItem.
+	Entity string `json:"entity"`
+	// Op is one of OpCreate, OpUpdate or OpDelete.
+	Op string `json:"op"`
+	// ID of the List or Item, ignored when creating.
+	ID int `json:"id,omitempty"`
+	// ListID is the List an Item is created in.
+	ListID    int     `json:"listId,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	Completed *bool   `json:"completed,omitempty"`
+	// UpdatedAt is the time the client made the change. Updates and deletes are only applied if UpdatedAt is
+	// not older than the server's copy (last writer wins).
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (m *Mutation) Validate() error {
+	if m.Entity != EntityList && m.Entity != EntityItem {
+		return Err(EINVALID, "entity must be one of %q or %q", EntityList, EntityItem)
+	}
+
+	switch m.Op {
+	case OpCreate:
+		if m.Entity == EntityItem && m.ListID <= 0 {
+			return Err(EINVALID, "list id required")
+		}
+	case OpUpdate, OpDelete:
+		if m.ID <= 0 {
+			return Err(EINVALID, "id required")
+		}
+	default:
+		return Err(EINVALID, "op must be one of %q, %q or %q", OpCreate, OpUpdate, OpDelete)
+	}
+
+	return nil
+}
+
+// MutationResult reports the outcome of a single pushed Mutation.
+type MutationResult struct {
+	ClientID string `json:"clientId"`
+	// Status is one of MutationApplied, MutationConflict or MutationError.
+	Status string `json:"status"`
+	// List or Item contain the server's copy after the mutation was processed. On conflict this is the copy
+	// that won.
+	List *List `json:"list,omitempty"`
+	Item *Item `json:"item,omitempty"`
+	// Error is the error message when Status is MutationError.
+	Error string `json:"error,omitempty"`
+}
+
+// SyncService provides delta synchronization for offline clients.
+type SyncService interface {
+	// FindChanges returns all the changes to the current user's Lists and Items recorded after the since token.
+	// An empty token returns every change.
+	// Errors returned:
+	//	invalid: the since token is malformed
+	//	unauthorized: no user was found in the context
+	FindChanges(ctx context.Context, since string) (*ChangeSet, error)
+	// PushMutations applies a batch of client mutations in order. Mutations which fail or lose a
+	// last-writer-wins conflict are reported in their MutationResult and do not prevent other mutations from
+	// being applied.
+	// Errors returned:
+	//	unauthorized: no user was found in the context
+	PushMutations(ctx context.Context, mutations []*Mutation) ([]*MutationResult, error)
+}
@@ -69,14 +69,21 @@ func (l *List) Validate() error {
 
 type ListFilter struct {
 	// Filter fields
-	ID        *int
+	ID *int
+	// IDs matches any of the Lists, it loads many Lists by ID at once.
+	IDs       []int
 	UserID    *int
 	Name      *string
 	Completed *bool
+	// ExcludeItems leaves the Items of the Lists empty, for callers which load them separately.
+	ExcludeItems bool
 
 	// Range restrictions
 	Offset int `json:"offset"`
 	Limit  int `json:"limit"`
+	// AfterID restricts the Lists to those with a greater ID. Unlike Offset, pages by ID neither skip nor repeat
+	// Lists when Lists are created or deleted in between.
+	AfterID int `json:"afterId"`
 }
 
 type ListUpdate struct {
@@ -86,9 +93,13 @@ type ListUpdate struct {
 
 type ItemFilter struct {
 	// Filter fields
-	ID        *int
-	UserID    *int
-	ListID    *int
+	ID *int
+	// IDs matches any of the Items, it loads many Items by ID at once.
+	IDs    []int
+	UserID *int
+	ListID *int
+	// ListIDs matches the Items of any of the Lists, it loads the Items of many Lists at once.
+	ListIDs   []int
 	Name      *string
 	Completed *bool
 
@@ -144,6 +155,11 @@ type ItemListService interface {
 	//	invalid: an invalid if no updates were specified.
 	//	not_found: no matching Item was found
 	UpdateItem(ctx context.Context, id int, upd ItemUpdate) (*Item, error)
+	// ImportLists creates the Lists and their Items for the current user in a single transaction. Either all
+	// Lists are created or none of them. Non-zero CreatedAt and UpdatedAt timestamps are preserved.
+	// Errors returned:
+	//	invalid: lists or items failed to validate, reported as format.RowErrors.
+	ImportLists(ctx context.Context, lists []*List) error
 	// UpdateList updates the Title and/or Completed state of a Todo.
 	// Errors returned:
 	//	invalid: an invalid if no updates were specified.
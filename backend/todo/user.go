@@ -21,6 +21,10 @@ type User struct {
 	Password string `json:"password,omitempty"`
 	// APIKey for bypassing normal auth flow access.
 	APIKey string `json:"-"`
+	// FeedToken authenticates the User's calendar feed. It is empty until the feed is first requested.
+	FeedToken string `json:"-"`
+	// DeleteAt is when the account is deleted, it is set once the User requested the deletion of their account.
+	DeleteAt *time.Time `json:"deleteAt,omitempty"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
@@ -51,16 +55,44 @@ type UserService interface {
 	FindUserByName(ctx context.Context, name string) (*User, error)
 	// FindUserByAPIKey finds a User by their API key.
 	FindUserByAPIKey(ctx context.Context, apiKey string) (*User, error)
+	// FindUserByFeedToken finds a User by the token of their calendar feed.
+	FindUserByFeedToken(ctx context.Context, token string) (*User, error)
+	// ResetFeedToken generates a new calendar feed token for a User, the previous feed URL stops working.
+	ResetFeedToken(ctx context.Context, id int) (*User, error)
+	// ResetPassword replaces the password of a User.
+	// Errors returned:
+	//	invalid: the password is empty
+	//	not_found: no matching User was found
+	ResetPassword(ctx context.Context, id int, password string) (*User, error)
+	// RotateAPIKey generates a new API key for a User, the previous key stops working.
+	// Errors returned:
+	//	not_found: no matching User was found
+	RotateAPIKey(ctx context.Context, id int) (*User, error)
 	// FindUsers finds one or more Users who match the UserFilter.
 	FindUsers(ctx context.Context, f UserFilter) ([]*User, error)
+	// ScheduleUserDeletion confirms the password of a User and schedules the deletion of their account and all of
+	// their data at the given time.
+	// Errors returned:
+	//	not_found: no matching User was found
+	//	unauthorized: the password does not match
+	ScheduleUserDeletion(ctx context.Context, id int, password string, at time.Time) (*User, error)
+	// CancelUserDeletion cancels the scheduled deletion of a User.
+	// Errors returned:
+	//	conflict: the deletion of the User is not scheduled
+	//	not_found: no matching User was found
+	CancelUserDeletion(ctx context.Context, id int) (*User, error)
+	// DeleteScheduledUsers deletes all Users whose scheduled deletion is due and returns how many were deleted.
+	// It is intended for the background worker.
+	DeleteScheduledUsers(ctx context.Context) (int, error)
 }
 
 type UserFilter struct {
 	// Filter fields
-	ID     *int    `json:"id"`
-	Name   *string `json:"name"`
-	Email  *string `json:"email"`
-	APIKey *string `json:"apiKey"`
+	ID        *int    `json:"id"`
+	Name      *string `json:"name"`
+	Email     *string `json:"email"`
+	APIKey    *string `json:"apiKey"`
+	FeedToken *string `json:"-"`
 
 	// Range restrictions
 	Offset int `json:"offset"`
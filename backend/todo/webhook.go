@@ -0,0 +1,170 @@
+package todo
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead marks a delivery which exhausted its retries and will not be attempted again.
+	DeliveryDead = "dead"
+)
+
+// Webhook is a subscription which delivers a user's events to a URL.
+type Webhook struct {
+	ID     int `json:"id"`
+	UserID int `json:"userId"`
+	// URL receives a POST request for every matching event.
+	URL string `json:"url"`
+	// EventTypes limits the webhook to the listed event types, e.g. "item.created". An empty list subscribes
+	// to every event type.
+	EventTypes []string `json:"eventTypes"`
+	// Secret signs the payload of every delivery. A random secret is generated if none is specified.
+	Secret string `json:"secret"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+var webhookEventTypes = map[string]bool{
+	EventListCreated: true,
+	EventListUpdated: true,
+	EventListDeleted: true,
+	EventItemCreated: true,
+	EventItemUpdated: true,
+	EventItemDeleted: true,
+}
+
+func (w *Webhook) Validate() error {
+	if w.UserID <= 0 {
+		return Err(EINVALID, "user id required")
+	}
+
+	if w.URL == "" {
+		return Err(EINVALID, "url required")
+	} else if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Err(EINVALID, "url must be an absolute http or https url")
+	} else if host := u.Hostname(); host == "localhost" || strings.HasSuffix(host, ".localhost") || IsInternalIP(net.ParseIP(host)) {
+		return Err(EINVALID, "url must not point to an internal address")
+	}
+
+	for _, typ := range w.EventTypes {
+		if !webhookEventTypes[typ] {
+			return Err(EINVALID, "unknown event type %q", typ)
+		}
+	}
+
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT network, which is not routed on the internet either.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsInternalIP reports whether ip is a loopback, private, link-local or otherwise non-public address, which webhooks
+// must not be delivered to so that users cannot reach the server's own network through them.
+func IsInternalIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// WebhookDelivery is a single event queued for delivery to a Webhook.
+type WebhookDelivery struct {
+	ID        int    `json:"id"`
+	WebhookID int    `json:"webhookId"`
+	EventType string `json:"eventType"`
+	// Payload is the JSON request body sent to the webhook URL.
+	Payload json.RawMessage `json:"payload"`
+	// Status is one of DeliveryPending, DeliveryDelivered or DeliveryDead.
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// ResponseStatus is the HTTP status code returned by the last attempt, if any.
+	ResponseStatus int `json:"responseStatus,omitempty"`
+	// LastError describes why the last attempt failed.
+	LastError     string    `json:"lastError,omitempty"`
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+
+	// Webhook is set on claimed deliveries.
+	Webhook *Webhook `json:"-"`
+}
+
+// WebhookPayload is the body of every webhook delivery.
+type WebhookPayload struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	List      *List     `json:"list,omitempty"`
+	Item      *Item     `json:"item,omitempty"`
+}
+
+type WebhookFilter struct {
+	// Filter fields
+	ID     *int
+	UserID *int
+
+	// Range restrictions
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type WebhookDeliveryFilter struct {
+	// Filter fields
+	WebhookID *int
+	Status    *string
+
+	// Range restrictions
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// WebhookDeliveryUpdate records the outcome of a delivery attempt.
+type WebhookDeliveryUpdate struct {
+	Status         string
+	ResponseStatus int
+	Error          string
+	// NextAttemptAt is when a pending delivery is retried.
+	NextAttemptAt time.Time
+}
+
+// WebhookService manages webhook subscriptions and their queue of deliveries.
+type WebhookService interface {
+	// CreateWebhook creates a Webhook for the current user.
+	// Errors returned:
+	//	invalid: the webhook failed to validate
+	//	unauthorized: no user was found in the context
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	// DeleteWebhook deletes a Webhook and all of its deliveries.
+	// Errors returned:
+	//	not_found: no matching Webhook belonging to the current user was found
+	DeleteWebhook(ctx context.Context, id int) error
+	// FindWebhookByID returns the Webhook with the matching ID.
+	// Errors returned:
+	//	not_found: no matching Webhook was found
+	//	unauthorized: the Webhook belongs to another user
+	FindWebhookByID(ctx context.Context, id int) (*Webhook, error)
+	// FindWebhooks returns the current user's Webhooks matching the filter.
+	FindWebhooks(ctx context.Context, f WebhookFilter) ([]*Webhook, error)
+	// FindWebhookDeliveries returns the deliveries of one of the current user's Webhooks, newest first.
+	// Errors returned:
+	//	invalid: no webhook ID was specified
+	//	not_found: no matching Webhook was found
+	FindWebhookDeliveries(ctx context.Context, f WebhookDeliveryFilter) ([]*WebhookDelivery, error)
+
+	// ClaimWebhookDeliveries returns up to n pending deliveries which are due and leases them for the given
+	// duration so that other workers skip them. It is not scoped to a user and is intended for the
+	// background dispatcher.
+	ClaimWebhookDeliveries(ctx context.Context, n int, lease time.Duration) ([]*WebhookDelivery, error)
+	// UpdateWebhookDelivery records the outcome of an attempt and increments the number of attempts.
+	UpdateWebhookDelivery(ctx context.Context, id int, upd WebhookDeliveryUpdate) error
+}
@@ -0,0 +1,92 @@
+// Package tracing sets up the OpenTelemetry tracer provider which exports the spans of requests, transactions and
+// queries.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ExporterNone disables tracing.
+	ExporterNone = ""
+	// ExporterStdout writes the spans as JSON.
+	ExporterStdout = "stdout"
+	// ExporterOTLP sends the spans to an OTLP/HTTP collector.
+	ExporterOTLP = "otlp"
+)
+
+// Config configures the exporter of a Provider.
+type Config struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterOTLP.
+	Exporter string
+	// Endpoint is the host and port of the OTLP collector, it defaults to localhost:4318.
+	Endpoint string
+	// Insecure sends the spans to the OTLP collector without TLS.
+	Insecure bool
+	// ServiceName is the name of the service of the spans.
+	ServiceName string
+	// Stdout is where ExporterStdout writes the spans.
+	Stdout io.Writer
+}
+
+// Provider is a trace.TracerProvider which exports its spans until it is shut down.
+type Provider struct {
+	trace.TracerProvider
+	shutdown func(ctx context.Context) error
+}
+
+// NewProvider returns a Provider which exports spans as configured, or a provider which records nothing with
+// ExporterNone.
+func NewProvider(ctx context.Context, c Config) (*Provider, error) {
+	var exporter sdktrace.SpanExporter
+	switch c.Exporter {
+	case ExporterNone:
+		return &Provider{
+			TracerProvider: trace.NewNoopTracerProvider(),
+			shutdown:       func(context.Context) error { return nil },
+		}, nil
+	case ExporterStdout:
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(c.Stdout)); err != nil {
+			return nil, err
+		}
+	case ExporterOTLP:
+		opts := []otlptracehttp.Option{}
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		var err error
+		if exporter, err = otlptracehttp.New(ctx, opts...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", c.Exporter)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName(c.ServiceName),
+			semconv.ServiceVersion(todo.Build.Version),
+		)),
+	)
+	return &Provider{TracerProvider: tp, shutdown: tp.Shutdown}, nil
+}
+
+// Shutdown exports the remaining spans and stops the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	return p.shutdown(ctx)
+}
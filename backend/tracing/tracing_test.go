@@ -0,0 +1,35 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cmokbel1/todo-app/backend/tracing"
+)
+
+func TestNewProvider(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := tracing.NewProvider(context.Background(), tracing.Config{Exporter: tracing.ExporterStdout, ServiceName: "test", Stdout: &buf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.Tracer("test").Start(context.Background(), "request")
+	span.End()
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(buf.String(), `"Name":"request"`) {
+		t.Fatalf("want the span exported got %q", buf.String())
+	}
+
+	// without an exporter nothing is recorded
+	if p, err = tracing.NewProvider(context.Background(), tracing.Config{}); err != nil {
+		t.Fatal(err)
+	} else if _, span := p.Tracer("test").Start(context.Background(), "request"); span.IsRecording() {
+		t.Fatal("want no recording span")
+	}
+	if _, err := tracing.NewProvider(context.Background(), tracing.Config{Exporter: "jaeger"}); err == nil {
+		t.Fatal("want error")
+	}
+}
@@ -0,0 +1,230 @@
+// Package webhook delivers queued webhook deliveries in the background.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+)
+
+// Headers sent with every delivery.
+const (
+	// SignatureHeader contains "sha256=" followed by the hex encoded HMAC-SHA256 of the request body keyed
+	// with the webhook secret.
+	SignatureHeader = "Todo-Webhook-Signature"
+	EventHeader     = "Todo-Webhook-Event"
+	DeliveryHeader  = "Todo-Webhook-Delivery"
+)
+
+// Sign returns the signature of a payload as sent in the SignatureHeader.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the valid signature of the payload.
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// Dispatcher periodically claims due deliveries from the WebhookService and sends them.
+type Dispatcher struct {
+	ctx    context.Context
+	cancel func()
+	done   chan struct{}
+
+	Service todo.WebhookService
+	Client  *http.Client
+	Logger  todo.Logger
+
+	// Interval between polls of the delivery queue.
+	Interval time.Duration
+	// BatchSize is the maximum number of deliveries claimed per poll.
+	BatchSize int
+	// Lease is how long a claimed delivery is hidden from other dispatchers.
+	Lease time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is dead.
+	MaxAttempts int
+	// Backoff returns the delay before retrying a delivery which failed for the nth time.
+	Backoff func(n int) time.Duration
+
+	Now func() time.Time
+}
+
+func NewDispatcher(svc todo.WebhookService) *Dispatcher {
+	d := &Dispatcher{
+		Service:     svc,
+		Client:      NewClient(),
+		Logger:      todo.NewLogger(),
+		Interval:    5 * time.Second,
+		BatchSize:   20,
+		Lease:       time.Minute,
+		MaxAttempts: 8,
+		Backoff:     ExponentialBackoff(30*time.Second, 6*time.Hour),
+		Now:         func() time.Time { return time.Now().UTC() },
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// errInternalAddress is returned for deliveries to addresses which are not public.
+var errInternalAddress = errors.New("webhook url resolves to an internal address")
+
+// NewClient returns the client which sends deliveries. It refuses to connect to internal addresses, also if a public
+// host name resolves to one, and it does not follow redirects, which could lead anywhere.
+func NewClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || todo.IsInternalIP(ip) {
+				return errInternalAddress
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		// no proxy from the environment, the check applies to the address which is connected to
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// ExponentialBackoff doubles the delay after every failure starting at base, up to max.
+func ExponentialBackoff(base, max time.Duration) func(n int) time.Duration {
+	return func(n int) time.Duration {
+		delay := base
+		for i := 1; i < n && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// Open starts dispatching in the background.
+func (d *Dispatcher) Open() {
+	d.done = make(chan struct{})
+	go d.run()
+}
+
+// Close stops dispatching, which cancels in-flight deliveries, and waits for the dispatcher to return. The canceled
+// deliveries are attempted again once their lease expires.
+func (d *Dispatcher) Close() error {
+	d.cancel()
+	if d.done != nil {
+		<-d.done
+	}
+	return nil
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		// keep draining while full batches are returned
+		for {
+			n, err := d.Dispatch(d.ctx)
+			if err != nil && d.ctx.Err() == nil {
+				d.Logger.Errorf("webhook dispatcher: %v", err)
+			}
+			if err != nil || n < d.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch claims a batch of due deliveries and attempts each of them. It returns the number of deliveries
+// attempted.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := d.Service.ClaimWebhookDeliveries(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		upd := d.deliver(ctx, delivery)
+		if err := d.Service.UpdateWebhookDelivery(ctx, delivery.ID, upd); err != nil {
+			return 0, fmt.Errorf("update delivery %d: %w", delivery.ID, err)
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends the delivery and returns the resulting state of the delivery.
+func (d *Dispatcher) deliver(ctx context.Context, delivery *todo.WebhookDelivery) todo.WebhookDeliveryUpdate {
+	status, err := d.send(ctx, delivery)
+	if err == nil {
+		return todo.WebhookDeliveryUpdate{Status: todo.DeliveryDelivered, ResponseStatus: status}
+	}
+
+	upd := todo.WebhookDeliveryUpdate{Status: todo.DeliveryPending, ResponseStatus: status, Error: err.Error()}
+	if attempts := delivery.Attempts + 1; attempts >= d.MaxAttempts {
+		d.Logger.Warnf("webhook delivery %d to %q is dead after %d attempts: %v", delivery.ID, delivery.Webhook.URL, attempts, err)
+		upd.Status = todo.DeliveryDead
+	} else {
+		upd.NextAttemptAt = d.Now().Add(d.Backoff(attempts))
+	}
+	return upd
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *todo.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todo-app-webhook/"+todo.Build.Version)
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, delivery.Payload))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,198 @@
+package webhook_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cmokbel1/todo-app/backend/todo"
+	"github.com/cmokbel1/todo-app/backend/webhook"
+)
+
+// queue is a WebhookService which only implements the delivery queue.
+type queue struct {
+	todo.WebhookService
+
+	mu         sync.Mutex
+	now        time.Time
+	deliveries []*todo.WebhookDelivery
+}
+
+func (q *queue) ClaimWebhookDeliveries(ctx context.Context, n int, lease time.Duration) ([]*todo.WebhookDelivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var claimed []*todo.WebhookDelivery
+	for _, d := range q.deliveries {
+		if len(claimed) < n && d.Status == todo.DeliveryPending && !d.NextAttemptAt.After(q.now) {
+			d.NextAttemptAt = q.now.Add(lease)
+			other := *d
+			claimed = append(claimed, &other)
+		}
+	}
+	return claimed, nil
+}
+
+func (q *queue) UpdateWebhookDelivery(ctx context.Context, id int, upd todo.WebhookDeliveryUpdate) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, d := range q.deliveries {
+		if d.ID == id {
+			d.Status, d.ResponseStatus, d.LastError = upd.Status, upd.ResponseStatus, upd.Error
+			d.NextAttemptAt = upd.NextAttemptAt
+			d.Attempts++
+			return nil
+		}
+	}
+	return todo.NotFound
+}
+
+func TestDispatcher(t *testing.T) {
+	const secret = "secret"
+	payload := []byte(`{"type":"item.created"}`)
+
+	newDispatcher := func(t *testing.T, h http.HandlerFunc) (*webhook.Dispatcher, *queue) {
+		t.Helper()
+		srv := httptest.NewServer(h)
+		t.Cleanup(srv.Close)
+
+		q := &queue{now: time.Now()}
+		q.deliveries = append(q.deliveries, &todo.WebhookDelivery{
+			ID:            1,
+			WebhookID:     1,
+			EventType:     todo.EventItemCreated,
+			Payload:       payload,
+			Status:        todo.DeliveryPending,
+			NextAttemptAt: q.now,
+			Webhook:       &todo.Webhook{ID: 1, URL: srv.URL, Secret: secret},
+		})
+
+		d := webhook.NewDispatcher(q)
+		d.Client = srv.Client()
+		d.Now = func() time.Time { return q.now }
+		return d, q
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		d, q := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if !webhook.Verify(secret, body, r.Header.Get(webhook.SignatureHeader)) {
+				t.Errorf("invalid signature %q", r.Header.Get(webhook.SignatureHeader))
+			}
+			if got, want := r.Header.Get(webhook.EventHeader), todo.EventItemCreated; got != want {
+				t.Errorf("want event header %q got %q", want, got)
+			}
+			if got, want := r.Header.Get(webhook.DeliveryHeader), "1"; got != want {
+				t.Errorf("want delivery header %q got %q", want, got)
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		if n, err := d.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if n != 1 {
+			t.Fatalf("want 1 delivery attempted got %d", n)
+		}
+
+		if got := q.deliveries[0]; got.Status != todo.DeliveryDelivered {
+			t.Fatalf("want status %q got %q", todo.DeliveryDelivered, got.Status)
+		} else if got.ResponseStatus != http.StatusNoContent {
+			t.Fatalf("want response status %d got %d", http.StatusNoContent, got.ResponseStatus)
+		}
+	})
+
+	t.Run("RetryWithBackoff", func(t *testing.T) {
+		d, q := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		d.Backoff = webhook.ExponentialBackoff(time.Minute, time.Hour)
+
+		for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+			if _, err := d.Dispatch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			got := q.deliveries[0]
+			if got.Status != todo.DeliveryPending {
+				t.Fatalf("attempt %d: want status %q got %q", i+1, todo.DeliveryPending, got.Status)
+			} else if delay := got.NextAttemptAt.Sub(q.now); delay != want {
+				t.Fatalf("attempt %d: want retry after %v got %v", i+1, want, delay)
+			}
+
+			// nothing is due until the backoff elapses
+			if n, err := d.Dispatch(context.Background()); err != nil {
+				t.Fatal(err)
+			} else if n != 0 {
+				t.Fatalf("attempt %d: want no deliveries attempted got %d", i+1, n)
+			}
+			q.now = got.NextAttemptAt
+		}
+	})
+
+	t.Run("DeadLetter", func(t *testing.T) {
+		d, q := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+		d.MaxAttempts = 2
+
+		for i := 0; i < d.MaxAttempts; i++ {
+			if _, err := d.Dispatch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			q.now = q.deliveries[0].NextAttemptAt
+		}
+
+		if got := q.deliveries[0]; got.Status != todo.DeliveryDead {
+			t.Fatalf("want status %q got %q", todo.DeliveryDead, got.Status)
+		} else if got.Attempts != 2 {
+			t.Fatalf("want 2 attempts got %d", got.Attempts)
+		} else if got.LastError == "" {
+			t.Fatal("want last error got none")
+		}
+	})
+
+	t.Run("InternalAddress", func(t *testing.T) {
+		d, q := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Error("want no request to an internal address")
+		})
+		d.Client = webhook.NewClient()
+
+		if _, err := d.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := q.deliveries[0]; got.Status != todo.DeliveryPending {
+			t.Fatalf("want status %q got %q", todo.DeliveryPending, got.Status)
+		} else if !strings.Contains(got.LastError, "internal address") {
+			t.Fatalf("want internal address error got %q", got.LastError)
+		}
+	})
+
+	t.Run("NoRedirects", func(t *testing.T) {
+		var redirected bool
+		d, q := newDispatcher(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/internal" {
+				redirected = true
+			}
+			http.Redirect(w, r, "/internal", http.StatusFound)
+		})
+		// the test server is on loopback, only the redirect policy of the client is tested
+		transport := d.Client.Transport
+		d.Client = webhook.NewClient()
+		d.Client.Transport = transport
+
+		if _, err := d.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if redirected {
+			t.Fatal("want redirect not followed")
+		} else if got := q.deliveries[0]; got.Status != todo.DeliveryPending || got.ResponseStatus != http.StatusFound {
+			t.Fatalf("want pending with status %d got %q with %d", http.StatusFound, got.Status, got.ResponseStatus)
+		}
+	})
+}
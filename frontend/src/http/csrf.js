@@ -0,0 +1,22 @@
+// The server rejects requests which change state with the session cookie unless they send the CSRF token of the
+// session, which other sites cannot read.
+let token = null;
+
+// csrfHeaders returns the headers with the CSRF token of the session, which is fetched once per session.
+async function csrfHeaders() {
+    if (token === null) {
+        const res = await fetch('/api/csrf');
+        if (!res.ok) {
+            throw new Error('failed to fetch CSRF token');
+        }
+        token = (await res.json()).token;
+    }
+    return { 'X-CSRF-Token': token };
+}
+
+// resetCsrfToken forgets the token once the session ends.
+function resetCsrfToken() {
+    token = null;
+}
+
+export { csrfHeaders, resetCsrfToken };
@@ -1,3 +1,5 @@
+import { csrfHeaders } from './csrf';
+
 // Get functions
 async function getLists() {
     try {
@@ -28,6 +30,7 @@ async function addItem(id, item) {
         const res = await fetch(`/api/todos/${id}/`,
             {
                 method: 'POST',
+                headers: await csrfHeaders(),
                 body: JSON.stringify(data)
             })
         const jsonResponse = await res.json();
@@ -44,6 +47,7 @@ async function addList(item) {
         const res = await fetch('/api/todos/',
             {
                 method: 'POST',
+                headers: await csrfHeaders(),
                 body: JSON.stringify(data)
             })
         const jsonResponse = await res.json();
@@ -61,6 +65,7 @@ async function setCompletion(id, completed, listId) {
         const res = await fetch(`/api/todos/${listId}/${id}`,
             {
                 method: 'PATCH',
+                headers: await csrfHeaders(),
                 body: JSON.stringify(data)
             })
         const jsonResponse = await res.json()
@@ -77,6 +82,7 @@ async function updateListName(id, name) {
         const res = await fetch(`/api/todos/${id}`,
             {
                 method: 'PATCH',
+                headers: await csrfHeaders(),
                 body: JSON.stringify(data)
             })
         const jsonResponse = await res.json();
@@ -92,7 +98,8 @@ async function deleteItem(listId, id) {
     try {
         const res = await fetch(`/api/todos/${listId}/${id}`,
             {
-                method: 'DELETE'
+                method: 'DELETE',
+                headers: await csrfHeaders()
             })
         if (res.status === 204) {
             return "";
@@ -108,7 +115,8 @@ async function deleteList(listId) {
     try {
         const res = await fetch(`/api/todos/${listId}`,
             {
-                method: 'DELETE'
+                method: 'DELETE',
+                headers: await csrfHeaders()
             })
         if (res.status === 204) {
             return "";
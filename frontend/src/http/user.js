@@ -1,3 +1,5 @@
+import { csrfHeaders, resetCsrfToken } from './csrf';
+
 //register a new user
 async function registerUser(username, email, password) {
     const data = { name: username, email: email, password: password };
@@ -61,8 +63,9 @@ async function getUser() {
 // the logout was successful, otherwise, error contains the reason for the failure.
 async function logout() {
     try {
-        const res = await fetch('/api/user/logout', { method: 'DELETE' })
+        const res = await fetch('/api/user/logout', { method: 'DELETE', headers: await csrfHeaders() })
         if (res.status === 204) {
+            resetCsrfToken();
             console.log('Logout Success');
             return { ok: true };
         } else {